@@ -5,6 +5,6 @@ go 1.16
 require (
 	github.com/garyburd/redigo v1.6.2
 	github.com/modood/table v0.0.0-20200225102042-88de94bb9876
-	gopkg.in/gcfg.v1 v1.2.3 // indirect
+	gopkg.in/gcfg.v1 v1.2.3
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
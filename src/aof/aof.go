@@ -0,0 +1,171 @@
+package aof
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"rediscmd/src/rdb"
+	"rediscmd/src/resp"
+)
+
+//aof文件中的一条命令
+type Command struct {
+	DB   int      //命令执行时所在的数据库
+	Args []string //命令及参数
+	Time int64    //redis7的aof时间戳注释（秒），0表示没有记录
+	File string   //命令所在的文件
+}
+
+//命令名称（小写）
+func (c *Command) Name() string {
+	if len(c.Args) == 0 {
+		return ""
+	}
+	return strings.ToLower(c.Args[0])
+}
+
+//不操作key的命令
+var noKeyCmds = map[string]bool{
+	"select": true, "multi": true, "exec": true, "discard": true, "flushdb": true, "flushall": true,
+	"swapdb": true, "script": true, "function": true, "publish": true, "ping": true,
+}
+
+//所有参数都是key的命令
+var allKeysCmds = map[string]bool{
+	"del": true, "unlink": true, "exists": true, "touch": true, "watch": true, "mget": true,
+}
+
+//命令中操作的key
+func (c *Command) Keys() []string {
+	name := c.Name()
+	if len(c.Args) < 2 || noKeyCmds[name] {
+		return nil
+	}
+	switch {
+	case allKeysCmds[name]:
+		return c.Args[1:]
+	case name == "mset" || name == "msetnx":
+		keys := []string{}
+		for i := 1; i < len(c.Args); i += 2 {
+			keys = append(keys, c.Args[i])
+		}
+		return keys
+	case name == "rename" || name == "renamenx" || name == "copy" || name == "smove" || name == "lmove" || name == "rpoplpush":
+		if len(c.Args) >= 3 {
+			return c.Args[1:3]
+		}
+	}
+	return c.Args[1:2]
+}
+
+//解析aof，path可以是单个aof文件、redis7的aof清单文件或aof目录，每解析出一条命令就交给handle处理，handle返回false时停止解析
+func ParseFile(path string, handle func(cmd *Command) bool) error {
+	files, err := aofFiles(path)
+	if err != nil {
+		return err
+	}
+	state := &parseState{handle: handle}
+	for _, file := range files {
+		if err := state.parseFile(file); err != nil || state.stopped {
+			return err
+		}
+	}
+	return nil
+}
+
+//根据路径获取需要按顺序解析的aof文件
+func aofFiles(path string) ([]string, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		if path, err = findManifest(path); err != nil {
+			return nil, err
+		}
+	}
+	if strings.HasSuffix(path, ".manifest") {
+		return loadManifest(path)
+	}
+	return []string{path}, nil
+}
+
+//跨文件的解析状态
+type parseState struct {
+	handle  func(cmd *Command) bool
+	db      int
+	stopped bool
+}
+
+//解析一个aof文件，文件以REDIS开头时先按rdb格式解析前缀部分
+func (s *parseState) parseFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := bufio.NewReaderSize(f, 64*1024)
+	if head, _ := reader.Peek(5); string(head) == "REDIS" {
+		if err := s.parseRDB(file, reader); err != nil || s.stopped {
+			return err
+		}
+	}
+	cmdReader := resp.NewReader(reader)
+	for {
+		args, err := cmdReader.ReadCommand()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			continue
+		}
+		cmd := &Command{DB: s.db, Args: args, File: file}
+		if strings.HasPrefix(cmdReader.Annotation, "#TS:") {
+			cmd.Time, _ = strconv.ParseInt(strings.TrimPrefix(cmdReader.Annotation, "#TS:"), 10, 64)
+		}
+		if cmd.Name() == "select" && len(args) > 1 {
+			s.db, _ = strconv.Atoi(args[1])
+			cmd.DB = s.db
+		}
+		if !s.handle(cmd) {
+			s.stopped = true
+			return nil
+		}
+	}
+}
+
+//将rdb格式的前缀转换为命令
+func (s *parseState) parseRDB(file string, reader *bufio.Reader) error {
+	var convertErr error
+	err := rdb.NewParser(reader).Parse(func(entry *rdb.Entry) bool {
+		if entry.Type == "module" { //模块数据无法转换为命令
+			return true
+		}
+		args, err := entry.Commands()
+		if err != nil {
+			convertErr = err
+			return false
+		}
+		if entry.DB != s.db {
+			s.db = entry.DB
+			args = append([][]string{{"select", strconv.Itoa(entry.DB)}}, args...)
+		}
+		for _, item := range args {
+			if !s.handle(&Command{DB: s.db, Args: item, File: file}) {
+				s.stopped = true
+				return false
+			}
+		}
+		return true
+	})
+	if convertErr != nil {
+		return convertErr
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package aof
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "appendonly.aof.1.incr.aof"), []byte("*2\r\n$6\r\nselect\r\n$1\r\n2\r\n#TS:1690000000\r\n*3\r\n$3\r\nset\r\n$1\r\na\r\n$1\r\n1\r\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "appendonly.aof.2.incr.aof"), []byte("*2\r\n$3\r\ndel\r\n$1\r\na\r\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "appendonly.aof.manifest"), []byte("file appendonly.aof.2.incr.aof seq 2 type i\nfile appendonly.aof.1.incr.aof seq 1 type i\n"), 0644)
+	got := []Command{}
+	err := ParseFile(dir, func(cmd *Command) bool {
+		got = append(got, Command{DB: cmd.DB, Args: cmd.Args, Time: cmd.Time, File: filepath.Base(cmd.File)})
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Command{
+		{DB: 2, Args: []string{"select", "2"}, File: "appendonly.aof.1.incr.aof"},
+		{DB: 2, Args: []string{"set", "a", "1"}, Time: 1690000000, File: "appendonly.aof.1.incr.aof"},
+		{DB: 2, Args: []string{"del", "a"}, File: "appendonly.aof.2.incr.aof"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("解析结果为%+v，期望%+v", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package aof
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//redis7多文件aof的清单中的文件类型
+const (
+	fileTypeBase    = "b"
+	fileTypeHistory = "h"
+	fileTypeIncr    = "i"
+)
+
+//清单中的一个aof文件
+type manifestFile struct {
+	name     string
+	seq      int
+	fileType string
+}
+
+//查找目录中的aof清单文件
+func findManifest(dir string) (string, error) {
+	manifests, err := filepath.Glob(filepath.Join(dir, "*.manifest"))
+	if err != nil {
+		return "", err
+	}
+	if len(manifests) != 1 {
+		return "", fmt.Errorf("目录%s中需要有且只有一个aof清单文件(*.manifest)", dir)
+	}
+	return manifests[0], nil
+}
+
+//读取清单文件，按加载顺序返回aof文件的路径：base文件在前，incr文件按序号排列，history文件不再参与加载
+func loadManifest(manifestPath string) ([]string, error) {
+	f, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var base *manifestFile
+	incrs := []manifestFile{}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields)%2 != 0 {
+			return nil, fmt.Errorf("清单文件第%d行格式错误", lineNo)
+		}
+		item := manifestFile{}
+		for i := 0; i < len(fields); i += 2 {
+			value := strings.Trim(fields[i+1], "\"")
+			switch fields[i] {
+			case "file":
+				item.name = value
+			case "seq":
+				item.seq, _ = strconv.Atoi(value)
+			case "type":
+				item.fileType = value
+			}
+		}
+		switch item.fileType {
+		case fileTypeBase:
+			base = &item
+		case fileTypeIncr:
+			incrs = append(incrs, item)
+		case fileTypeHistory:
+		default:
+			return nil, fmt.Errorf("清单文件第%d行的文件类型%s无法识别", lineNo, item.fileType)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(incrs, func(i, j int) bool { return incrs[i].seq < incrs[j].seq })
+	dir := filepath.Dir(manifestPath)
+	files := []string{}
+	if base != nil {
+		files = append(files, filepath.Join(dir, base.name))
+	}
+	for _, item := range incrs {
+		files = append(files, filepath.Join(dir, item.name))
+	}
+	return files, nil
+}
@@ -0,0 +1,95 @@
+package aof
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "base和incr",
+			content: "file appendonly.aof.1.base.rdb seq 1 type b\n" +
+				"file appendonly.aof.3.incr.aof seq 3 type i\n" +
+				"file appendonly.aof.2.incr.aof seq 2 type i\n",
+			want: []string{"appendonly.aof.1.base.rdb", "appendonly.aof.2.incr.aof", "appendonly.aof.3.incr.aof"},
+		},
+		{
+			name: "跳过history、空行和注释",
+			content: "# comment\n\n" +
+				"file appendonly.aof.1.base.aof seq 1 type h\n" +
+				"file appendonly.aof.2.base.aof seq 2 type b\n" +
+				"file appendonly.aof.1.incr.aof seq 1 type i\n",
+			want: []string{"appendonly.aof.2.base.aof", "appendonly.aof.1.incr.aof"},
+		},
+		{
+			name:    "带引号的文件名",
+			content: "file \"appendonly.aof.1.incr.aof\" seq 1 type i\n",
+			want:    []string{"appendonly.aof.1.incr.aof"},
+		},
+		{
+			name:    "没有base",
+			content: "file appendonly.aof.1.incr.aof seq 1 type i\n",
+			want:    []string{"appendonly.aof.1.incr.aof"},
+		},
+		{
+			name:    "空清单",
+			content: "",
+			want:    []string{},
+		},
+		{
+			name:    "字段不成对",
+			content: "file appendonly.aof.1.incr.aof seq\n",
+			wantErr: true,
+		},
+		{
+			name:    "未知类型",
+			content: "file appendonly.aof.1.incr.aof seq 1 type x\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		manifest := filepath.Join(dir, "appendonly.aof.manifest")
+		if err := ioutil.WriteFile(manifest, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := loadManifest(manifest)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: 错误为%v", tt.name, err)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		for i := range tt.want {
+			tt.want[i] = filepath.Join(dir, tt.want[i])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 文件为%q，期望%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindManifest(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := findManifest(dir); err == nil {
+		t.Error("目录中没有清单文件时应返回错误")
+	}
+	manifest := filepath.Join(dir, "appendonly.aof.manifest")
+	ioutil.WriteFile(manifest, nil, 0644)
+	if got, err := findManifest(dir); err != nil || got != manifest {
+		t.Errorf("findManifest = %q, %v，期望%q", got, err, manifest)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "other.manifest"), nil, 0644)
+	if _, err := findManifest(dir); err == nil {
+		t.Error("目录中有多个清单文件时应返回错误")
+	}
+}
@@ -0,0 +1,167 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"regexp"
+)
+
+var gzipCodec = &Codec{
+	Name: "gzip",
+	Decode: func(data []byte) ([]byte, error) {
+		return gunzip(data, 0)
+	},
+	Encode: func(data []byte) ([]byte, error) {
+		return compress(data, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+	},
+	decodeLimit: gunzip,
+}
+
+var zlibCodec = &Codec{
+	Name: "zlib",
+	Decode: func(data []byte) ([]byte, error) {
+		return zlibDecompress(data, 0)
+	},
+	Encode: func(data []byte) ([]byte, error) {
+		return compress(data, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })
+	},
+	decodeLimit: zlibDecompress,
+}
+
+//gzip解压，limit大于0时解压后超过limit字节返回错误
+func gunzip(data []byte, limit int64) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readAllLimit(reader, limit)
+}
+
+//zlib解压，limit大于0时解压后超过limit字节返回错误
+func zlibDecompress(data []byte, limit int64) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readAllLimit(reader, limit)
+}
+
+//读取全部内容，limit大于0时最多读取limit+1字节，超过limit字节返回错误
+func readAllLimit(reader io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return ioutil.ReadAll(reader)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errTooLarge(limit)
+	}
+	return data, nil
+}
+
+//使用gzip或zlib压缩
+func compress(data []byte, newWriter func(w io.Writer) io.WriteCloser) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := newWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var base64Codec = &Codec{
+	Name: "base64",
+	Decode: func(data []byte) ([]byte, error) {
+		data = bytes.TrimSpace(data)
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+			if decoded, err := encoding.DecodeString(string(data)); err == nil {
+				return decoded, nil
+			}
+		}
+		return nil, errors.New("不是有效的base64内容")
+	},
+	Encode: func(data []byte) ([]byte, error) {
+		return []byte(base64.StdEncoding.EncodeToString(data)), nil
+	},
+}
+
+//解码时格式化json，编码时压缩json
+var jsonCodec = &Codec{
+	Name: "json",
+	Decode: func(data []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	},
+	Encode: func(data []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	},
+}
+
+//解码时输出十六进制，编码时将输入的十六进制转换为二进制
+var hexCodec = &Codec{
+	Name: "hex",
+	Decode: func(data []byte) ([]byte, error) {
+		return []byte(hex.Dump(data)), nil
+	},
+	Encode: func(data []byte) ([]byte, error) {
+		return hex.DecodeString(string(bytes.Join(bytes.Fields(data), nil)))
+	},
+}
+
+func isGzip(data []byte) bool {
+	return len(data) > 18 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+func isZlib(data []byte) bool {
+	//zlib头的第一个字节为0x78，且前两个字节组成的数字是31的倍数
+	return len(data) > 6 && data[0] == 0x78 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0
+}
+
+//只识别对象和数组，避免把数字、字符串当作json
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+	return json.Valid(trimmed)
+}
+
+var base64Reg = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+
+//只有解码后的内容可以继续识别为压缩、json或msgpack时才认为是base64，避免把普通单词当作base64
+func isBase64(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if len(data) < 8 || !base64Reg.Match(data) {
+		return false
+	}
+	decoded, err := base64Codec.Decode(data)
+	if err != nil {
+		return false
+	}
+	switch detect(decoded) {
+	case "gzip", "zlib", "json", "msgpack":
+		return true
+	}
+	return false
+}
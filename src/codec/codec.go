@@ -0,0 +1,180 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxAutoDepth = 8 //自动识别时最多解码的层数
+
+//缓存值的编解码器
+type Codec struct {
+	Name   string
+	Decode func(data []byte) ([]byte, error) //将存储的内容解码为便于阅读的内容
+	Encode func(data []byte) ([]byte, error) //将输入的内容编码为存储的内容，为nil时不支持编码
+
+	decodeLimit func(data []byte, limit int64) ([]byte, error) //解码时读取到limit字节即停止，用于解压，为nil时解码后再检查大小
+}
+
+var codecs = map[string]*Codec{}
+
+//注册编解码器，同名的编解码器会被替换
+func Register(codec *Codec) {
+	codecs[codec.Name] = codec
+}
+
+//获取已注册的编解码器
+func Get(name string) (*Codec, bool) {
+	codec, ok := codecs[name]
+	return codec, ok
+}
+
+//编解码器的名称列表
+func Names() []string {
+	return []string{"gzip", "zlib", "base64", "json", "msgpack", "protobuf", "hex"}
+}
+
+func init() {
+	Register(gzipCodec)
+	Register(zlibCodec)
+	Register(base64Codec)
+	Register(jsonCodec)
+	Register(hexCodec)
+	Register(msgpackCodec)
+	Register(NewProtobufCodec(nil, ""))
+}
+
+//编解码链，按存储内容的解码顺序排列，例如gzip,json表示先gzip解压再格式化json
+type Chain []*Codec
+
+//解析逗号分隔的编解码链
+func ParseChain(names string) (Chain, error) {
+	chain := Chain{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		codec, ok := Get(name)
+		if !ok {
+			return nil, fmt.Errorf("不支持的编解码器【%s】，仅支持%s", name, strings.Join(Names(), "|"))
+		}
+		chain = append(chain, codec)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("编解码链不能为空")
+	}
+	return chain, nil
+}
+
+//返回将同名编解码器替换为codec后的新链，用于只在本次使用的编解码器，例如指定了消息类型的protobuf
+func (c Chain) Replace(codec *Codec) Chain {
+	chain := make(Chain, len(c))
+	for i, item := range c {
+		if item.Name == codec.Name {
+			item = codec
+		}
+		chain[i] = item
+	}
+	return chain
+}
+
+//按顺序解码
+func (c Chain) Decode(data []byte) ([]byte, error) {
+	var err error
+	for _, codec := range c {
+		if data, err = codec.Decode(data); err != nil {
+			return nil, fmt.Errorf("%s解码失败：%s", codec.Name, err.Error())
+		}
+	}
+	return data, nil
+}
+
+//按顺序解码，每一步解码后的内容超过limit字节时返回错误，gzip、zlib解压超过limit字节时即停止，避免少量压缩数据解压出超大的内容
+func (c Chain) DecodeLimit(data []byte, limit int64) ([]byte, error) {
+	var err error
+	for _, codec := range c {
+		if codec.decodeLimit != nil {
+			data, err = codec.decodeLimit(data, limit)
+		} else if data, err = codec.Decode(data); err == nil && int64(len(data)) > limit {
+			err = errTooLarge(limit)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s解码失败：%s", codec.Name, err.Error())
+		}
+	}
+	return data, nil
+}
+
+func errTooLarge(limit int64) error {
+	return fmt.Errorf("解码后超过%d字节的限制", limit)
+}
+
+//按解码的相反顺序编码
+func (c Chain) Encode(data []byte) ([]byte, error) {
+	var err error
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].Encode == nil {
+			return nil, fmt.Errorf("%s不支持编码", c[i].Name)
+		}
+		if data, err = c[i].Encode(data); err != nil {
+			return nil, fmt.Errorf("%s编码失败：%s", c[i].Name, err.Error())
+		}
+	}
+	return data, nil
+}
+
+//自动识别并解码，返回解码后的内容和识别出的编解码器名称，无法识别的二进制内容输出为十六进制
+func AutoDecode(data []byte) ([]byte, []string) {
+	names := []string{}
+	for i := 0; i < maxAutoDepth; i++ {
+		name := detect(data)
+		if name == "" {
+			break
+		}
+		decoded, err := codecs[name].Decode(data)
+		if err != nil {
+			break
+		}
+		data = decoded
+		names = append(names, name)
+		if name != "gzip" && name != "zlib" && name != "base64" { //json、msgpack、hex的解码结果已便于阅读
+			break
+		}
+	}
+	return data, names
+}
+
+//识别内容的编码，返回空表示普通文本
+func detect(data []byte) string {
+	switch {
+	case isGzip(data):
+		return "gzip"
+	case isZlib(data):
+		return "zlib"
+	case isJSON(data):
+		return "json"
+	case IsText(data):
+		if isBase64(data) {
+			return "base64"
+		}
+		return ""
+	case isMsgpack(data):
+		return "msgpack"
+	}
+	return "hex"
+}
+
+//是否为可打印的文本
+func IsText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if (r < 0x20 && r != '\t' && r != '\r' && r != '\n') || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,229 @@
+package codec
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func chainNames(chain Chain) []string {
+	names := []string{}
+	for _, codec := range chain {
+		names = append(names, codec.Name)
+	}
+	return names
+}
+
+func TestParseChain(t *testing.T) {
+	tests := []struct {
+		names   string
+		want    []string
+		wantErr bool
+	}{
+		{"gzip", []string{"gzip"}, false},
+		{"gzip,json", []string{"gzip", "json"}, false},
+		{" GZIP , Base64 ,", []string{"gzip", "base64"}, false},
+		{"msgpack,hex", []string{"msgpack", "hex"}, false},
+		{"", nil, true},
+		{",", nil, true},
+		{"gzip,snappy", nil, true},
+	}
+	for _, tt := range tests {
+		chain, err := ParseChain(tt.names)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseChain(%q) error = %v", tt.names, err)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(chainNames(chain), tt.want) {
+			t.Errorf("ParseChain(%q) = %v, want %v", tt.names, chainNames(chain), tt.want)
+		}
+	}
+}
+
+func TestChainRoundTrip(t *testing.T) {
+	tests := []struct {
+		names string
+		input string
+		want  string //解码结果，编码时会压缩json，所以格式化后的json与输入不同
+	}{
+		{"gzip", "hello", "hello"},
+		{"zlib", "hello", "hello"},
+		{"base64", "\x00\x01binary", "\x00\x01binary"},
+		{"gzip,base64", "hello", "hello"},
+		{"json", `{ "b": 1, "a": [1, 2] }`, "{\n  \"b\": 1,\n  \"a\": [\n    1,\n    2\n  ]\n}"},
+		{"gzip,json", `{"a":"x"}`, "{\n  \"a\": \"x\"\n}"},
+		{"msgpack", `{"b":1,"a":[true,null,"s",1.5]}`, "{\n  \"b\": 1,\n  \"a\": [\n    true,\n    null,\n    \"s\",\n    1.5\n  ]\n}"},
+		{"base64,msgpack", `[1,-1,300]`, "[\n  1,\n  -1,\n  300\n]"},
+	}
+	for _, tt := range tests {
+		chain, err := ParseChain(tt.names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := chain.Encode([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%s: 编码失败：%v", tt.names, err)
+			continue
+		}
+		decoded, err := chain.Decode(encoded)
+		if err != nil {
+			t.Errorf("%s: 解码失败：%v", tt.names, err)
+			continue
+		}
+		if string(decoded) != tt.want {
+			t.Errorf("%s: 解码结果为%q，期望%q", tt.names, decoded, tt.want)
+		}
+	}
+}
+
+func TestChainErrors(t *testing.T) {
+	tests := []struct {
+		names  string
+		encode bool
+		input  string
+		errMsg string
+	}{
+		{"protobuf", true, "{}", "protobuf不支持编码"},
+		{"gzip", false, "plain", "gzip解码失败"},
+		{"base64", false, "!!!", "base64解码失败"},
+		{"json", true, "{", "json编码失败"},
+		{"msgpack", false, "\x81", "msgpack解码失败"},
+		{"hex", true, "zz", "hex编码失败"},
+	}
+	for _, tt := range tests {
+		chain, err := ParseChain(tt.names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.encode {
+			_, err = chain.Encode([]byte(tt.input))
+		} else {
+			_, err = chain.Decode([]byte(tt.input))
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), tt.errMsg) {
+			t.Errorf("%s: 错误为%v，期望以%s开头", tt.names, err, tt.errMsg)
+		}
+	}
+}
+
+func TestChainReplace(t *testing.T) {
+	chain, err := ParseChain("base64,protobuf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement := &Codec{Name: "protobuf", Decode: func(data []byte) ([]byte, error) { return []byte("replaced"), nil }}
+	replaced := chain.Replace(replacement)
+	if replaced[1] != replacement || replaced[0] != chain[0] {
+		t.Errorf("Replace未替换同名的编解码器：%v", chainNames(replaced))
+	}
+	if registered, _ := Get("protobuf"); chain[1] != registered {
+		t.Error("Replace修改了原来的编解码链")
+	}
+	if got, err := replaced.Decode([]byte("AA==")); err != nil || string(got) != "replaced" {
+		t.Errorf("替换后解码结果为%q, %v", got, err)
+	}
+}
+
+func TestAutoDecode(t *testing.T) {
+	gzipped, _ := gzipCodec.Encode([]byte(`{"a":1}`))
+	zlibbed, _ := zlibCodec.Encode([]byte("plain text value"))
+	packed, _ := msgpackCodec.Encode([]byte(`{"a":1}`))
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+		names []string
+	}{
+		{"普通文本", []byte("hello world"), "hello world", []string{}},
+		{"普通单词不当作base64", []byte("abcdefgh"), "abcdefgh", []string{}},
+		{"json", []byte(`{"a":1}`), "{\n  \"a\": 1\n}", []string{"json"}},
+		{"gzip+json", gzipped, "{\n  \"a\": 1\n}", []string{"gzip", "json"}},
+		{"zlib", zlibbed, "plain text value", []string{"zlib"}},
+		{"base64+gzip+json", []byte(base64Encode(gzipped)), "{\n  \"a\": 1\n}", []string{"base64", "gzip", "json"}},
+		{"msgpack", packed, "{\n  \"a\": 1\n}", []string{"msgpack"}},
+		{"二进制", []byte{0x00, 0x01}, "00000000  00 01                                             |..|\n", []string{"hex"}},
+	}
+	for _, tt := range tests {
+		got, names := AutoDecode(tt.input)
+		if string(got) != tt.want || !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("%s: AutoDecode = %q, %v，期望%q, %v", tt.name, got, names, tt.want, tt.names)
+		}
+	}
+}
+
+func base64Encode(data []byte) string {
+	encoded, _ := base64Codec.Encode(data)
+	return string(encoded)
+}
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want bool
+	}{
+		{[]byte("hello\tworld\r\n"), true},
+		{[]byte("中文"), true},
+		{[]byte{}, true},
+		{[]byte{0x00}, false},
+		{[]byte{0x7f}, false},
+		{[]byte{0xff, 0xfe}, false},
+	}
+	for _, tt := range tests {
+		if got := IsText(tt.data); got != tt.want {
+			t.Errorf("IsText(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestProtobufWithoutSchema(t *testing.T) {
+	//字段1为varint 150，字段2为字符串"hi"
+	data := []byte{0x08, 0x96, 0x01, 0x12, 0x02, 'h', 'i'}
+	got, err := NewProtobufCodec(nil, "").Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(got, []byte("150")) || !bytes.Contains(got, []byte("hi")) {
+		t.Errorf("解码结果为%s", got)
+	}
+	if _, err := NewProtobufCodec(nil, "").Decode([]byte{0x08}); err == nil {
+		t.Error("不完整的varint应返回错误")
+	}
+}
+
+func TestChainDecodeLimit(t *testing.T) {
+	zeros := make([]byte, 1<<20)
+	gzipped, _ := gzipCodec.Encode(zeros)
+	zlibbed, _ := zlibCodec.Encode(zeros)
+	gzippedJSON, _ := gzipCodec.Encode([]byte(`{"a":[1,2,3]}`))
+	tests := []struct {
+		name    string
+		names   string
+		data    []byte
+		limit   int64
+		wantLen int
+		wantErr bool
+	}{
+		{"gzip未超过限制", "gzip", gzipped, 1 << 20, 1 << 20, false},
+		{"gzip超过限制", "gzip", gzipped, 1<<20 - 1, 0, true},
+		{"zlib超过限制", "zlib", zlibbed, 1024, 0, true},
+		{"base64超过限制", "base64", []byte("aGVsbG8="), 4, 0, true},
+		{"base64未超过限制", "base64", []byte("aGVsbG8="), 5, 5, false},
+		{"格式化json后超过限制", "gzip,json", gzippedJSON, 16, 0, true},
+		{"格式化json后未超过限制", "gzip,json", gzippedJSON, 1024, 36, false},
+	}
+	for _, tt := range tests {
+		chain, err := ParseChain(tt.names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := chain.DecodeLimit(tt.data, tt.limit)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: 错误为%v", tt.name, err)
+			continue
+		}
+		if !tt.wantErr && len(got) != tt.wantLen {
+			t.Errorf("%s: 解码后为%d字节，期望%d字节", tt.name, len(got), tt.wantLen)
+		}
+	}
+}
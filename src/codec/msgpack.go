@@ -0,0 +1,342 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const msgpackMaxDepth = 100 //解析时最大嵌套层数
+
+//解码时将msgpack转换为json，编码时将json转换为msgpack
+var msgpackCodec = &Codec{
+	Name: "msgpack",
+	Decode: func(data []byte) ([]byte, error) {
+		value, err := decodeMsgpack(data)
+		if err != nil {
+			return nil, err
+		}
+		return marshalIndent(value)
+	},
+	Encode: func(data []byte) ([]byte, error) {
+		value, err := parseOrderedJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := writeMsgpack(&buf, value); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	},
+}
+
+//只把完整解析的map和数组识别为msgpack
+func isMsgpack(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	c := data[0]
+	if !(c >= 0x80 && c <= 0x9f) && c != 0xdc && c != 0xdd && c != 0xde && c != 0xdf {
+		return false
+	}
+	_, err := decodeMsgpack(data)
+	return err == nil
+}
+
+//解析完整的msgpack内容
+func decodeMsgpack(data []byte) (interface{}, error) {
+	reader := &msgpackReader{data: data}
+	value, err := reader.read(0)
+	if err != nil {
+		return nil, err
+	}
+	if reader.pos != len(data) {
+		return nil, errors.New("msgpack末尾存在多余的内容")
+	}
+	return value, nil
+}
+
+type msgpackReader struct {
+	data []byte
+	pos  int
+}
+
+var errMsgpackEOF = errors.New("msgpack内容不完整")
+
+func (r *msgpackReader) next(n int) ([]byte, error) {
+	if n < 0 || len(r.data)-r.pos < n {
+		return nil, errMsgpackEOF
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+//读取n字节的大端无符号整数
+func (r *msgpackReader) uint(n int) (uint64, error) {
+	b, err := r.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (r *msgpackReader) read(depth int) (interface{}, error) {
+	if depth > msgpackMaxDepth {
+		return nil, errors.New("msgpack嵌套层数过多")
+	}
+	b, err := r.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c >= 0x80 && c <= 0x8f:
+		return r.readMap(int(c&0x0f), depth)
+	case c >= 0x90 && c <= 0x9f:
+		return r.readArray(int(c&0x0f), depth)
+	case c >= 0xa0 && c <= 0xbf:
+		return r.readString(int(c & 0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6: //bin 8/16/32
+		n, err := r.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		data, err := r.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(data), nil
+	case 0xc7, 0xc8, 0xc9: //ext 8/16/32
+		n, err := r.uint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return r.readExt(int(n))
+	case 0xca:
+		v, err := r.uint(4)
+		return jsonFloat(float64(math.Float32frombits(uint32(v)))), err
+	case 0xcb:
+		v, err := r.uint(8)
+		return jsonFloat(math.Float64frombits(v)), err
+	case 0xcc, 0xcd, 0xce, 0xcf: //uint 8/16/32/64
+		return r.uint(1 << (c - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3: //int 8/16/32/64
+		n := 1 << (c - 0xd0)
+		v, err := r.uint(n)
+		shift := uint(64 - n*8)
+		return int64(v<<shift) >> shift, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8: //fixext 1/2/4/8/16
+		return r.readExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb: //str 8/16/32
+		n, err := r.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return r.readString(int(n))
+	case 0xdc, 0xdd: //array 16/32
+		n, err := r.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.readArray(int(n), depth)
+	case 0xde, 0xdf: //map 16/32
+		n, err := r.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.readMap(int(n), depth)
+	}
+	return nil, fmt.Errorf("不支持的msgpack类型0x%02x", c)
+}
+
+func (r *msgpackReader) readString(n int) (interface{}, error) {
+	b, err := r.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (r *msgpackReader) readArray(n int, depth int) (interface{}, error) {
+	if n > len(r.data)-r.pos { //每个元素至少占用一个字节
+		return nil, errMsgpackEOF
+	}
+	list := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		value, err := r.read(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+func (r *msgpackReader) readMap(n int, depth int) (interface{}, error) {
+	if n*2 > len(r.data)-r.pos {
+		return nil, errMsgpackEOF
+	}
+	m := make(orderedMap, 0, n)
+	for i := 0; i < n; i++ {
+		key, err := r.read(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := r.read(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		keyStr, ok := key.(string)
+		if !ok {
+			content, _ := json.Marshal(key)
+			keyStr = string(content)
+		}
+		m = append(m, orderedItem{Key: keyStr, Value: value})
+	}
+	return m, nil
+}
+
+//读取扩展类型，类型-1为时间戳
+func (r *msgpackReader) readExt(n int) (interface{}, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return nil, err
+	}
+	extType := int8(b[0])
+	data, err := r.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if extType == -1 {
+		var t time.Time
+		switch n {
+		case 4:
+			t = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+		case 8:
+			v := binary.BigEndian.Uint64(data)
+			t = time.Unix(int64(v&0x3ffffffff), int64(v>>34))
+		case 12:
+			t = time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data)))
+		}
+		if !t.IsZero() {
+			return t.Format(time.RFC3339Nano), nil
+		}
+	}
+	return orderedMap{{Key: "ext_type", Value: extType}, {Key: "data", Value: base64.StdEncoding.EncodeToString(data)}}, nil
+}
+
+//json不支持NaN和Inf，转换为字符串
+func jsonFloat(v float64) interface{} {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+//将json值写入为msgpack
+func writeMsgpack(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			writeMsgpackInt(buf, i)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		writeMsgpackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		writeMsgpackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := writeMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+	case orderedMap:
+		writeMsgpackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, item := range v {
+			writeMsgpackHeader(buf, len(item.Key), 0xa0, 32, 0xd9, 0xda, 0xdb)
+			buf.WriteString(item.Key)
+			if err := writeMsgpack(buf, item.Value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的json类型%T", value)
+	}
+	return nil
+}
+
+//写入字符串、数组、map的长度头，fixMax为fix格式的最大长度，code8为0表示没有8位长度的格式
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fixCode byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fixCode | byte(n))
+	case n <= math.MaxUint8 && code8 != 0:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
@@ -0,0 +1,98 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+//保持字段顺序的对象，用于输出json
+type orderedMap []orderedItem
+
+type orderedItem struct {
+	Key   string
+	Value interface{}
+}
+
+func (m orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, item := range m {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		key, err := json.Marshal(item.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+//格式化输出json
+func marshalIndent(value interface{}) ([]byte, error) {
+	return json.MarshalIndent(value, "", "  ")
+}
+
+//解析json并保持对象的字段顺序，数字解析为json.Number
+func parseOrderedJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := readOrderedValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("json末尾存在多余的内容")
+	}
+	return value, nil
+}
+
+func readOrderedValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		m := orderedMap{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, orderedItem{Key: key.(string), Value: value})
+		}
+		_, err = decoder.Token()
+		return m, err
+	case '[':
+		list := []interface{}{}
+		for decoder.More() {
+			value, err := readOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token()
+		return list, err
+	}
+	return nil, errors.New("json格式错误")
+}
@@ -0,0 +1,411 @@
+package codec
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const protoMaxDepth = 64 //解析时最大嵌套层数
+
+//protobuf字段类型，对应FieldDescriptorProto.Type
+const (
+	protoTypeDouble   = 1
+	protoTypeFloat    = 2
+	protoTypeInt64    = 3
+	protoTypeUint64   = 4
+	protoTypeInt32    = 5
+	protoTypeFixed64  = 6
+	protoTypeFixed32  = 7
+	protoTypeBool     = 8
+	protoTypeString   = 9
+	protoTypeMessage  = 11
+	protoTypeBytes    = 12
+	protoTypeUint32   = 13
+	protoTypeEnum     = 14
+	protoTypeSfixed32 = 15
+	protoTypeSfixed64 = 16
+	protoTypeSint32   = 17
+	protoTypeSint64   = 18
+)
+
+//protobuf消息的字段定义
+type protoField struct {
+	Name     string
+	Number   int
+	Type     int
+	Repeated bool
+	TypeName string //消息和枚举类型的全名，不包含开头的.
+}
+
+//protobuf消息定义
+type protoMessage struct {
+	Name   string
+	Fields map[int]*protoField
+}
+
+//从descriptor set文件中解析出的类型定义
+type ProtoSchema struct {
+	Messages map[string]*protoMessage
+	Enums    map[string]map[int64]string
+}
+
+//加载protoc --descriptor_set_out生成的文件
+func LoadProtoSchema(file string) (*ProtoSchema, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	schema := &ProtoSchema{Messages: map[string]*protoMessage{}, Enums: map[string]map[int64]string{}}
+	err = walkProtoFields(data, func(num int, wireType int, value uint64, raw []byte) error {
+		if num == 1 && wireType == 2 { //FileDescriptorSet.file
+			return schema.loadFile(raw)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("解析descriptor set文件出错：%s", err.Error())
+	}
+	return schema, nil
+}
+
+//解析FileDescriptorProto
+func (s *ProtoSchema) loadFile(data []byte) error {
+	pkg := ""
+	messages, enums := [][]byte{}, [][]byte{}
+	err := walkProtoFields(data, func(num int, wireType int, value uint64, raw []byte) error {
+		switch num {
+		case 2:
+			pkg = string(raw)
+		case 4:
+			messages = append(messages, raw)
+		case 5:
+			enums = append(enums, raw)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, raw := range enums {
+		if err := s.loadEnum(pkg, raw); err != nil {
+			return err
+		}
+	}
+	for _, raw := range messages {
+		if err := s.loadMessage(pkg, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//解析DescriptorProto，包括嵌套的消息和枚举
+func (s *ProtoSchema) loadMessage(scope string, data []byte) error {
+	message := &protoMessage{Fields: map[int]*protoField{}}
+	nested, enums, fields := [][]byte{}, [][]byte{}, [][]byte{}
+	err := walkProtoFields(data, func(num int, wireType int, value uint64, raw []byte) error {
+		switch num {
+		case 1:
+			message.Name = joinProtoName(scope, string(raw))
+		case 2:
+			fields = append(fields, raw)
+		case 3:
+			nested = append(nested, raw)
+		case 4:
+			enums = append(enums, raw)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, raw := range fields {
+		field := &protoField{}
+		err := walkProtoFields(raw, func(num int, wireType int, value uint64, raw []byte) error {
+			switch num {
+			case 1:
+				field.Name = string(raw)
+			case 3:
+				field.Number = int(value)
+			case 4:
+				field.Repeated = value == 3 //LABEL_REPEATED
+			case 5:
+				field.Type = int(value)
+			case 6:
+				field.TypeName = strings.TrimPrefix(string(raw), ".")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		message.Fields[field.Number] = field
+	}
+	s.Messages[message.Name] = message
+	for _, raw := range enums {
+		if err := s.loadEnum(message.Name, raw); err != nil {
+			return err
+		}
+	}
+	for _, raw := range nested {
+		if err := s.loadMessage(message.Name, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//解析EnumDescriptorProto
+func (s *ProtoSchema) loadEnum(scope string, data []byte) error {
+	name, values := "", map[int64]string{}
+	err := walkProtoFields(data, func(num int, wireType int, value uint64, raw []byte) error {
+		switch num {
+		case 1:
+			name = joinProtoName(scope, string(raw))
+		case 2:
+			valueName, valueNum := "", int64(0)
+			err := walkProtoFields(raw, func(num int, wireType int, value uint64, raw []byte) error {
+				switch num {
+				case 1:
+					valueName = string(raw)
+				case 2:
+					valueNum = int64(int32(value))
+				}
+				return nil
+			})
+			values[valueNum] = valueName
+			return err
+		}
+		return nil
+	})
+	s.Enums[name] = values
+	return err
+}
+
+func joinProtoName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+//创建protobuf解码器，schema为nil时按字段编号解码
+func NewProtobufCodec(schema *ProtoSchema, messageType string) *Codec {
+	return &Codec{
+		Name: "protobuf",
+		Decode: func(data []byte) ([]byte, error) {
+			var message *protoMessage
+			if schema != nil {
+				var ok bool
+				if message, ok = schema.Messages[strings.TrimPrefix(messageType, ".")]; !ok {
+					return nil, fmt.Errorf("descriptor set中不存在消息类型%s", messageType)
+				}
+			}
+			value, err := decodeProto(schema, message, data, 0)
+			if err != nil {
+				return nil, err
+			}
+			return marshalIndent(value)
+		},
+	}
+}
+
+//遍历protobuf的字段，varint和定长类型的值在value中，长度类型的值在raw中
+func walkProtoFields(data []byte, handle func(num int, wireType int, value uint64, raw []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("字段标签格式错误")
+		}
+		data = data[n:]
+		num, wireType := int(tag>>3), int(tag&7)
+		if num <= 0 {
+			return errors.New("字段编号错误")
+		}
+		var value uint64
+		var raw []byte
+		switch wireType {
+		case 0:
+			if value, n = binary.Uvarint(data); n <= 0 {
+				return errors.New("varint格式错误")
+			}
+			data = data[n:]
+		case 1:
+			if len(data) < 8 {
+				return errors.New("内容不完整")
+			}
+			value, data = binary.LittleEndian.Uint64(data), data[8:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return errors.New("内容不完整")
+			}
+			raw, data = data[n:n+int(length)], data[n+int(length):]
+		case 5:
+			if len(data) < 4 {
+				return errors.New("内容不完整")
+			}
+			value, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		default:
+			return fmt.Errorf("不支持的wire type %d", wireType)
+		}
+		if err := handle(num, wireType, value, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//解码protobuf消息，message为nil时按字段编号输出
+func decodeProto(schema *ProtoSchema, message *protoMessage, data []byte, depth int) (orderedMap, error) {
+	if depth > protoMaxDepth {
+		return nil, errors.New("消息嵌套层数过多")
+	}
+	result := orderedMap{}
+	index := map[string]int{}
+	add := func(name string, value interface{}, repeated bool) {
+		i, ok := index[name]
+		if !ok {
+			index[name] = len(result)
+			if repeated {
+				value = []interface{}{value}
+			}
+			result = append(result, orderedItem{Key: name, Value: value})
+			return
+		}
+		if list, ok := result[i].Value.([]interface{}); ok && repeated {
+			result[i].Value = append(list, value)
+			return
+		}
+		if repeated {
+			result[i].Value = []interface{}{result[i].Value, value}
+			return
+		}
+		result[i].Value = value //非repeated字段出现多次时以最后一次为准
+	}
+	err := walkProtoFields(data, func(num int, wireType int, value uint64, raw []byte) error {
+		var field *protoField
+		if message != nil {
+			field = message.Fields[num]
+		}
+		if field == nil {
+			decoded, err := decodeUnknownProto(wireType, value, raw, depth)
+			if err != nil {
+				return err
+			}
+			_, seen := index[strconv.Itoa(num)]
+			add(strconv.Itoa(num), decoded, seen)
+			return nil
+		}
+		if wireType == 2 && isPackable(field.Type) { //packed repeated
+			return walkPacked(field.Type, raw, func(v uint64) {
+				add(field.Name, schema.protoScalar(field, v), true)
+			})
+		}
+		if wireType == 2 {
+			decoded, err := schema.protoLengthValue(field, raw, depth)
+			if err != nil {
+				return err
+			}
+			add(field.Name, decoded, field.Repeated)
+			return nil
+		}
+		add(field.Name, schema.protoScalar(field, value), field.Repeated)
+		return nil
+	})
+	return result, err
+}
+
+//没有类型定义的字段，长度类型依次尝试解析为文本、嵌套消息，否则输出base64
+func decodeUnknownProto(wireType int, value uint64, raw []byte, depth int) (interface{}, error) {
+	switch wireType {
+	case 0:
+		return value, nil
+	case 1:
+		return value, nil
+	case 5:
+		return value, nil
+	}
+	if IsText(raw) {
+		return string(raw), nil
+	}
+	if nested, err := decodeProto(nil, nil, raw, depth+1); err == nil && len(raw) > 0 {
+		return nested, nil
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+func isPackable(fieldType int) bool {
+	return fieldType != protoTypeString && fieldType != protoTypeBytes && fieldType != protoTypeMessage
+}
+
+//遍历packed repeated字段中的值
+func walkPacked(fieldType int, data []byte, handle func(v uint64)) error {
+	for len(data) > 0 {
+		switch fieldType {
+		case protoTypeDouble, protoTypeFixed64, protoTypeSfixed64:
+			if len(data) < 8 {
+				return errors.New("内容不完整")
+			}
+			handle(binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case protoTypeFloat, protoTypeFixed32, protoTypeSfixed32:
+			if len(data) < 4 {
+				return errors.New("内容不完整")
+			}
+			handle(uint64(binary.LittleEndian.Uint32(data)))
+			data = data[4:]
+		default:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return errors.New("varint格式错误")
+			}
+			handle(v)
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+//按字段类型转换varint和定长类型的值
+func (s *ProtoSchema) protoScalar(field *protoField, v uint64) interface{} {
+	switch field.Type {
+	case protoTypeDouble:
+		return jsonFloat(math.Float64frombits(v))
+	case protoTypeFloat:
+		return jsonFloat(float64(math.Float32frombits(uint32(v))))
+	case protoTypeInt64, protoTypeSfixed64:
+		return int64(v)
+	case protoTypeInt32:
+		return int64(int32(v))
+	case protoTypeSfixed32:
+		return int64(int32(uint32(v)))
+	case protoTypeSint32, protoTypeSint64:
+		return int64(v>>1) ^ -int64(v&1)
+	case protoTypeBool:
+		return v != 0
+	case protoTypeEnum:
+		if name, ok := s.Enums[field.TypeName][int64(int32(v))]; ok {
+			return name
+		}
+		return int64(int32(v))
+	}
+	return v
+}
+
+//按字段类型转换长度类型的值
+func (s *ProtoSchema) protoLengthValue(field *protoField, raw []byte, depth int) (interface{}, error) {
+	switch field.Type {
+	case protoTypeString:
+		return string(raw), nil
+	case protoTypeMessage:
+		return decodeProto(s, s.Messages[field.TypeName], raw, depth+1)
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
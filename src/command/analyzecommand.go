@@ -0,0 +1,290 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//前缀统计树的节点
+type prefixNode struct {
+	keys     int64
+	memory   int64
+	elements int64
+	children map[string]*prefixNode
+}
+
+func (n *prefixNode) add(keyInfo model.RedisKeyInfo) {
+	n.keys++
+	n.memory += keyInfo.Memory
+	n.elements += keyInfo.Elements
+}
+
+func (n *prefixNode) child(prefix string) *prefixNode {
+	if n.children == nil {
+		n.children = map[string]*prefixNode{}
+	}
+	node, ok := n.children[prefix]
+	if !ok {
+		node = &prefixNode{}
+		n.children[prefix] = node
+	}
+	return node
+}
+
+//按前缀统计的行
+type prefixStatRow struct {
+	Prefix   string `json:"prefix"`
+	Keys     int64  `json:"keys"`
+	Bytes    int64  `json:"bytes"`
+	Memory   string `json:"memory"`
+	Percent  string `json:"percent"`
+	Elements int64  `json:"elements"`
+}
+
+//大key的行
+type bigKeyRow struct {
+	Key      string `json:"key"`
+	Type     string `json:"type"`
+	Bytes    int64  `json:"bytes"`
+	Memory   string `json:"memory"`
+	Elements int64  `json:"elements"`
+	TTL      string `json:"ttl"`
+}
+
+//按类型统计的行
+type typeStatRow struct {
+	Type     string `json:"type"`
+	Keys     int64  `json:"keys"`
+	Bytes    int64  `json:"bytes"`
+	Memory   string `json:"memory"`
+	Elements int64  `json:"elements"`
+}
+
+//过期时间覆盖率的行
+type ttlStatRow struct {
+	Item    string `json:"item"`
+	Keys    int64  `json:"keys"`
+	Percent string `json:"percent"`
+}
+
+//分析报告
+type analyzeReport struct {
+	Keys     int64           `json:"keys"`
+	Bytes    int64           `json:"bytes"`
+	Prefixes []prefixStatRow `json:"prefixes"`
+	TopKeys  []bigKeyRow     `json:"top_keys"`
+	Types    []typeStatRow   `json:"types"`
+	TTL      []ttlStatRow    `json:"ttl"`
+}
+
+func percent(part, total int64) string {
+	if total == 0 {
+		return "0.00%"
+	}
+	return fmt.Sprintf("%.2f%%", float64(part)*100/float64(total))
+}
+
+func formatTTL(ttl int64) string {
+	if ttl < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fs", float64(ttl)/1000)
+}
+
+//分析报告支持的参数
+var analyzeFlags = []string{"sample", "delimiter", "depth", "top", "json"}
+
+//分析报告的参数
+type analyzeOptions struct {
+	delimiter string //前缀分隔符
+	sample    int    //抽样数量，0表示全部分析
+	depth     int    //前缀树的深度
+	top       int    //展示的大key和每层前缀的数量
+	asJSON    bool
+}
+
+//从命令参数中解析分析报告的参数
+func parseAnalyzeOptions(flags map[string]string) (*analyzeOptions, error) {
+	opts := &analyzeOptions{delimiter: ":", depth: 2, top: 10}
+	if value, ok := flags["delimiter"]; ok {
+		opts.delimiter = value
+	}
+	for name, value := range map[string]*int{"sample": &opts.sample, "depth": &opts.depth, "top": &opts.top} {
+		if str, ok := flags[name]; ok {
+			num, err := strconv.Atoi(str)
+			if err != nil || num < 0 {
+				return nil, fmt.Errorf("参数--%s必须是非负整数", name)
+			}
+			*value = num
+		}
+	}
+	_, opts.asJSON = flags["json"]
+	return opts, nil
+}
+
+//分析当前数据库的内存占用
+func analyzeCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "no-ttl", "json")
+	if err == nil {
+		err = checkCMDFlags(flags, append(keyFilterFlags, analyzeFlags...)...)
+	}
+	if err != nil {
+		return err
+	}
+	filter, err := parseKeyFilter(flags)
+	if err != nil {
+		return err
+	}
+	opts, err := parseAnalyzeOptions(flags)
+	if err != nil {
+		return err
+	}
+	pattern := "*"
+	if len(cmdParams) >= 2 {
+		pattern = cmdParams[1]
+	}
+	keyInfoChan := make(chan model.RedisKeyInfo, 1000)
+	go db.AnalyzeRedisKeys(pattern, opts.sample, filter, keyInfoChan)
+	printAnalyzeReport(buildAnalyzeReport(keyInfoChan, opts), opts)
+	return nil
+}
+
+//输出分析报告
+func printAnalyzeReport(report *analyzeReport, opts *analyzeOptions) {
+	if opts.asJSON {
+		printRows(report, true)
+		return
+	}
+	log.Printf("共分析%d个key，总内存占用%s", report.Keys, util.FormatByteSize(report.Bytes))
+	log.Println("按前缀统计：")
+	printRows(report.Prefixes, false)
+	log.Printf("内存占用最大的%d个key：", opts.top)
+	printRows(report.TopKeys, false)
+	log.Println("按类型统计：")
+	printRows(report.Types, false)
+	log.Println("过期时间覆盖率：")
+	printRows(report.TTL, false)
+}
+
+//汇总key的统计信息生成分析报告
+func buildAnalyzeReport(keyInfoChan chan model.RedisKeyInfo, opts *analyzeOptions) *analyzeReport {
+	delimiter, depth, top := opts.delimiter, opts.depth, opts.top
+	root := &prefixNode{}
+	typeNodes := map[string]*prefixNode{}
+	topKeys := []model.RedisKeyInfo{}
+	var withTTL int64
+	for keyInfo := range keyInfoChan {
+		root.add(keyInfo)
+		if !opts.asJSON && root.keys%10000 == 0 {
+			log.Printf("已分析%d个key...", root.keys)
+		}
+		node := root
+		segments := strings.Split(keyInfo.Key, delimiter)
+		if len(segments) == 1 || depth == 0 {
+			root.child("(无前缀)").add(keyInfo)
+		}
+		for i := 0; i < depth && i < len(segments)-1; i++ {
+			node = node.child(strings.Join(segments[:i+1], delimiter) + delimiter + "*")
+			node.add(keyInfo)
+		}
+		typeNode, ok := typeNodes[keyInfo.Type]
+		if !ok {
+			typeNode = &prefixNode{}
+			typeNodes[keyInfo.Type] = typeNode
+		}
+		typeNode.add(keyInfo)
+		if keyInfo.TTL >= 0 {
+			withTTL++
+		}
+		topKeys = append(topKeys, keyInfo)
+		if len(topKeys) >= 2*top+1000 {
+			topKeys = sortTopKeys(topKeys, top)
+		}
+	}
+
+	report := &analyzeReport{Keys: root.keys, Bytes: root.memory}
+	report.Prefixes = prefixStatRows(root, 0, top, root.memory)
+	for _, keyInfo := range sortTopKeys(topKeys, top) {
+		report.TopKeys = append(report.TopKeys, bigKeyRow{
+			Key:      keyInfo.Key,
+			Type:     keyInfo.Type,
+			Bytes:    keyInfo.Memory,
+			Memory:   util.FormatByteSize(keyInfo.Memory),
+			Elements: keyInfo.Elements,
+			TTL:      formatTTL(keyInfo.TTL),
+		})
+	}
+	for keyType, node := range typeNodes {
+		report.Types = append(report.Types, typeStatRow{
+			Type:     keyType,
+			Keys:     node.keys,
+			Bytes:    node.memory,
+			Memory:   util.FormatByteSize(node.memory),
+			Elements: node.elements,
+		})
+	}
+	sort.Slice(report.Types, func(i, j int) bool { return report.Types[i].Bytes > report.Types[j].Bytes })
+	report.TTL = []ttlStatRow{
+		{Item: "有过期时间", Keys: withTTL, Percent: percent(withTTL, root.keys)},
+		{Item: "无过期时间", Keys: root.keys - withTTL, Percent: percent(root.keys-withTTL, root.keys)},
+	}
+	return report
+}
+
+//按内存占用倒序取前top个key
+func sortTopKeys(keyInfos []model.RedisKeyInfo, top int) []model.RedisKeyInfo {
+	sort.Slice(keyInfos, func(i, j int) bool { return keyInfos[i].Memory > keyInfos[j].Memory })
+	if len(keyInfos) > top {
+		keyInfos = keyInfos[:top]
+	}
+	return keyInfos
+}
+
+//深度优先展开前缀树，每层只展示内存占用最大的top个前缀，其余合并为一行
+func prefixStatRows(node *prefixNode, level, top int, total int64) []prefixStatRow {
+	rows := []prefixStatRow{}
+	prefixes := make([]string, 0, len(node.children))
+	for prefix := range node.children {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return node.children[prefixes[i]].memory > node.children[prefixes[j]].memory
+	})
+	indent := strings.Repeat("  ", level)
+	other := &prefixNode{}
+	for i, prefix := range prefixes {
+		child := node.children[prefix]
+		if top > 0 && i >= top {
+			other.keys += child.keys
+			other.memory += child.memory
+			other.elements += child.elements
+			continue
+		}
+		rows = append(rows, prefixStatRow{
+			Prefix:   indent + prefix,
+			Keys:     child.keys,
+			Bytes:    child.memory,
+			Memory:   util.FormatByteSize(child.memory),
+			Percent:  percent(child.memory, total),
+			Elements: child.elements,
+		})
+		rows = append(rows, prefixStatRows(child, level+1, top, total)...)
+	}
+	if other.keys > 0 {
+		rows = append(rows, prefixStatRow{
+			Prefix:   fmt.Sprintf("%s(其他%d个前缀)", indent, len(prefixes)-top),
+			Keys:     other.keys,
+			Bytes:    other.memory,
+			Memory:   util.FormatByteSize(other.memory),
+			Percent:  percent(other.memory, total),
+			Elements: other.elements,
+		})
+	}
+	return rows
+}
@@ -0,0 +1,166 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"rediscmd/src/aof"
+	"rediscmd/src/conf"
+	"rediscmd/src/db"
+	"rediscmd/src/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//按命令统计的行
+type cmdStatRow struct {
+	Command string `json:"command"`
+	Count   int64  `json:"count"`
+}
+
+//解析aof文件或命令流文件
+func aofCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams)
+	if err == nil {
+		err = checkCMDFlags(flags, "db", "cmd", "to", "rate")
+	}
+	if err != nil {
+		return err
+	}
+	if !checkCMDParamsCount(cmdParams, 3) {
+		return fmt.Errorf("解析aof文件需要至少3个参数：aof [inspect|replay] [file|dir|manifest]，请重新输入")
+	}
+	match, err := parseKeyMatcher(cmdParams[3:])
+	if err != nil {
+		return err
+	}
+	hasPattern := len(cmdParams) > 3
+	dbid := -1
+	if str, ok := flags["db"]; ok {
+		if dbid, err = strconv.Atoi(str); err != nil || dbid < 0 {
+			return fmt.Errorf("无法解析您输入的数据库编号")
+		}
+	}
+	cmdNames := map[string]bool{}
+	if str, ok := flags["cmd"]; ok {
+		for _, name := range strings.Split(str, ",") {
+			cmdNames[strings.ToLower(name)] = true
+		}
+	}
+	//按数据库、命令名称和key过滤命令
+	matchCommand := func(cmd *aof.Command) bool {
+		if dbid >= 0 && cmd.DB != dbid {
+			return false
+		}
+		if len(cmdNames) > 0 && !cmdNames[cmd.Name()] {
+			return false
+		}
+		if !hasPattern {
+			return true
+		}
+		for _, key := range cmd.Keys() {
+			if match(key) {
+				return true
+			}
+		}
+		return false
+	}
+	switch cmdParams[1] {
+	case "inspect":
+		return aofInspectCMD(cmdParams[2], matchCommand)
+	case "replay":
+		rate := 0
+		if str, ok := flags["rate"]; ok {
+			if rate, err = strconv.Atoi(str); err != nil || rate < 0 {
+				return fmt.Errorf("参数--rate必须是非负整数")
+			}
+		}
+		return aofReplayCMD(cmdParams[2], flags["to"], rate, matchCommand, hasPattern || dbid >= 0 || len(cmdNames) > 0)
+	}
+	return fmt.Errorf("aof不支持【%s】操作，仅支持inspect|replay", cmdParams[1])
+}
+
+//查看aof文件中的命令
+func aofInspectCMD(file string, matchCommand func(cmd *aof.Command) bool) error {
+	stats := map[string]int64{}
+	var total int64
+	err := aof.ParseFile(file, func(cmd *aof.Command) bool {
+		total++
+		if !matchCommand(cmd) {
+			return true
+		}
+		stats[cmd.Name()]++
+		if cmd.Time > 0 {
+			log.Printf("db(%d) [%s] %s", cmd.DB, time.Unix(cmd.Time, 0).Format("2006-01-02 15:04:05"), util.FormatCommand(cmd.Args))
+		} else {
+			log.Printf("db(%d) %s", cmd.DB, util.FormatCommand(cmd.Args))
+		}
+		return true
+	})
+	rows := make([]cmdStatRow, 0, len(stats))
+	var matched int64
+	for name, count := range stats {
+		rows = append(rows, cmdStatRow{Command: name, Count: count})
+		matched += count
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Count > rows[j].Count })
+	log.Printf("共解析%d条命令，匹配%d条", total, matched)
+	printRows(rows, false)
+	if err != nil {
+		return fmt.Errorf("解析aof文件出错：%s", err.Error())
+	}
+	return nil
+}
+
+//将aof文件中的命令重放到指定配置文件的redis，filtered为true时只重放匹配的命令并自动切换数据库
+func aofReplayCMD(file, profile string, rate int, matchCommand func(cmd *aof.Command) bool, filtered bool) error {
+	if profile == "" {
+		return fmt.Errorf("请通过--to指定重放的目标配置")
+	}
+	targetConf, err := conf.GetProfileRedisConf(profile)
+	if err != nil {
+		return err
+	}
+	if targetConf.Redis.ReadOnly {
+		return fmt.Errorf("目标配置%s为只读配置，禁止重放命令", profile)
+	}
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("此操作将把%s中的命令写入%s，请确认是否执行此操作(y/n):", file, conf.ProfileConfAbsPath(profile)), false)
+	if isSure != "y" {
+		return nil
+	}
+	cmdChan := make(chan []string, 1000)
+	var parseErr error
+	go func() {
+		defer close(cmdChan)
+		lastDB := -1
+		parseErr = aof.ParseFile(file, func(cmd *aof.Command) bool {
+			if !filtered {
+				cmdChan <- cmd.Args
+				return true
+			}
+			if cmd.Name() == "select" || !matchCommand(cmd) {
+				return true
+			}
+			if cmd.DB != lastDB {
+				cmdChan <- []string{"select", strconv.Itoa(cmd.DB)}
+				lastDB = cmd.DB
+			}
+			cmdChan <- cmd.Args
+			return true
+		})
+	}()
+	start := time.Now()
+	succeed, failed, err := db.ReplayRedisCommands(profile, rate, cmdChan)
+	if err != nil {
+		return err
+	}
+	log.Printf("重放结束，成功%d条，失败%d条，耗时%d毫秒", succeed, failed, time.Since(start).Milliseconds())
+	if parseErr != nil {
+		return fmt.Errorf("解析aof文件出错：%s", parseErr.Error())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d条命令重放失败", failed)
+	}
+	return nil
+}
@@ -0,0 +1,563 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"rediscmd/src/codec"
+	"rediscmd/src/conf"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/rdb"
+	"rediscmd/src/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	browsePageKeys   = 2000 //每次加载目录时最多scan的key数量
+	browseValueLimit = 500  //值窗格中集合类型最多展示的元素数量
+	browseDelimiter  = ":"  //key的目录分隔符
+	browseHelp       = "↑↓移动 →展开 ←折叠 Enter打开 Tab切换窗格 /搜索 d切换数据库 r刷新 x删除 q退出"
+)
+
+//keyspace树的节点
+type browseNode struct {
+	name     string
+	prefix   string //目录节点为包含分隔符的前缀，key节点为完整的key
+	pattern  string //目录节点scan使用的模式
+	isKey    bool
+	isMore   bool //加载更多的节点
+	flat     bool //搜索结果不按分隔符拆分目录
+	depth    int
+	parent   *browseNode
+	children []*browseNode
+	index    map[string]*browseNode
+	count    int //已扫描到的子孙key数量
+	expanded bool
+	loaded   bool
+	cursor   string
+}
+
+//创建目录节点
+func newBrowseFolder(parent *browseNode, name, prefix string) *browseNode {
+	node := &browseNode{name: name, prefix: prefix, pattern: util.EscapeGlob(prefix) + "*", parent: parent, index: map[string]*browseNode{}, cursor: "0"}
+	if parent != nil {
+		node.depth = parent.depth + 1
+	}
+	return node
+}
+
+//scan加载目录的下一批key
+func (n *browseNode) load() error {
+	keys, cursor, err := db.ScanRedisKeysPage(n.pattern, n.cursor, browsePageKeys)
+	if err != nil {
+		return err
+	}
+	n.loaded, n.cursor = true, cursor
+	for _, key := range keys {
+		n.addKey(key)
+	}
+	children := make([]*browseNode, 0, len(n.children)+1)
+	for _, child := range n.children {
+		if !child.isMore {
+			children = append(children, child)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].isKey != children[j].isKey {
+			return !children[i].isKey //目录在前
+		}
+		return children[i].name < children[j].name
+	})
+	if cursor != "0" {
+		children = append(children, &browseNode{name: "… 加载更多", isMore: true, parent: n, depth: n.depth + 1})
+	}
+	n.children = children
+	return nil
+}
+
+//将扫描到的key加入目录，key中还有分隔符时归入子目录
+func (n *browseNode) addKey(key string) {
+	if !strings.HasPrefix(key, n.prefix) {
+		return
+	}
+	rest := key[len(n.prefix):]
+	if i := strings.Index(rest, browseDelimiter); i >= 0 && !n.flat {
+		child, ok := n.index["d:"+rest[:i]]
+		if !ok {
+			child = newBrowseFolder(n, rest[:i], n.prefix+rest[:i+len(browseDelimiter)])
+			n.index["d:"+rest[:i]] = child
+			n.children = append(n.children, child)
+		}
+		child.count++
+		return
+	}
+	if _, ok := n.index["k:"+key]; ok {
+		return
+	}
+	name := rest
+	if n.flat {
+		name = key
+	}
+	child := &browseNode{name: name, prefix: key, isKey: true, parent: n, depth: n.depth + 1}
+	n.index["k:"+key] = child
+	n.children = append(n.children, child)
+}
+
+//从父目录中移除节点
+func (n *browseNode) remove() {
+	parent := n.parent
+	for i, child := range parent.children {
+		if child == n {
+			parent.children = append(parent.children[:i], parent.children[i+1:]...)
+			break
+		}
+	}
+	delete(parent.index, "k:"+n.prefix)
+}
+
+//全屏浏览界面的状态
+type browser struct {
+	root        *browseNode
+	rows        []*browseNode //展开后可见的节点
+	selected    int
+	offset      int
+	search      string
+	focusValue  bool
+	valueKey    string
+	valueLines  []string
+	valueOffset int
+	status      string
+	prompt      string
+	input       string
+	onSubmit    func(input string)
+	height      int
+	width       int
+}
+
+//全屏浏览keyspace
+func browseCMD(cmdParams []string) error {
+	restore, err := util.TerminalRawMode()
+	if err != nil {
+		return err
+	}
+	logWriter := log.Writer()
+	log.SetOutput(ioutil.Discard) //避免日志输出破坏界面
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		restore()
+		log.SetOutput(logWriter)
+	}()
+	b := &browser{}
+	b.reset()
+	buf := make([]byte, 64)
+	for {
+		b.render()
+		n, err := os.Stdin.Read(buf)
+		if err != nil || !b.handleKey(string(buf[:n])) {
+			return nil
+		}
+	}
+}
+
+//重新加载keyspace树，search不为空时展示搜索结果
+func (b *browser) reset() {
+	if b.search == "" {
+		b.root = newBrowseFolder(nil, "", "")
+	} else {
+		b.root = &browseNode{pattern: b.search, flat: true, index: map[string]*browseNode{}, cursor: "0"}
+	}
+	b.root.expanded = true
+	b.selected, b.offset, b.valueKey, b.focusValue = 0, 0, "", false
+	b.loadNode(b.root)
+}
+
+func (b *browser) loadNode(node *browseNode) {
+	if err := node.load(); err != nil {
+		b.status = err.Error()
+	}
+	b.refreshRows()
+}
+
+//重新计算可见的节点
+func (b *browser) refreshRows() {
+	b.rows = b.rows[:0]
+	var walk func(node *browseNode)
+	walk = func(node *browseNode) {
+		for _, child := range node.children {
+			b.rows = append(b.rows, child)
+			if child.expanded {
+				walk(child)
+			}
+		}
+	}
+	walk(b.root)
+	if b.selected >= len(b.rows) {
+		b.selected = len(b.rows) - 1
+	}
+	if b.selected < 0 {
+		b.selected = 0
+	}
+}
+
+func (b *browser) current() *browseNode {
+	if b.selected < len(b.rows) {
+		return b.rows[b.selected]
+	}
+	return nil
+}
+
+//处理按键，返回false时退出界面
+func (b *browser) handleKey(key string) bool {
+	if b.prompt != "" {
+		b.handlePromptKey(key)
+		return true
+	}
+	b.status = ""
+	pageSize := b.height - 3
+	switch key {
+	case "q", "\x03":
+		return false
+	case "\t":
+		b.focusValue = !b.focusValue
+	case "\x1b[A", "k":
+		b.move(-1)
+	case "\x1b[B", "j":
+		b.move(1)
+	case "\x1b[5~":
+		b.move(-pageSize)
+	case "\x1b[6~":
+		b.move(pageSize)
+	case "\x1b[C", "l":
+		b.expand()
+	case "\x1b[D", "h":
+		b.collapse()
+	case "\r", "\n":
+		b.open()
+	case "\x1b":
+		if b.search != "" {
+			b.search = ""
+			b.reset()
+		}
+	case "/":
+		b.ask("搜索key(glob，回车为空时返回目录树)：", func(input string) {
+			b.search = input
+			b.reset()
+		})
+	case "d":
+		b.ask(fmt.Sprintf("切换数据库[0~%d)：", db.RedisDBCount()), b.changeDB)
+	case "r":
+		b.refresh()
+	case "x":
+		b.deleteKey()
+	}
+	return true
+}
+
+//在底部输入内容
+func (b *browser) ask(prompt string, onSubmit func(input string)) {
+	b.prompt, b.input, b.onSubmit = prompt, "", onSubmit
+}
+
+func (b *browser) handlePromptKey(key string) {
+	switch key {
+	case "\x1b", "\x03":
+		b.prompt = ""
+	case "\r", "\n":
+		b.prompt = ""
+		b.onSubmit(strings.TrimSpace(b.input))
+	case "\x7f", "\b":
+		if b.input != "" {
+			_, size := utf8.DecodeLastRuneInString(b.input)
+			b.input = b.input[:len(b.input)-size]
+		}
+	default:
+		if utf8.ValidString(key) && !strings.HasPrefix(key, "\x1b") && key[0] >= 0x20 {
+			b.input += key
+		}
+	}
+}
+
+func (b *browser) move(step int) {
+	if b.focusValue {
+		b.valueOffset += step
+		if b.valueOffset > len(b.valueLines)-1 {
+			b.valueOffset = len(b.valueLines) - 1
+		}
+		if b.valueOffset < 0 {
+			b.valueOffset = 0
+		}
+		return
+	}
+	b.selected += step
+	if b.selected >= len(b.rows) {
+		b.selected = len(b.rows) - 1
+	}
+	if b.selected < 0 {
+		b.selected = 0
+	}
+}
+
+//展开目录，key节点切换到值窗格
+func (b *browser) expand() {
+	node := b.current()
+	switch {
+	case node == nil || b.focusValue:
+	case node.isMore:
+		b.loadNode(node.parent)
+	case node.isKey:
+		b.focusValue = true
+	default:
+		node.expanded = true
+		if !node.loaded {
+			b.loadNode(node)
+		}
+		b.refreshRows()
+	}
+}
+
+//折叠目录，已折叠时选中父目录
+func (b *browser) collapse() {
+	node := b.current()
+	if b.focusValue || node == nil {
+		b.focusValue = false
+		return
+	}
+	if !node.isKey && node.expanded {
+		node.expanded = false
+		b.refreshRows()
+		return
+	}
+	for i, row := range b.rows {
+		if row == node.parent {
+			b.selected = i
+		}
+	}
+}
+
+func (b *browser) open() {
+	node := b.current()
+	if node != nil && !node.isKey && !node.isMore && node.expanded {
+		b.collapse()
+		return
+	}
+	b.expand()
+}
+
+func (b *browser) changeDB(input string) {
+	dbid, err := strconv.Atoi(input)
+	if err != nil || dbid < 0 || dbid >= db.RedisDBCount() {
+		b.status = fmt.Sprintf("请输入[0~%d)的数据库编号", db.RedisDBCount())
+		return
+	}
+	db.ChangeRedisOptionDBId(dbid)
+	b.search = ""
+	b.reset()
+}
+
+//重新加载选中的目录（key节点为其所在目录）和值
+func (b *browser) refresh() {
+	node := b.current()
+	if node == nil {
+		b.reset()
+		return
+	}
+	if node.isKey || node.isMore {
+		node = node.parent
+	}
+	node.children, node.index, node.cursor, node.loaded = nil, map[string]*browseNode{}, "0", false
+	if node.parent == nil || node.expanded {
+		b.loadNode(node)
+	}
+	b.valueKey = ""
+	b.refreshRows()
+}
+
+func (b *browser) deleteKey() {
+	node := b.current()
+	if node == nil || !node.isKey {
+		b.status = "只能删除key节点"
+		return
+	}
+	if db.IsRedisReadOnly() {
+		b.status = fmt.Sprintf("当前配置%s为只读配置，禁止删除", conf.RedisConfName())
+		return
+	}
+	b.ask(fmt.Sprintf("确认删除%s？(y/n)：", node.prefix), func(input string) {
+		if input != "y" {
+			return
+		}
+		if err := db.DeleteRedisKey(node.prefix); err != nil {
+			b.status = fmt.Sprintf("删除失败：%s", err.Error())
+			return
+		}
+		node.remove()
+		b.valueKey = ""
+		b.refreshRows()
+		b.status = fmt.Sprintf("已删除%s", node.prefix)
+	})
+}
+
+//渲染界面
+func (b *browser) render() {
+	b.height, b.width = util.TerminalSize()
+	bodyHeight := b.height - 2
+	if b.selected < b.offset {
+		b.offset = b.selected
+	}
+	if b.selected >= b.offset+bodyHeight {
+		b.offset = b.selected - bodyHeight + 1
+	}
+	if node := b.current(); node != nil && node.isKey && node.prefix != b.valueKey {
+		b.valueKey, b.valueOffset = node.prefix, 0
+		b.valueLines = browseValueLines(node.prefix)
+	} else if node == nil || !node.isKey {
+		b.valueKey, b.valueLines = "", nil
+	}
+	treeWidth := b.width * 2 / 5
+	if treeWidth < 20 {
+		treeWidth = 20
+	}
+	valueWidth := b.width - treeWidth - 1
+	if valueWidth < 0 {
+		valueWidth = 0
+	}
+
+	var screen strings.Builder
+	screen.WriteString("\x1b[H")
+	title := fmt.Sprintf(" rediscmd browse │ %s │ db(%d)", conf.RedisConfName(), db.RedisOptionDBId())
+	if db.IsRedisReadOnly() {
+		title += " │ 只读"
+	}
+	if b.search != "" {
+		title += fmt.Sprintf(" │ 搜索：%s（Esc返回）", b.search)
+	}
+	screen.WriteString("\x1b[7m" + util.FitWidth(title, b.width) + "\x1b[0m\r\n")
+	for i := 0; i < bodyHeight; i++ {
+		row := b.offset + i
+		line := ""
+		if row < len(b.rows) {
+			line = browseRowText(b.rows[row])
+		} else if row == 0 {
+			line = "（没有key）"
+		}
+		line = util.FitWidth(line, treeWidth)
+		if row == b.selected && row < len(b.rows) {
+			if b.focusValue {
+				line = "\x1b[4m" + line + "\x1b[0m"
+			} else {
+				line = "\x1b[7m" + line + "\x1b[0m"
+			}
+		}
+		screen.WriteString(line + "│")
+		valueLine := ""
+		if b.valueOffset+i < len(b.valueLines) {
+			valueLine = b.valueLines[b.valueOffset+i]
+		}
+		screen.WriteString(util.FitWidth(valueLine, valueWidth) + "\r\n")
+	}
+	bottom := browseHelp
+	if b.prompt != "" {
+		bottom = b.prompt + b.input + "█"
+	} else if b.status != "" {
+		bottom = b.status
+	}
+	screen.WriteString(util.FitWidth(bottom, b.width))
+	fmt.Print(screen.String())
+}
+
+//树节点的展示内容
+func browseRowText(node *browseNode) string {
+	indent := strings.Repeat("  ", node.depth-1)
+	switch {
+	case node.isMore:
+		return indent + "  " + node.name
+	case node.isKey:
+		return indent + "  " + node.name
+	}
+	marker := "▸ "
+	if node.expanded {
+		marker = "▾ "
+	}
+	count := strconv.Itoa(node.count)
+	if node.parent.cursor != "0" {
+		count += "+" //父目录还没有扫描完
+	}
+	name := node.name
+	if name == "" {
+		name = "（空）"
+	}
+	return fmt.Sprintf("%s%s%s%s (%s)", indent, marker, name, browseDelimiter, count)
+}
+
+//值窗格的内容，按数据类型展示
+func browseValueLines(key string) []string {
+	lines := []string{"key：" + browseText(key)}
+	info, err := db.GetRedisKeyInfo(key)
+	if err != nil {
+		return append(lines, err.Error())
+	}
+	ttl := "永不过期"
+	if info.TTL >= 0 {
+		ttl = (time.Duration(info.TTL) * time.Millisecond).String()
+	}
+	lines = append(lines, fmt.Sprintf("类型：%s  元素：%d  内存：%s  TTL：%s", info.Type, info.Elements, util.FormatByteSize(info.Memory), ttl), "")
+	value, err := db.GetRedisKeyValue(key, browseValueLimit)
+	if err != nil {
+		return append(lines, err.Error())
+	}
+	shown := 0
+	switch v := value.Value.(type) {
+	case string:
+		decoded, names := codec.AutoDecode([]byte(v))
+		if len(names) > 0 {
+			lines = append(lines, "解码："+strings.Join(names, ","), "")
+		}
+		return append(lines, strings.Split(strings.ReplaceAll(string(decoded), "\t", "    "), "\n")...)
+	case []string:
+		for i, item := range v {
+			if value.Type == "list" {
+				lines = append(lines, fmt.Sprintf("[%d] %s", i, browseText(item)))
+			} else {
+				lines = append(lines, browseText(item))
+			}
+		}
+		shown = len(v)
+	case []model.KV:
+		for _, kv := range v {
+			if value.Type == "zset" {
+				lines = append(lines, fmt.Sprintf("%s  %s", kv.Value, browseText(kv.Key)))
+			} else {
+				lines = append(lines, fmt.Sprintf("%s = %s", browseText(kv.Key), browseText(kv.Value)))
+			}
+		}
+		shown = len(v)
+	case []rdb.StreamEntry:
+		for _, entry := range v {
+			fields := make([]string, 0, len(entry.Fields))
+			for _, kv := range entry.Fields {
+				fields = append(fields, browseText(kv.Key)+"="+browseText(kv.Value))
+			}
+			lines = append(lines, entry.ID+"  "+strings.Join(fields, " "))
+		}
+		shown = len(v)
+	}
+	if int64(shown) < info.Elements {
+		lines = append(lines, "", fmt.Sprintf("……仅展示前%d个元素，共%d个", shown, info.Elements))
+	}
+	return lines
+}
+
+//单行展示的文本，包含换行或二进制内容时转义
+func browseText(str string) string {
+	if codec.IsText([]byte(str)) && !strings.ContainsAny(str, "\r\n\t") {
+		return str
+	}
+	return strconv.Quote(str)
+}
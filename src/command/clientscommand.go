@@ -0,0 +1,204 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"rediscmd/src/conf"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//客户端连接的行
+type clientRow struct {
+	ID    int64  `json:"id"`
+	Addr  string `json:"addr"`
+	Name  string `json:"name"`
+	DB    int    `json:"db"`
+	Age   string `json:"age"`
+	Idle  string `json:"idle"`
+	Flags string `json:"flags"`
+	Cmd   string `json:"cmd"`
+}
+
+//按来源ip或名称分组的行
+type clientGroupRow struct {
+	Group   string `json:"group"`
+	Clients int    `json:"clients"`
+	MaxIdle string `json:"max_idle"`
+	MaxAge  string `json:"max_age"`
+}
+
+//从命令参数中解析客户端的过滤条件
+func parseClientFilter(flags map[string]string) (*model.ClientFilter, error) {
+	filter := &model.ClientFilter{Addr: strings.Trim(flags["addr"], "'\""), Name: strings.Trim(flags["name"], "'\"")}
+	var err error
+	if expr, ok := flags["idle"]; ok {
+		filter.Idle, err = parseNumCond(expr, func(str string) (int64, error) {
+			d, err := util.ParseDuration(str)
+			return int64(d.Seconds()), err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("--idle参数错误：%s", err.Error())
+		}
+	}
+	if expr, ok := flags["db"]; ok {
+		filter.DB, err = parseNumCond(expr, func(str string) (int64, error) {
+			return strconv.ParseInt(str, 10, 64)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("--db参数错误：%s", err.Error())
+		}
+	}
+	return filter, nil
+}
+
+//判断客户端是否满足过滤条件，地址只写ip时匹配该ip的所有端口
+func matchClient(filter *model.ClientFilter, client model.RedisClient) bool {
+	if filter.Addr != "" && !util.GlobMatch(filter.Addr, client.Addr) && !util.GlobMatch(filter.Addr+":*", client.Addr) {
+		return false
+	}
+	if filter.Name != "" && !util.GlobMatch(filter.Name, client.Name) {
+		return false
+	}
+	if filter.Idle != nil && !filter.Idle.Match(client.Idle) {
+		return false
+	}
+	return filter.DB == nil || filter.DB.Match(int64(client.DB))
+}
+
+//客户端地址中的ip
+func clientIP(addr string) string {
+	if index := strings.LastIndex(addr, ":"); index >= 0 {
+		return addr[:index]
+	}
+	return addr
+}
+
+func formatSeconds(sec int64) string {
+	return (time.Duration(sec) * time.Second).String()
+}
+
+//查看客户端连接 clients [kill [地址]] [--addr ip或glob] [--name glob] [--idle '>300'] [--db n] [--group ip|name]
+func clientsCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "json")
+	if err == nil {
+		err = checkCMDFlags(flags, "addr", "name", "idle", "db", "group", "json")
+	}
+	if err != nil {
+		return err
+	}
+	kill := len(cmdParams) > 1 && cmdParams[1] == "kill"
+	if (len(cmdParams) > 1 && !kill) || len(cmdParams) > 3 {
+		return fmt.Errorf("clients仅支持kill [地址]参数，过滤条件通过--addr、--name、--idle、--db指定")
+	}
+	if len(cmdParams) == 3 {
+		if _, ok := flags["addr"]; ok {
+			return fmt.Errorf("地址参数与--addr不能同时使用")
+		}
+		flags["addr"] = cmdParams[2]
+	}
+	filter, err := parseClientFilter(flags)
+	if err != nil {
+		return err
+	}
+	groupBy, group := flags["group"]
+	if group && groupBy != "ip" && groupBy != "name" {
+		return fmt.Errorf("参数--group仅支持ip|name")
+	}
+	if kill {
+		if db.IsRedisReadOnly() {
+			return fmt.Errorf("当前配置%s为只读配置，禁止断开客户端连接", conf.RedisConfName())
+		}
+		if filter.IsEmpty() {
+			return fmt.Errorf("断开客户端连接需要至少一个过滤条件")
+		}
+	}
+	_, asJSON := flags["json"]
+	clients, err := db.GetRedisClients()
+	if err != nil {
+		return err
+	}
+	matched := []model.RedisClient{}
+	for _, client := range clients {
+		if matchClient(filter, client) {
+			matched = append(matched, client)
+		}
+	}
+	if len(matched) == 0 {
+		log.Println("没有匹配的客户端连接")
+		return nil
+	}
+	if group {
+		printRows(clientGroupRows(matched, groupBy), asJSON)
+	} else {
+		rows := make([]clientRow, 0, len(matched))
+		for _, client := range matched {
+			rows = append(rows, clientRow{ID: client.ID, Addr: client.Addr, Name: client.Name, DB: client.DB,
+				Age: formatSeconds(client.Age), Idle: formatSeconds(client.Idle), Flags: client.Flags, Cmd: client.Cmd})
+		}
+		printRows(rows, asJSON)
+	}
+	log.Printf("共%d个客户端连接，匹配%d个", len(clients), len(matched))
+	if !kill {
+		return nil
+	}
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("请确认是否断开以上%d个客户端连接(y/n):", len(matched)), false)
+	if isSure != "y" {
+		return nil
+	}
+	ids := make([]int64, 0, len(matched))
+	for _, client := range matched {
+		ids = append(ids, client.ID)
+	}
+	killed, err := db.KillRedisClients(ids)
+	log.Printf("已断开%d个客户端连接", killed)
+	return err
+}
+
+//按来源ip或名称分组统计连接数，按连接数倒序排列
+func clientGroupRows(clients []model.RedisClient, groupBy string) []clientGroupRow {
+	type group struct {
+		name            string
+		count           int
+		maxIdle, maxAge int64
+	}
+	groups := map[string]*group{}
+	for _, client := range clients {
+		name := client.Name
+		if groupBy == "ip" {
+			name = clientIP(client.Addr)
+		}
+		item, ok := groups[name]
+		if !ok {
+			item = &group{name: name}
+			groups[name] = item
+		}
+		item.count++
+		if client.Idle > item.maxIdle {
+			item.maxIdle = client.Idle
+		}
+		if client.Age > item.maxAge {
+			item.maxAge = client.Age
+		}
+	}
+	items := make([]*group, 0, len(groups))
+	for _, item := range groups {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].count != items[j].count {
+			return items[i].count > items[j].count
+		}
+		return items[i].name < items[j].name
+	})
+	rows := make([]clientGroupRow, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, clientGroupRow{Group: item.name, Clients: item.count, MaxIdle: formatSeconds(item.maxIdle), MaxAge: formatSeconds(item.maxAge)})
+	}
+	return rows
+}
@@ -0,0 +1,238 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"rediscmd/src/conf"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//redis配置中带单位的内存大小，例如100mb、1gb
+var configMemoryReg = regexp.MustCompile(`^(\d+)(k|kb|m|mb|g|gb)$`)
+
+//redis配置中内存单位对应的字节数
+var configMemoryUnits = map[string]int64{"k": 1000, "kb": 1024, "m": 1000 * 1000, "mb": 1024 * 1024, "g": 1000 * 1000 * 1000, "gb": 1024 * 1024 * 1024}
+
+//配置差异的行
+type configDiffRow struct {
+	Name     string `json:"name"`
+	Current  string `json:"current"`
+	Baseline string `json:"baseline"`
+	Status   string `json:"status"`
+}
+
+//查看、修改和对比服务器配置 config get|set|diff
+func configCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "rewrite", "json")
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) < 2 {
+		return fmt.Errorf("config需要get|set|diff参数，请重新输入")
+	}
+	_, asJSON := flags["json"]
+	switch cmdParams[1] {
+	case "get":
+		if err := checkCMDFlags(flags, "json"); err != nil {
+			return err
+		}
+		if len(cmdParams) > 3 {
+			return fmt.Errorf("config get最多需要一个配置名称参数，支持*模糊匹配")
+		}
+		pattern := "*"
+		if len(cmdParams) == 3 {
+			pattern = cmdParams[2]
+		}
+		kvs, err := db.GetRedisConfig(pattern)
+		if err != nil {
+			return err
+		}
+		if len(kvs) == 0 {
+			log.Printf("没有匹配%s的配置项", pattern)
+			return nil
+		}
+		printRows(kvs, asJSON)
+	case "set":
+		if err := checkCMDFlags(flags, "rewrite"); err != nil {
+			return err
+		}
+		_, rewrite := flags["rewrite"]
+		return configSetCMD(cmdParams[2:], rewrite)
+	case "diff":
+		if err := checkCMDFlags(flags, "against", "json"); err != nil {
+			return err
+		}
+		against, ok := flags["against"]
+		if !ok || len(cmdParams) > 3 {
+			return fmt.Errorf("config diff需要--against 配置名称或redis.conf文件，可选配置名称参数，支持*模糊匹配")
+		}
+		pattern := "*"
+		if len(cmdParams) == 3 {
+			pattern = cmdParams[2]
+		}
+		return configDiffCMD(against, pattern, asJSON)
+	default:
+		return fmt.Errorf("config不支持【%s】操作，仅支持get|set|diff", cmdParams[1])
+	}
+	return nil
+}
+
+//修改配置项，多个值以空格拼接，例如save 3600 1 300 100
+func configSetCMD(args []string, rewrite bool) error {
+	if db.IsRedisReadOnly() {
+		return fmt.Errorf("当前配置%s为只读配置，禁止修改服务器配置", conf.RedisConfName())
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("config set需要配置名称和值两个参数，请重新输入")
+	}
+	name, value := args[0], strings.Join(args[1:], " ")
+	oldValue, err := db.GetRedisConfigValue(name)
+	if err != nil {
+		return fmt.Errorf("无法读取配置项%s：%s", name, err.Error())
+	}
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("请确认是否将%s从%q修改为%q(y/n):", name, oldValue, value), false)
+	if isSure != "y" {
+		return nil
+	}
+	if err := db.SetRedisConfigValue(name, value); err != nil {
+		return err
+	}
+	log.Printf("%s已修改为%q", name, value)
+	if !rewrite {
+		return nil
+	}
+	if err := db.RewriteRedisConfig(); err != nil {
+		return fmt.Errorf("写入配置文件失败：%s", err.Error())
+	}
+	log.Println("配置已写入redis的配置文件")
+	return nil
+}
+
+//对比当前服务器与其他配置名称对应的服务器或redis.conf文件的配置，文件只对比其中出现的配置项
+func configDiffCMD(against, pattern string, asJSON bool) error {
+	current, err := db.GetRedisConfig(pattern)
+	if err != nil {
+		return err
+	}
+	var baseline []model.KV
+	fromFile := false
+	if _, err := os.Stat(conf.ProfileConfAbsPath(against)); err == nil {
+		baseline, err = db.GetProfileRedisConfig(against, pattern)
+		if err != nil {
+			return fmt.Errorf("获取%s的配置失败：%s", against, err.Error())
+		}
+	} else if _, err := os.Stat(against); err == nil {
+		if baseline, err = readRedisConfFile(against, pattern); err != nil {
+			return err
+		}
+		fromFile = true
+	} else {
+		return fmt.Errorf("%s既不是配置名称也不是redis.conf文件", against)
+	}
+	rows := configDiffRows(current, baseline, fromFile)
+	if len(rows) == 0 {
+		log.Printf("与%s的配置没有差异", against)
+		return nil
+	}
+	printRows(rows, asJSON)
+	log.Printf("与%s共有%d项配置不同", against, len(rows))
+	return nil
+}
+
+//对比两份配置，返回有差异的配置项
+func configDiffRows(current, baseline []model.KV, onlyBaseline bool) []configDiffRow {
+	currentValues := make(map[string]string, len(current))
+	for _, kv := range current {
+		currentValues[kv.Key] = kv.Value
+	}
+	baselineValues := make(map[string]string, len(baseline))
+	for _, kv := range baseline {
+		baselineValues[kv.Key] = kv.Value
+	}
+	names := make([]string, 0, len(currentValues))
+	for _, kv := range baseline {
+		names = append(names, kv.Key)
+	}
+	if !onlyBaseline {
+		for _, kv := range current {
+			if _, ok := baselineValues[kv.Key]; !ok {
+				names = append(names, kv.Key)
+			}
+		}
+	}
+	sort.Strings(names)
+	rows := []configDiffRow{}
+	for _, name := range names {
+		currentValue, inCurrent := currentValues[name]
+		baselineValue, inBaseline := baselineValues[name]
+		row := configDiffRow{Name: name, Current: currentValue, Baseline: baselineValue}
+		switch {
+		case !inCurrent:
+			row.Status = "仅对比方有"
+		case !inBaseline:
+			row.Status = "仅当前有"
+		case normalizeConfigValue(currentValue) != normalizeConfigValue(baselineValue):
+			row.Status = "不同"
+		default:
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+//统一配置值的格式，内存单位转换为字节数，忽略大小写
+func normalizeConfigValue(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	items := strings.Fields(value)
+	for i, item := range items {
+		if match := configMemoryReg.FindStringSubmatch(item); match != nil {
+			num, _ := strconv.ParseInt(match[1], 10, 64)
+			items[i] = strconv.FormatInt(num*configMemoryUnits[match[2]], 10)
+		}
+	}
+	return strings.Join(items, " ")
+}
+
+//读取redis.conf文件中匹配pattern的配置项，重复的配置项（例如save）以空格拼接
+func readRedisConfFile(file, pattern string) ([]model.KV, error) {
+	content, err := util.ReadFileAsString(file)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	names := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		items := strings.Fields(line)
+		name := strings.ToLower(items[0])
+		if name == "include" || !util.GlobMatch(pattern, name) {
+			continue
+		}
+		for i := range items {
+			items[i] = strings.Trim(items[i], "\"'")
+		}
+		value := strings.Join(items[1:], " ")
+		if old, ok := values[name]; ok {
+			values[name] = old + " " + value
+			continue
+		}
+		values[name] = value
+		names = append(names, name)
+	}
+	kvs := make([]model.KV, 0, len(names))
+	for _, name := range names {
+		kvs = append(kvs, model.KV{Key: name, Value: values[name]})
+	}
+	return kvs, nil
+}
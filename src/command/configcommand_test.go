@@ -0,0 +1,88 @@
+package command
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"rediscmd/src/model"
+)
+
+func TestNormalizeConfigValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"100mb", "104857600"},
+		{"100m", "100000000"},
+		{"1GB", "1073741824"},
+		{"1g", "1000000000"},
+		{"2k", "2000"},
+		{"2kb", "2048"},
+		{"1024", "1024"},
+		{" Yes ", "yes"},
+		{"allkeys-LRU", "allkeys-lru"},
+		{"3600 1  300 100", "3600 1 300 100"},
+		{"normal 0 0 0 slave 256mb 64mb 60", "normal 0 0 0 slave 268435456 67108864 60"},
+		{"1.5gb", "1.5gb"},
+		{"mb", "mb"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeConfigValue(tt.value); got != tt.want {
+			t.Errorf("normalizeConfigValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestReadRedisConfFile(t *testing.T) {
+	content := "# redis.conf\n" +
+		"\n" +
+		"include /etc/redis/common.conf\n" +
+		"bind 127.0.0.1 -::1\n" +
+		"  Port 6379\r\n" +
+		"save 3600 1\n" +
+		"save 300 100\n" +
+		"maxmemory 100mb\n" +
+		"maxmemory-policy allkeys-lru\n" +
+		"requirepass \"p@ss\"\n" +
+		"logfile ''\n"
+	file := filepath.Join(t.TempDir(), "redis.conf")
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pattern string
+		want    []model.KV
+	}{
+		{"*", []model.KV{
+			{Key: "bind", Value: "127.0.0.1 -::1"},
+			{Key: "port", Value: "6379"},
+			{Key: "save", Value: "3600 1 300 100"},
+			{Key: "maxmemory", Value: "100mb"},
+			{Key: "maxmemory-policy", Value: "allkeys-lru"},
+			{Key: "requirepass", Value: "p@ss"},
+			{Key: "logfile", Value: ""},
+		}},
+		{"maxmemory*", []model.KV{
+			{Key: "maxmemory", Value: "100mb"},
+			{Key: "maxmemory-policy", Value: "allkeys-lru"},
+		}},
+		{"save", []model.KV{{Key: "save", Value: "3600 1 300 100"}}},
+		{"include", []model.KV{}},
+		{"appendonly", []model.KV{}},
+	}
+	for _, tt := range tests {
+		got, err := readRedisConfFile(file, tt.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readRedisConfFile(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+	if _, err := readRedisConfFile(file+".missing", "*"); err == nil {
+		t.Error("文件不存在时应返回错误")
+	}
+}
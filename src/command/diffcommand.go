@@ -0,0 +1,170 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"rediscmd/src/conf"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/resp"
+	"rediscmd/src/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	diffDefaultWorkers      = 4               //默认并发对比的连接数
+	diffDefaultTTLTolerance = 5 * time.Second //默认允许的过期时间误差
+)
+
+//差异汇总的行
+type diffSummaryRow struct {
+	Kind string `json:"kind"`
+	Keys int64  `json:"keys"`
+}
+
+//解析profile[:db]格式的配置名称和数据库编号，profile为空时为当前配置，db为空时为当前操作的数据库
+func parseProfileEndpoint(str string) (string, int, error) {
+	profile, dbid := str, db.RedisOptionDBId()
+	if index := strings.LastIndex(str, ":"); index >= 0 {
+		id, err := strconv.Atoi(str[index+1:])
+		if err != nil || id < 0 {
+			return "", 0, fmt.Errorf("无法解析%s中的数据库编号", str)
+		}
+		profile, dbid = str[:index], id
+	}
+	if profile == "" {
+		profile = conf.RedisConfName()
+	}
+	if _, err := conf.GetProfileRedisConf(profile); err != nil {
+		return "", 0, err
+	}
+	return profile, dbid, nil
+}
+
+//对比两个配置或数据库中匹配的key
+func diffCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "typed", "json", "no-ttl")
+	if err == nil {
+		err = checkCMDFlags(flags, append(keyFilterFlags, "left", "right", "typed", "ttl-tolerance", "workers", "patch", "limit", "json")...)
+	}
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) > 2 || flags["right"] == "" {
+		return fmt.Errorf("对比key需要模糊key参数（默认全部）和--right 配置名称[:数据库编号]，请重新输入")
+	}
+	filter, err := parseKeyFilter(flags)
+	if err != nil {
+		return err
+	}
+	options := db.DiffOptions{Pattern: "*", Filter: filter, Workers: diffDefaultWorkers, TTLTolerance: diffDefaultTTLTolerance.Milliseconds()}
+	if len(cmdParams) == 2 {
+		options.Pattern = cmdParams[1]
+	}
+	if options.LeftProfile, options.LeftDB, err = parseProfileEndpoint(flags["left"]); err != nil {
+		return err
+	}
+	if options.RightProfile, options.RightDB, err = parseProfileEndpoint(flags["right"]); err != nil {
+		return err
+	}
+	if options.LeftProfile == options.RightProfile && options.LeftDB == options.RightDB {
+		return fmt.Errorf("左右两侧是同一个数据库，无需对比")
+	}
+	_, options.Typed = flags["typed"]
+	if str, ok := flags["ttl-tolerance"]; ok {
+		tolerance, err := util.ParseDuration(str)
+		if err != nil {
+			return err
+		}
+		options.TTLTolerance = tolerance.Milliseconds()
+	}
+	if str, ok := flags["workers"]; ok {
+		if options.Workers, err = strconv.Atoi(str); err != nil || options.Workers <= 0 {
+			return fmt.Errorf("参数--workers必须是正整数")
+		}
+	}
+	limit := 0
+	if str, ok := flags["limit"]; ok {
+		if limit, err = strconv.Atoi(str); err != nil || limit <= 0 {
+			return fmt.Errorf("参数--limit必须是正整数")
+		}
+	}
+	_, asJSON := flags["json"]
+	var patch *bufio.Writer
+	if out, ok := flags["patch"]; ok {
+		if _, err := os.Stat(out); err == nil {
+			isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("文件%s已存在，请确认是否覆盖(y/n):", out), false)
+			if isSure != "y" {
+				return nil
+			}
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		patch = bufio.NewWriter(f)
+		defer patch.Flush()
+		resp.WriteCommand(patch, "select", strconv.Itoa(options.RightDB))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	log.Printf("正在对比%s db(%d)与%s db(%d)中匹配%s的key，可按Ctrl+C中止", options.LeftProfile, options.LeftDB, options.RightProfile, options.RightDB, options.Pattern)
+	var leftScanned, rightScanned, failed, found int64
+	counts := map[string]int64{}
+	diffChan := make(chan model.KeyDiff, 1000)
+	go db.DiffRedisKeys(ctx, options, &leftScanned, &rightScanned, &failed, diffChan)
+	for diff := range diffChan {
+		if limit > 0 && found >= int64(limit) {
+			cancel() //已达到数量限制，丢弃剩余结果
+			continue
+		}
+		found++
+		counts[diff.Kind]++
+		if patch != nil {
+			for _, args := range diff.Patch {
+				resp.WriteCommand(patch, args...)
+			}
+		}
+		if asJSON {
+			content, _ := json.Marshal(diff)
+			fmt.Println(string(content))
+		} else if diff.Left != "" || diff.Right != "" {
+			log.Printf("%s [%s] %s | %s", diff.Key, diff.Kind, diff.Left, diff.Right)
+		} else {
+			log.Printf("%s [%s]", diff.Key, diff.Kind)
+		}
+	}
+	if ctx.Err() != nil && (limit <= 0 || found < int64(limit)) {
+		log.Println("对比已中止")
+	}
+	log.Printf("左侧扫描%d个key，右侧扫描%d个key，共%d处差异", leftScanned, rightScanned, found)
+	if found > 0 {
+		rows := make([]diffSummaryRow, 0, len(counts))
+		for kind, count := range counts {
+			rows = append(rows, diffSummaryRow{Kind: kind, Keys: count})
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Kind < rows[j].Kind })
+		if !asJSON {
+			printRows(rows, false)
+		}
+		if patch != nil {
+			log.Printf("同步命令已写入%s，可通过aof replay %s --to %s使右侧与左侧一致", flags["patch"], flags["patch"], options.RightProfile)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("对比过程中出错%d次，结果可能不完整", failed)
+	}
+	return nil
+}
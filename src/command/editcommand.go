@@ -0,0 +1,173 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//在$EDITOR中编辑key的值，写回时通过watch检测并发修改，保留原有的过期时间
+func editCMD(cmdParams []string) error {
+	if len(cmdParams) != 2 {
+		return fmt.Errorf("编辑缓存值需要精确key参数，请重新输入")
+	}
+	key := cmdParams[1]
+	changed := false
+	err := db.EditRedisKey(key, func(value *model.RedisValue) (*model.RedisValue, error) {
+		content, compactJSON, err := editContent(value)
+		if err != nil {
+			return nil, err
+		}
+		suffix := ".json"
+		addedNewline := false //编辑器保存时通常会在末尾加换行，原值没有换行时编辑前加上，写回前去掉
+		if value.Type == "string" && !compactJSON {
+			suffix = ".txt"
+			addedNewline = !strings.HasSuffix(content, "\n")
+		}
+		for {
+			input := content
+			if addedNewline {
+				input += "\n"
+			}
+			edited, err := util.EditInEditor(input, suffix)
+			if err != nil {
+				return nil, err
+			}
+			if addedNewline {
+				edited = strings.TrimSuffix(edited, "\n")
+			}
+			if edited == content {
+				log.Println("内容未修改")
+				return nil, nil
+			}
+			newValue, err := parseEditContent(value, edited, compactJSON)
+			if err == nil {
+				changed = true
+				return newValue, nil
+			}
+			log.Printf("内容格式错误：%s", err.Error())
+			isSure, _ := util.ReadValueFromConsole("请确认是否重新编辑(y/n):", false)
+			if isSure != "y" {
+				return nil, nil
+			}
+			content = edited
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if changed {
+		log.Printf("%s 修改已写入", key)
+	}
+	return nil
+}
+
+//生成编辑的内容，字符串原样编辑（压缩的json格式化后编辑），集合类型转换为json
+func editContent(value *model.RedisValue) (string, bool, error) {
+	switch v := value.Value.(type) {
+	case string:
+		if !utf8.ValidString(v) {
+			return "", false, errors.New("值包含二进制内容，无法编辑")
+		}
+		if (strings.HasPrefix(v, "{") || strings.HasPrefix(v, "[")) && json.Valid([]byte(v)) && !strings.Contains(v, "\n") {
+			var buf bytes.Buffer
+			json.Indent(&buf, []byte(v), "", "  ")
+			return buf.String() + "\n", true, nil
+		}
+		return v, false, nil
+	case []string:
+		for _, item := range v {
+			if !utf8.ValidString(item) {
+				return "", false, errors.New("值包含二进制内容，无法编辑")
+			}
+		}
+		content, err := json.MarshalIndent(v, "", "  ")
+		return string(content) + "\n", false, err
+	case []model.KV:
+		for _, kv := range v {
+			if !utf8.ValidString(kv.Key) || !utf8.ValidString(kv.Value) {
+				return "", false, errors.New("值包含二进制内容，无法编辑")
+			}
+		}
+		var buf bytes.Buffer
+		err := json.Indent(&buf, []byte(formatKVs(v)), "", "  ")
+		return buf.String() + "\n", false, err
+	}
+	return "", false, fmt.Errorf("不支持编辑%s类型的key", value.Type)
+}
+
+//解析编辑后的内容
+func parseEditContent(value *model.RedisValue, content string, compactJSON bool) (*model.RedisValue, error) {
+	newValue := &model.RedisValue{Key: value.Key, Type: value.Type, ExpireAt: value.ExpireAt}
+	switch value.Type {
+	case "string":
+		if !compactJSON {
+			newValue.Value = content
+			return newValue, nil
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(content)); err != nil {
+			return nil, err
+		}
+		newValue.Value = buf.String()
+	case "list", "set":
+		items := []json.RawMessage{}
+		if err := json.Unmarshal([]byte(content), &items); err != nil {
+			return nil, err
+		}
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			values = append(values, rawJSONString(item))
+		}
+		newValue.Value = values
+	case "hash", "zset":
+		items := map[string]json.RawMessage{}
+		if err := json.Unmarshal([]byte(content), &items); err != nil {
+			return nil, err
+		}
+		kvs := make([]model.KV, 0, len(items))
+		for key, item := range items {
+			kv := model.KV{Key: key, Value: rawJSONString(item)}
+			if value.Type == "zset" {
+				if _, err := strconv.ParseFloat(kv.Value, 64); err != nil {
+					return nil, fmt.Errorf("成员%s的分数%s不是数字", kv.Key, kv.Value)
+				}
+			}
+			kvs = append(kvs, kv)
+		}
+		sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+		newValue.Value = kvs
+	}
+	if value.Type != "string" && collectionLen(newValue.Value) == 0 {
+		log.Printf("编辑后的%s为空，写入后key将被删除", value.Type)
+	}
+	return newValue, nil
+}
+
+//json字符串取其内容，数字、布尔等其他值取原文
+func rawJSONString(raw json.RawMessage) string {
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str
+	}
+	return string(bytes.TrimSpace(raw))
+}
+
+func collectionLen(value interface{}) int {
+	switch v := value.(type) {
+	case []string:
+		return len(v)
+	case []model.KV:
+		return len(v)
+	}
+	return 0
+}
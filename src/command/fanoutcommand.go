@@ -0,0 +1,387 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"rediscmd/src/conf"
+	"rediscmd/src/util"
+	"strings"
+	"sync"
+	"time"
+)
+
+//需要交互、切换配置或本身涉及多个配置的命令，不支持在多个配置上执行
+var fanOutExcludeCMDs = map[string]bool{"cls": true, "quit": true, "edit": true, "browse": true, "resetconf": true, "changeconf": true, "addconf": true, "changeoptdbid": true, "rdb": true, "aof": true, "diff": true, "sync": true, "begin": true, "commit": true, "discard": true}
+
+//可能修改缓存或服务器状态并需要确认的命令，分发前统一确认一次
+var fanOutConfirmCMDs = map[string]bool{"rename": true, "stream": true, "slowlog": true, "clients": true, "config": true, "script": true, "function": true}
+
+//执行命令的配置
+type fanOutTarget struct {
+	profile  string
+	endpoint string //配置名称:数据库编号
+	err      error  //配置名称不正确时的错误
+}
+
+//每个配置的执行结果
+type fanOutRow struct {
+	Profile  string `json:"profile"`
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error"`
+}
+
+//合并输出中的一行，保留json字段的顺序
+type fanOutRecord struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+//以子进程在多个配置上并发执行命令，一个配置失败不影响其他配置，有配置失败或跳过时返回错误
+func fanOutCMD(profiles string, cmdParams []string) error {
+	if len(cmdParams) == 0 {
+		return fmt.Errorf("请输入需要在多个配置上执行的命令")
+	}
+	if fanOutExcludeCMDs[cmdParams[0]] {
+		return fmt.Errorf("【%s】不支持在多个配置上执行", cmdParams[0])
+	}
+	args := []string{}
+	merge, asJSON := false, false
+	for _, item := range cmdParams {
+		switch item {
+		case "--merge":
+			merge = true
+			continue
+		case "--json":
+			asJSON = true
+		}
+		args = append(args, item)
+	}
+	if merge && !asJSON {
+		args = append(args, "--json") //合并输出需要各配置以json格式输出
+	}
+	targets, err := parseFanOutTargets(profiles)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取可执行文件路径失败：%s", err.Error())
+	}
+	input := "" //子进程中的确认没有输入，视为取消
+	if writeCMDs[args[0]] || fanOutConfirmCMDs[args[0]] {
+		isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("此操作将在%d个配置上执行【%s】，命令中的确认将自动回答y，请确认是否执行(y/n):", len(targets), strings.Join(args, " ")), false)
+		if isSure != "y" {
+			return nil
+		}
+		input = "y\n"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt) //Ctrl+C同时发送给子进程，等待子进程结束后汇总
+	defer stop()
+	var lock sync.Mutex //各配置的输出逐行加锁打印，避免交错
+	rows := make([]fanOutRow, len(targets))
+	outputs := make([][]byte, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		rows[i] = fanOutRow{Profile: target.endpoint}
+		if target.err != nil {
+			rows[i].Status, rows[i].Error = "失败", target.err.Error()
+			continue
+		}
+		if writeCMDs[args[0]] {
+			targetConf, err := conf.GetProfileRedisConf(target.profile)
+			if err != nil {
+				rows[i].Status, rows[i].Error = "失败", util.Truncate(err.Error(), 80)
+				continue
+			}
+			if targetConf.Redis.ReadOnly {
+				rows[i].Status, rows[i].Error = "跳过", "只读配置"
+				continue
+			}
+		}
+		wg.Add(1)
+		go func(i int, target fanOutTarget) {
+			defer wg.Done()
+			start := time.Now()
+			output, err := runFanOutTarget(exe, target, args, input, !merge, &lock)
+			rows[i].Duration = time.Since(start).Truncate(time.Millisecond).String()
+			outputs[i] = output
+			if err != nil {
+				rows[i].Status, rows[i].Error = "失败", util.Truncate(err.Error(), 80)
+				return
+			}
+			rows[i].Status = "成功"
+		}(i, target)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		log.Println("执行已中止")
+	}
+	if merge {
+		mergeFanOutOutputs(rows, outputs, asJSON)
+	}
+	succeeded := 0
+	for _, row := range rows {
+		if row.Status == "成功" {
+			succeeded++
+		} else if merge && asJSON {
+			log.Printf("%s%s：%s", row.Profile, row.Status, row.Error)
+		}
+	}
+	if !merge || !asJSON { //json合并输出时不打印汇总表格，避免破坏json格式
+		printRows(rows, false)
+	}
+	if succeeded < len(rows) {
+		return fmt.Errorf("共%d个配置，成功%d个，失败或跳过%d个", len(rows), succeeded, len(rows)-succeeded)
+	}
+	log.Printf("共%d个配置，全部成功", len(rows))
+	return nil
+}
+
+//解析逗号分隔的配置名称[:数据库编号]，只有一个名称且当前配置文件中定义了同名配置组时展开配置组，不存在的配置在结果中标记为失败
+func parseFanOutTargets(profiles string) ([]fanOutTarget, error) {
+	names := strings.Split(profiles, ",")
+	if len(names) == 1 {
+		if group := conf.GetRedisConfGroup(profiles); group != nil {
+			names = group
+		}
+	}
+	targets := []fanOutTarget{}
+	exists := map[string]bool{}
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		profile, dbid, err := parseProfileEndpoint(name)
+		if err != nil {
+			targets = append(targets, fanOutTarget{endpoint: name, err: err})
+			continue
+		}
+		endpoint := fmt.Sprintf("%s:%d", profile, dbid)
+		if exists[endpoint] {
+			continue
+		}
+		exists[endpoint] = true
+		targets = append(targets, fanOutTarget{profile: profile, endpoint: endpoint})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("--profiles需要配置名称或配置组名称")
+	}
+	return targets, nil
+}
+
+//以非交互方式执行子进程，日志逐行加上配置名称前缀输出，stream为false时返回标准输出的内容
+func runFanOutTarget(exe string, target fanOutTarget, args []string, input string, stream bool, lock *sync.Mutex) ([]byte, error) {
+	cmd := exec.Command(exe, append([]string{"--conf", target.endpoint}, args...)...)
+	cmd.Stdin = strings.NewReader(input)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stdout io.ReadCloser
+	output := &bytes.Buffer{}
+	if stream {
+		if stdout, err = cmd.StdoutPipe(); err != nil {
+			return nil, err
+		}
+	} else {
+		cmd.Stdout = output
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	var wg sync.WaitGroup
+	var lastLog string
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lastLog = printFanOutLines(stderr, target.endpoint, lock)
+	}()
+	if stream {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			printFanOutLines(stdout, target.endpoint, lock)
+		}()
+	}
+	wg.Wait() //读取完所有输出后才能等待子进程结束
+	if err := cmd.Wait(); err != nil {
+		if lastLog != "" {
+			return output.Bytes(), fmt.Errorf("%s", trimLogTime(lastLog))
+		}
+		return output.Bytes(), err
+	}
+	return output.Bytes(), nil
+}
+
+//逐行输出并加上[配置名称]前缀，返回最后一行非空内容
+func printFanOutLines(reader io.Reader, prefix string, lock *sync.Mutex) string {
+	last := ""
+	bufReader := bufio.NewReader(reader)
+	for {
+		line, err := bufReader.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			lock.Lock()
+			fmt.Printf("[%s] %s\n", prefix, line)
+			lock.Unlock()
+			last = line
+		}
+		if err != nil {
+			return last
+		}
+	}
+}
+
+//去掉子进程日志开头的时间
+func trimLogTime(line string) string {
+	if len(line) > 20 {
+		if _, err := time.Parse("2006/01/02 15:04:05", line[:19]); err == nil {
+			return line[20:]
+		}
+	}
+	return line
+}
+
+//将各配置的json输出合并为一个表格或json数组，第一列为配置名称
+func mergeFanOutOutputs(rows []fanOutRow, outputs [][]byte, asJSON bool) {
+	columns := []string{"profile"}
+	hasColumn := map[string]bool{"profile": true}
+	records := []fanOutRecord{}
+	for i, output := range outputs {
+		if rows[i].Status != "成功" {
+			continue
+		}
+		items, err := decodeFanOutRecords(output)
+		if err != nil {
+			rows[i].Status, rows[i].Error = "失败", fmt.Sprintf("无法解析json输出：%s", err.Error())
+			continue
+		}
+		profile, _ := json.Marshal(rows[i].Profile)
+		for _, item := range items {
+			keys := []string{"profile"}
+			for _, key := range item.keys {
+				if key != "profile" { //与输出中的同名字段冲突时以配置名称为准
+					keys = append(keys, key)
+				}
+			}
+			item.keys = keys
+			item.values["profile"] = profile
+			for _, key := range item.keys {
+				if !hasColumn[key] {
+					hasColumn[key] = true
+					columns = append(columns, key)
+				}
+			}
+			records = append(records, item)
+		}
+	}
+	if len(records) == 0 {
+		log.Println("各配置均没有可合并的输出")
+		return
+	}
+	if asJSON {
+		items := make([]string, 0, len(records))
+		for _, record := range records {
+			fields := make([]string, 0, len(record.keys))
+			for _, key := range record.keys {
+				name, _ := json.Marshal(key)
+				fields = append(fields, string(name)+":"+string(record.values[key]))
+			}
+			items = append(items, "{"+strings.Join(fields, ",")+"}")
+		}
+		content := &bytes.Buffer{}
+		json.Indent(content, []byte("["+strings.Join(items, ",")+"]"), "", "  ")
+		fmt.Println(content.String())
+		return
+	}
+	cells := make([][]string, 0, len(records))
+	for _, record := range records {
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, formatJSONCell(record.values[column]))
+		}
+		cells = append(cells, row)
+	}
+	printColumns(columns, cells)
+}
+
+//解析子进程输出的json，可以包含多个对象数组或对象，非对象的数组元素作为value列
+func decodeFanOutRecords(output []byte) ([]fanOutRecord, error) {
+	records := []fanOutRecord{}
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+		items := []json.RawMessage{value}
+		if bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+			items = nil
+			if err := json.Unmarshal(value, &items); err != nil {
+				return nil, err
+			}
+		}
+		for _, item := range items {
+			record, err := decodeFanOutRecord(item)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+	}
+}
+
+//按字段顺序解析json对象，不是对象时作为value列
+func decodeFanOutRecord(item json.RawMessage) (fanOutRecord, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(item), []byte("{")) {
+		return fanOutRecord{keys: []string{"value"}, values: map[string]json.RawMessage{"value": item}}, nil
+	}
+	record := fanOutRecord{values: map[string]json.RawMessage{}}
+	decoder := json.NewDecoder(bytes.NewReader(item))
+	if _, err := decoder.Token(); err != nil { //跳过{
+		return record, err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return record, err
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return record, err
+		}
+		if _, ok := record.values[key]; !ok {
+			record.keys = append(record.keys, key)
+		}
+		record.values[key] = value
+	}
+	return record, nil
+}
+
+//将json值格式化为表格中的内容，字符串去掉引号
+func formatJSONCell(value json.RawMessage) string {
+	if len(value) == 0 || string(value) == "null" {
+		return ""
+	}
+	var str string
+	if err := json.Unmarshal(value, &str); err == nil {
+		return str
+	}
+	content := &bytes.Buffer{}
+	if err := json.Compact(content, value); err != nil {
+		return string(value)
+	}
+	return content.String()
+}
@@ -0,0 +1,74 @@
+package command
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+//将记录转换为按字段顺序排列的key=value，便于比较
+func fanOutRecordFields(records []fanOutRecord) [][]string {
+	result := [][]string{}
+	for _, record := range records {
+		fields := []string{}
+		for _, key := range record.keys {
+			fields = append(fields, key+"="+string(record.values[key]))
+		}
+		result = append(result, fields)
+	}
+	return result
+}
+
+func TestDecodeFanOutRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    [][]string
+		wantErr bool
+	}{
+		{"空输出", "", [][]string{}, false},
+		{"对象数组保持字段顺序", `[{"key":"a","ttl":-1},{"ttl":10,"key":"b"}]`, [][]string{{`key="a"`, "ttl=-1"}, {"ttl=10", `key="b"`}}, false},
+		{"单个对象", `{"name":"maxmemory","value":"0"}`, [][]string{{`name="maxmemory"`, `value="0"`}}, false},
+		{"多个json值", "[{\"a\":1}]\n[{\"a\":2}]\n{\"a\":3}", [][]string{{"a=1"}, {"a=2"}, {"a=3"}}, false},
+		{"非对象的数组元素", `["x",1,null]`, [][]string{{`value="x"`}, {"value=1"}, {"value=null"}}, false},
+		{"非对象的值", `"ok"`, [][]string{{`value="ok"`}}, false},
+		{"嵌套的值保持原样", `[{"a":{"b":[1,2]}}]`, [][]string{{`a={"b":[1,2]}`}}, false},
+		{"重复的字段以最后一次为准", `{"a":1,"b":2,"a":3}`, [][]string{{"a=3", "b=2"}}, false},
+		{"空数组", `[]`, [][]string{}, false},
+		{"不是json", "共3个key", nil, true},
+		{"json不完整", `[{"a":1}`, nil, true},
+	}
+	for _, tt := range tests {
+		records, err := decodeFanOutRecords([]byte(tt.output))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: 错误为%v", tt.name, err)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got := fanOutRecordFields(records); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 解析结果为%q，期望%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatJSONCell(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`"text"`, "text"},
+		{`"a\"b"`, `a"b`},
+		{"null", ""},
+		{"", ""},
+		{"12", "12"},
+		{"true", "true"},
+		{"{ \"a\" : [1, 2] }", `{"a":[1,2]}`},
+	}
+	for _, tt := range tests {
+		if got := formatJSONCell(json.RawMessage(tt.value)); got != tt.want {
+			t.Errorf("formatJSONCell(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
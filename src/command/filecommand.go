@@ -0,0 +1,201 @@
+package command
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"rediscmd/src/codec"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"strings"
+)
+
+const maxRedisStringSize = 512 << 20 //redis字符串值的最大字节数
+
+const maxKeyFileName = 200 //key转换为文件名后的最大字节数，截断后加上~和40位sha1不超过255字节
+
+//解析set的值，@开头时读取文件的原始字节，@@开头表示以@开头的值，返回值是否来自文件
+func readSetValue(flags map[string]string, raw string) (string, bool, error) {
+	if !strings.HasPrefix(raw, "@") || raw == "@" {
+		value, err := encodeValue(flags, raw)
+		return value, false, err
+	}
+	if strings.HasPrefix(raw, "@@") {
+		value, err := encodeValue(flags, raw[1:])
+		return value, false, err
+	}
+	file := raw[1:]
+	maxSize := int64(maxRedisStringSize)
+	if str, ok := flags["max-size"]; ok {
+		size, err := util.ParseByteSize(str)
+		if err != nil || size <= 0 {
+			return "", true, fmt.Errorf("无法解析--max-size的大小%s", str)
+		}
+		maxSize = size
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", true, err
+	}
+	if info.IsDir() {
+		return "", true, fmt.Errorf("%s是目录，请指定文件", file)
+	}
+	if info.Size() > maxSize {
+		return "", true, fmt.Errorf("文件%s大小为%s，超过限制%s", file, util.FormatByteSize(info.Size()), util.FormatByteSize(maxSize))
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", true, err
+	}
+	value, err := encodeValue(flags, string(content))
+	if err != nil {
+		return "", true, err
+	}
+	if int64(len(value)) > maxSize {
+		return "", true, fmt.Errorf("编码后的大小为%s，超过限制%s", util.FormatByteSize(int64(len(value))), util.FormatByteSize(maxSize))
+	}
+	return value, true, nil
+}
+
+//将匹配的key的原始字节写入文件，out为目录（已存在或以/结尾）时每个key写入单独的文件
+func getOutCMD(cmdParams []string, flags map[string]string) error {
+	out := flags["out"]
+	var chain codec.Chain
+	if names, ok := flags["decode"]; ok {
+		if names == "auto" {
+			return fmt.Errorf("写入文件时不支持--decode auto，请指定编解码链，例如gzip")
+		}
+		var err error
+		if chain, err = parseDecodeChain(flags); err != nil {
+			return err
+		}
+	}
+	var maxSize int64
+	if str, ok := flags["max-size"]; ok {
+		size, err := util.ParseByteSize(str)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("无法解析--max-size的大小%s", str)
+		}
+		maxSize = size
+	}
+	var keys []string
+	err := keysFilterOptionCMD("将key的值写入文件需要2~3个参数，请重新输入", cmdParams, flags, func(cmdParams []string, filter *model.KeyFilter) error {
+		keys = db.SearchRedisKeys(cmdParams[1], filter)
+		return nil
+	}, func(cmdParams []string, filter *model.KeyFilter) error {
+		keysChan := make(chan string, 1000)
+		go db.SearchRedisKeysIgnoreCase(cmdParams[1], filter, keysChan)
+		for key := range keysChan {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		log.Println("没有匹配的key")
+		return nil
+	}
+	isDir := strings.HasSuffix(out, "/") || strings.HasSuffix(out, string(os.PathSeparator))
+	if info, err := os.Stat(out); err == nil && info.IsDir() {
+		isDir = true
+	}
+	if !isDir && len(keys) > 1 {
+		return fmt.Errorf("匹配到%d个key，请将--out指定为目录（以/结尾），每个key写入单独的文件", len(keys))
+	}
+	files := make([]string, 0, len(keys))
+	existed := 0
+	for _, key := range keys {
+		file := out
+		if isDir {
+			file = filepath.Join(out, escapeKeyFileName(key))
+		}
+		if _, err := os.Stat(file); err == nil {
+			existed++
+		}
+		files = append(files, file)
+	}
+	if existed > 0 {
+		isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("%d个文件已存在，请确认是否覆盖(y/n):", existed), false)
+		if isSure != "y" {
+			return nil
+		}
+	}
+	if isDir {
+		if err := os.MkdirAll(out, 0755); err != nil {
+			return err
+		}
+	}
+	var written, total int64
+	for i, key := range keys {
+		size, err := writeKeyFile(key, files[i], chain, maxSize)
+		if err != nil {
+			log.Printf("%s未写入：%s", key, err.Error())
+			continue
+		}
+		written++
+		total += size
+	}
+	log.Printf("共匹配%d个key，%d个已写入%s，共%s", len(keys), written, out, util.FormatByteSize(total))
+	if written < int64(len(keys)) {
+		return fmt.Errorf("%d个key未写入", int64(len(keys))-written)
+	}
+	return nil
+}
+
+//将一个key的值写入文件，返回写入的字节数
+func writeKeyFile(key, file string, chain codec.Chain, maxSize int64) (int64, error) {
+	size, err := db.GetRedisValueSize(key)
+	if err != nil {
+		return 0, err
+	}
+	if maxSize > 0 && size > maxSize {
+		return 0, fmt.Errorf("值的大小为%s，超过限制%s", util.FormatByteSize(size), util.FormatByteSize(maxSize))
+	}
+	value, err := db.GetRedisValue(key)
+	if err != nil {
+		return 0, err
+	}
+	content := []byte(value)
+	if chain != nil {
+		limit := maxSize
+		if limit <= 0 {
+			limit = maxRedisStringSize
+		}
+		if content, err = chain.DecodeLimit(content, limit); err != nil { //解压后的大小同样受限
+			return 0, err
+		}
+	}
+	if err := ioutil.WriteFile(file, content, 0644); err != nil {
+		return 0, err
+	}
+	return int64(len(content)), nil
+}
+
+//将key转换为文件名，小写字母、数字和-_.以外的字节（包括大写字母，避免不区分大小写的文件系统中A和a重名）以%XX表示，超过maxKeyFileName字节时截断并加上~和key的sha1，只有未截断的文件名可以还原为key
+func escapeKeyFileName(key string) string {
+	builder := strings.Builder{}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.' && i > 0:
+			builder.WriteByte(c)
+		default:
+			builder.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	name := builder.String()
+	if len(name) <= maxKeyFileName {
+		return name
+	}
+	cut := maxKeyFileName
+	if index := strings.LastIndexByte(name[cut-2:cut], '%'); index >= 0 { //不截断%XX
+		cut = cut - 2 + index
+	}
+	return fmt.Sprintf("%s~%x", name[:cut], sha1.Sum([]byte(key)))
+}
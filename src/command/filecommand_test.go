@@ -0,0 +1,110 @@
+package command
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEscapeKeyFileName(t *testing.T) {
+	longCJK := strings.Repeat("中", 100)
+	tests := []struct {
+		key       string
+		want      string
+		truncated bool
+	}{
+		{key: "user_1-a.json", want: "user_1-a.json"},
+		{key: "user:1", want: "user%3A1"},
+		{key: "a/b", want: "a%2Fb"},
+		{key: "..", want: "%2E."},
+		{key: ".hidden", want: "%2Ehidden"},
+		{key: "a b%~", want: "a%20b%25%7E"},
+		{key: "中", want: "%E4%B8%AD"},
+		{key: "\x00", want: "%00"},
+		{key: "", want: ""},
+		{key: "A", want: "%41"},
+		{key: "a", want: "a"},
+		{key: "User:ID", want: "%55ser%3A%49%44"},
+		{key: "user:id", want: "user%3Aid"},
+		{key: strings.Repeat("a", maxKeyFileName), want: strings.Repeat("a", maxKeyFileName)},
+		{key: strings.Repeat("a", maxKeyFileName+1), truncated: true},
+		{key: strings.Repeat("a", maxKeyFileName+1) + "b", truncated: true},
+		{key: longCJK, truncated: true},
+		{key: longCJK + "a", truncated: true},
+		{key: "a" + longCJK, truncated: true},
+		{key: "ab" + longCJK, truncated: true},
+	}
+	seen := map[string]string{} //按不区分大小写的文件系统比较
+	for _, tt := range tests {
+		got := escapeKeyFileName(tt.key)
+		if !tt.truncated {
+			if got != tt.want {
+				t.Errorf("escapeKeyFileName(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+			if key, err := url.PathUnescape(got); err != nil || key != tt.key {
+				t.Errorf("escapeKeyFileName(%q) = %q，无法还原：%q, %v", tt.key, got, key, err)
+			}
+		} else {
+			index := strings.LastIndexByte(got, '~')
+			if len(got) > 255 || index < 0 || len(got)-index != 41 {
+				t.Errorf("escapeKeyFileName(%q) = %q，应截断为不超过255字节并以~和sha1结尾", tt.key, got)
+			} else if prefix, err := url.PathUnescape(got[:index]); err != nil || !strings.HasPrefix(tt.key, prefix) {
+				t.Errorf("escapeKeyFileName(%q) = %q，截断后的前缀%q不完整：%v", tt.key, got, prefix, err)
+			}
+		}
+		folded := strings.ToLower(got)
+		if other, ok := seen[folded]; ok {
+			t.Errorf("%q和%q转换后的文件名在不区分大小写时相同：%q", tt.key, other, got)
+		}
+		seen[folded] = tt.key
+	}
+}
+
+func TestReadSetValue(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "value.bin")
+	content := "line1\n\x00\xff"
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		flags    map[string]string
+		raw      string
+		want     string
+		fromFile bool
+		errMsg   string
+	}{
+		{name: "普通值", flags: map[string]string{}, raw: "value", want: "value"},
+		{name: "单独的@", flags: map[string]string{}, raw: "@", want: "@"},
+		{name: "@@表示@开头的值", flags: map[string]string{}, raw: "@@home", want: "@home"},
+		{name: "编码普通值", flags: map[string]string{"encode": "base64"}, raw: "value", want: "dmFsdWU="},
+		{name: "读取文件的原始字节", flags: map[string]string{}, raw: "@" + file, want: content, fromFile: true},
+		{name: "编码文件内容", flags: map[string]string{"encode": "hex"}, raw: "@" + file, fromFile: true, errMsg: "hex编码失败"},
+		{name: "文件大小超过限制", flags: map[string]string{"max-size": "4b"}, raw: "@" + file, fromFile: true, errMsg: "超过限制"},
+		{name: "编码后超过限制", flags: map[string]string{"max-size": "10b", "encode": "base64"}, raw: "@" + file, fromFile: true, errMsg: "编码后的大小"},
+		{name: "无法解析max-size", flags: map[string]string{"max-size": "abc"}, raw: "@" + file, fromFile: true, errMsg: "无法解析--max-size"},
+		{name: "目录", flags: map[string]string{}, raw: "@" + dir, fromFile: true, errMsg: "是目录"},
+	}
+	for _, tt := range tests {
+		got, fromFile, err := readSetValue(tt.flags, tt.raw)
+		if fromFile != tt.fromFile {
+			t.Errorf("%s: 是否来自文件为%v，期望%v", tt.name, fromFile, tt.fromFile)
+		}
+		if tt.errMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("%s: 错误为%v，期望包含%s", tt.name, err, tt.errMsg)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: 读取结果为%q, %v，期望%q", tt.name, got, err, tt.want)
+		}
+	}
+	if _, _, err := readSetValue(map[string]string{}, "@"+filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("文件不存在时错误为%v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"regexp"
+	"strconv"
+)
+
+//在缓存值中查找内容，可按Ctrl+C中止
+func grepCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "no-ttl", "json")
+	if err == nil {
+		err = checkCMDFlags(flags, append(keyFilterFlags, "limit", "json")...)
+	}
+	if err != nil {
+		return err
+	}
+	filter, err := parseKeyFilter(flags)
+	if err != nil {
+		return err
+	}
+	ignoreCase, args := splitKeysMode(cmdParams[1:])
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("查找缓存值需要正则表达式参数，请重新输入")
+	}
+	expr := args[0]
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	valueReg, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("正则表达式错误：%s", err.Error())
+	}
+	//忽略大小写时scan无法按模式匹配，改为在本地匹配key
+	pattern, keyArgs := "*", []string{"n"}
+	if len(args) == 2 {
+		keyArgs = append(keyArgs, args[1])
+		if ignoreCase {
+			keyArgs[0] = "y"
+		} else {
+			pattern = args[1]
+		}
+	}
+	matchKey, err := parseKeyMatcher(keyArgs)
+	if err != nil {
+		return err
+	}
+	limit := 0
+	if str, ok := flags["limit"]; ok {
+		if limit, err = strconv.Atoi(str); err != nil || limit <= 0 {
+			return fmt.Errorf("参数--limit必须是正整数")
+		}
+	}
+	_, asJSON := flags["json"]
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var scanned, matched int64
+	matchChan := make(chan model.GrepMatch, 1000)
+	go db.GrepRedisKeys(ctx, pattern, filter, matchKey, valueReg, &scanned, matchChan)
+	for grepMatch := range matchChan {
+		if limit > 0 && matched >= int64(limit) {
+			cancel() //已达到数量限制，丢弃剩余结果
+			continue
+		}
+		matched++
+		if asJSON {
+			content, _ := json.Marshal(grepMatch)
+			fmt.Println(string(content))
+		} else {
+			log.Printf("%s [%s] %s: %s", grepMatch.Key, grepMatch.Type, grepMatch.Location, grepMatch.Snippet)
+		}
+	}
+	if ctx.Err() != nil && (limit <= 0 || matched < int64(limit)) {
+		log.Println("查找已中止")
+	}
+	log.Printf("共查找%d个key，匹配%d处", scanned, matched)
+	return nil
+}
@@ -0,0 +1,290 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"rediscmd/src/conf"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const topDefaultInterval = 2 * time.Second //top默认的刷新间隔
+
+//info命令默认显示的分组
+var infoDefaultSections = []string{"memory", "clients", "persistence", "replication", "stats", "keyspace", "commandstats"}
+
+//命令统计的行
+type commandStatRow struct {
+	Command     string `json:"command"`
+	Calls       int64  `json:"calls"`
+	Usec        int64  `json:"usec"`
+	UsecPerCall string `json:"usec_per_call"`
+}
+
+//数据库key统计的行
+type keyspaceRow struct {
+	DB      string `json:"db"`
+	Keys    int64  `json:"keys"`
+	Expires int64  `json:"expires"`
+	AvgTTL  int64  `json:"avg_ttl"`
+}
+
+//top指标的行
+type topRow struct {
+	Metric string `json:"metric"`
+	Value  string `json:"value"`
+	Delta  string `json:"delta"`
+}
+
+//按分组查看服务器的info信息
+func infoCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "json")
+	if err == nil {
+		err = checkCMDFlags(flags, "json")
+	}
+	if err != nil {
+		return err
+	}
+	_, asJSON := flags["json"]
+	names := infoDefaultSections
+	if len(cmdParams) > 1 {
+		names = cmdParams[1:]
+	}
+	sections, err := db.GetRedisInfo("all")
+	if err != nil {
+		return err
+	}
+	selected := []model.RedisInfoSection{}
+	if len(names) == 1 && strings.ToLower(names[0]) == "all" {
+		selected = sections
+	} else {
+		for _, name := range names {
+			found := false
+			for _, section := range sections {
+				if section.Name == strings.ToLower(name) {
+					selected = append(selected, section)
+					found = true
+				}
+			}
+			if !found {
+				log.Printf("info中没有%s分组", name)
+			}
+		}
+	}
+	if asJSON {
+		content := make(map[string]map[string]string, len(selected))
+		for _, section := range selected {
+			items := make(map[string]string, len(section.Items))
+			for _, item := range section.Items {
+				items[item.Key] = item.Value
+			}
+			content[section.Name] = items
+		}
+		printRows(content, true)
+		return nil
+	}
+	for _, section := range selected {
+		log.Printf("# %s", section.Name)
+		if len(section.Items) == 0 {
+			continue
+		}
+		switch section.Name {
+		case "commandstats":
+			printRows(commandStatRows(section.Items), false)
+		case "keyspace":
+			printRows(keyspaceRows(section.Items), false)
+		default:
+			printRows(section.Items, false)
+		}
+	}
+	return nil
+}
+
+//解析info中calls=1,usec=2格式的值
+func parseInfoFields(value string) map[string]string {
+	fields := map[string]string{}
+	for _, item := range strings.Split(value, ",") {
+		if index := strings.Index(item, "="); index > 0 {
+			fields[item[:index]] = item[index+1:]
+		}
+	}
+	return fields
+}
+
+//命令统计按调用次数倒序排列
+func commandStatRows(items []model.KV) []commandStatRow {
+	rows := make([]commandStatRow, 0, len(items))
+	for _, item := range items {
+		fields := parseInfoFields(item.Value)
+		row := commandStatRow{Command: strings.TrimPrefix(item.Key, "cmdstat_"), UsecPerCall: fields["usec_per_call"]}
+		row.Calls, _ = strconv.ParseInt(fields["calls"], 10, 64)
+		row.Usec, _ = strconv.ParseInt(fields["usec"], 10, 64)
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Calls > rows[j].Calls })
+	return rows
+}
+
+func keyspaceRows(items []model.KV) []keyspaceRow {
+	rows := make([]keyspaceRow, 0, len(items))
+	for _, item := range items {
+		fields := parseInfoFields(item.Value)
+		row := keyspaceRow{DB: item.Key}
+		row.Keys, _ = strconv.ParseInt(fields["keys"], 10, 64)
+		row.Expires, _ = strconv.ParseInt(fields["expires"], 10, 64)
+		row.AvgTTL, _ = strconv.ParseInt(fields["avg_ttl"], 10, 64)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+//定时刷新服务器的关键指标，按Ctrl+C结束
+func topCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "json")
+	if err == nil {
+		err = checkCMDFlags(flags, "interval", "count", "json")
+	}
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) != 1 {
+		return fmt.Errorf("top不需要其他参数，请通过--interval、--count指定刷新间隔和次数")
+	}
+	interval := topDefaultInterval
+	if str, ok := flags["interval"]; ok {
+		if interval, err = util.ParseDuration(str); err != nil || interval < 500*time.Millisecond {
+			return fmt.Errorf("刷新间隔不能小于500毫秒")
+		}
+	}
+	count := 0
+	if str, ok := flags["count"]; ok {
+		if count, err = strconv.Atoi(str); err != nil || count <= 0 {
+			return fmt.Errorf("参数--count必须是正整数")
+		}
+	}
+	_, asJSON := flags["json"]
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last map[string]string
+	failed := 0
+	for i := 0; (count == 0 || i < count) && ctx.Err() == nil; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				continue
+			case <-ticker.C:
+			}
+		}
+		current, err := topSnapshot()
+		if err != nil {
+			log.Println(err)
+			failed++
+			continue
+		}
+		rows := topRows(last, current)
+		last = current
+		now := time.Now().Format("15:04:05")
+		if asJSON {
+			content, _ := json.Marshal(struct {
+				Time    string   `json:"time"`
+				Metrics []topRow `json:"metrics"`
+			}{now, rows})
+			fmt.Println(string(content))
+			continue
+		}
+		util.ClearConsoleScreen()
+		log.Printf("%s %s 每%s刷新，按Ctrl+C结束", conf.RedisConfName(), now, interval)
+		printRows(rows, false)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d次刷新失败", failed)
+	}
+	return nil
+}
+
+//获取默认分组的info信息，keyspace中各数据库的key数量合计为keys
+func topSnapshot() (map[string]string, error) {
+	sections, err := db.GetRedisInfo("")
+	if err != nil {
+		return nil, err
+	}
+	if len(sections) == 0 {
+		return nil, errors.New("info没有返回任何内容")
+	}
+	values := map[string]string{}
+	var keys int64
+	for _, section := range sections {
+		for _, item := range section.Items {
+			values[item.Key] = item.Value
+			if section.Name == "keyspace" {
+				count, _ := strconv.ParseInt(parseInfoFields(item.Value)["keys"], 10, 64)
+				keys += count
+			}
+		}
+	}
+	values["keys"] = strconv.FormatInt(keys, 10)
+	return values, nil
+}
+
+//根据前后两次的info信息生成指标，last为空时不计算变化量
+func topRows(last, current map[string]string) []topRow {
+	number := func(values map[string]string, name string) float64 {
+		num, _ := strconv.ParseFloat(values[name], 64)
+		return num
+	}
+	delta := func(name string, format func(float64) string) string {
+		if last == nil {
+			return ""
+		}
+		diff := number(current, name) - number(last, name)
+		if diff < 0 {
+			return "-" + format(-diff)
+		}
+		return "+" + format(diff)
+	}
+	integer := func(num float64) string {
+		return strconv.FormatInt(int64(num), 10)
+	}
+	bytes := func(num float64) string {
+		return util.FormatByteSize(int64(num))
+	}
+	hitRate := func(hits, misses float64) string {
+		if hits+misses == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f%%", hits*100/(hits+misses))
+	}
+	row := func(metric, name string, format func(float64) string) topRow {
+		return topRow{Metric: metric, Value: format(number(current, name)), Delta: delta(name, format)}
+	}
+	hits, misses := number(current, "keyspace_hits"), number(current, "keyspace_misses")
+	hitRow := topRow{Metric: "命中率", Value: hitRate(hits, misses)}
+	if last != nil {
+		hitRow.Delta = "区间" + hitRate(hits-number(last, "keyspace_hits"), misses-number(last, "keyspace_misses"))
+	}
+	return []topRow{
+		row("每秒命令数", "instantaneous_ops_per_sec", integer),
+		row("命令总数", "total_commands_processed", integer),
+		hitRow,
+		row("内存", "used_memory", bytes),
+		row("key数量", "keys", integer),
+		row("客户端连接数", "connected_clients", integer),
+		row("阻塞的客户端", "blocked_clients", integer),
+		row("淘汰的key", "evicted_keys", integer),
+		row("过期的key", "expired_keys", integer),
+		{Metric: "网络输入/输出", Value: fmt.Sprintf("%skbps/%skbps", current["instantaneous_input_kbps"], current["instantaneous_output_kbps"])},
+	}
+}
@@ -0,0 +1,139 @@
+package command
+
+import (
+	"fmt"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"strings"
+)
+
+//key过滤条件支持的参数
+var keyFilterFlags = []string{"type", "ttl", "no-ttl", "idle", "mem", "encoding"}
+
+//key过滤条件参数的说明
+const keyFilterUsage = "[--type 类型] [--ttl '<60'] [--no-ttl] [--idle '>7d'] [--mem '>1MB'] [--encoding 编码]"
+
+//解析比较条件，例如 >7d、<=1MB、60
+func parseNumCond(expr string, parse func(string) (int64, error)) (*model.NumCond, error) {
+	expr = strings.Trim(expr, "'\" ")
+	cond := &model.NumCond{Op: "="}
+	for _, op := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(expr, op) {
+			cond.Op = op
+			expr = strings.TrimPrefix(expr, op)
+			break
+		}
+	}
+	value, err := parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	cond.Value = value
+	return cond, nil
+}
+
+//从命令参数中解析key的过滤条件
+func parseKeyFilter(flags map[string]string) (*model.KeyFilter, error) {
+	filter := &model.KeyFilter{
+		Type:     strings.ToLower(strings.Trim(flags["type"], "'\"")),
+		Encoding: strings.ToLower(strings.Trim(flags["encoding"], "'\"")),
+	}
+	_, filter.NoTTL = flags["no-ttl"]
+	var err error
+	if expr, ok := flags["ttl"]; ok {
+		filter.TTL, err = parseNumCond(expr, func(str string) (int64, error) {
+			d, err := util.ParseDuration(str)
+			return d.Milliseconds(), err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("--ttl参数错误：%s", err.Error())
+		}
+	}
+	if expr, ok := flags["idle"]; ok {
+		filter.Idle, err = parseNumCond(expr, func(str string) (int64, error) {
+			d, err := util.ParseDuration(str)
+			return int64(d.Seconds()), err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("--idle参数错误：%s", err.Error())
+		}
+	}
+	if expr, ok := flags["mem"]; ok {
+		filter.Mem, err = parseNumCond(expr, util.ParseByteSize)
+		if err != nil {
+			return nil, fmt.Errorf("--mem参数错误：%s", err.Error())
+		}
+	}
+	if filter.NoTTL && filter.TTL != nil {
+		return nil, fmt.Errorf("--ttl与--no-ttl不能同时使用")
+	}
+	return filter, nil
+}
+
+//根据[y:忽略大小写|不传或n:精确] [keypattern]参数生成key的匹配函数，用于在本地匹配key
+func parseKeyMatcher(args []string) (func(key string) bool, error) {
+	ignoreCase := false
+	if len(args) >= 1 && (args[0] == "y" || args[0] == "n") {
+		ignoreCase = args[0] == "y"
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		return nil, fmt.Errorf("参数不符合规则")
+	}
+	pattern := "*"
+	if len(args) == 1 {
+		pattern = args[0]
+	}
+	if !ignoreCase {
+		return func(key string) bool {
+			return util.GlobMatch(pattern, key)
+		}, nil
+	}
+	patternReg, err := util.IgnoreCaseKeyRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(key string) bool {
+		return patternReg.MatchString(strings.ToLower(key))
+	}, nil
+}
+
+//拆分[y:忽略大小写|不传或n:精确]参数，返回是否忽略大小写和剩余参数
+func splitKeysMode(args []string) (bool, []string) {
+	if len(args) > 1 && (args[0] == "y" || args[0] == "n") {
+		return args[0] == "y", args[1:]
+	}
+	return false, args
+}
+
+//按精确或忽略大小写的方式查询key，查询结果写入返回的通道
+func searchKeysChan(ignoreCase bool, pattern string, filter *model.KeyFilter) chan string {
+	keysChan := make(chan string, 1000)
+	if ignoreCase {
+		go db.SearchRedisKeysIgnoreCase(pattern, filter, keysChan)
+		return keysChan
+	}
+	go func() {
+		defer close(keysChan)
+		for _, key := range db.SearchRedisKeys(pattern, filter) {
+			keysChan <- key
+		}
+	}()
+	return keysChan
+}
+
+//将通道中的key按批次交给handle处理
+func batchKeys(keysChan chan string, batchCount int, handle func(keys []string)) {
+	keys := make([]string, 0, batchCount)
+	for key := range keysChan {
+		keys = append(keys, key)
+		if len(keys) >= batchCount {
+			handle(keys)
+			keys = make([]string, 0, batchCount)
+		}
+	}
+	if len(keys) > 0 {
+		handle(keys)
+	}
+}
@@ -0,0 +1,363 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"rediscmd/src/conf"
+	"rediscmd/src/db"
+	"rediscmd/src/util"
+	"regexp"
+	"strings"
+)
+
+//40位十六进制的sha1
+var scriptSHAReg = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+//脚本库中脚本的行
+type luaScriptRow struct {
+	Name   string `json:"name"`
+	SHA    string `json:"sha"`
+	Size   string `json:"size"`
+	Loaded bool   `json:"loaded"`
+}
+
+//脚本缓存检查结果的行
+type scriptExistsRow struct {
+	Script string `json:"script"`
+	SHA    string `json:"sha"`
+	Exists bool   `json:"exists"`
+}
+
+//执行lua脚本 eval [file.lua|脚本库中的名称]
+func evalCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "ro", "json")
+	if err == nil {
+		err = checkCMDFlags(flags, "keys", "args", "ro", "json")
+	}
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) != 2 {
+		return fmt.Errorf("eval需要lua脚本文件或脚本库中的脚本名称参数，请重新输入")
+	}
+	_, readOnly := flags["ro"]
+	if err := checkScriptReadOnly(readOnly); err != nil {
+		return err
+	}
+	name, script, err := readLuaScript(cmdParams[1])
+	if err != nil {
+		return err
+	}
+	reply, err := db.EvalRedisScript(script, splitCMDList(flags["keys"]), splitCMDList(flags["args"]), readOnly)
+	if err != nil {
+		return fmt.Errorf("执行脚本%s出错：%s", name, err.Error())
+	}
+	_, asJSON := flags["json"]
+	printRedisReply(reply, asJSON)
+	return nil
+}
+
+//管理服务器的脚本缓存和本地脚本库 script load|exists|flush|list
+func luaScriptCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "json")
+	if err == nil {
+		err = checkCMDFlags(flags, "json")
+	}
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) < 2 {
+		return fmt.Errorf("script需要load|exists|flush|list参数，请重新输入")
+	}
+	_, asJSON := flags["json"]
+	switch cmdParams[1] {
+	case "load":
+		if len(cmdParams) != 3 {
+			return fmt.Errorf("script load需要lua脚本文件或脚本库中的脚本名称参数")
+		}
+		name, script, err := readLuaScript(cmdParams[2])
+		if err != nil {
+			return err
+		}
+		sha, err := db.LoadRedisScript(script)
+		if err != nil {
+			return err
+		}
+		log.Printf("脚本%s已加载，sha1为%s", name, sha)
+	case "exists":
+		if len(cmdParams) < 3 {
+			return fmt.Errorf("script exists需要sha1、lua脚本文件或脚本库中的脚本名称参数")
+		}
+		rows := []scriptExistsRow{}
+		for _, item := range cmdParams[2:] {
+			row := scriptExistsRow{Script: item, SHA: item}
+			if !scriptSHAReg.MatchString(item) {
+				_, script, err := readLuaScript(item)
+				if err != nil {
+					return err
+				}
+				row.SHA = db.RedisScriptSHA(script)
+			}
+			rows = append(rows, row)
+		}
+		shas := make([]string, 0, len(rows))
+		for _, row := range rows {
+			shas = append(shas, row.SHA)
+		}
+		exists, err := db.ExistsRedisScripts(shas)
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			rows[i].Exists = i < len(exists) && exists[i]
+		}
+		printRows(rows, asJSON)
+	case "flush":
+		if err := checkScriptWritable("清空脚本缓存"); err != nil {
+			return err
+		}
+		isSure, _ := util.ReadValueFromConsole("此操作将清空服务器的脚本缓存，请确认是否执行(y/n):", false)
+		if isSure != "y" {
+			return nil
+		}
+		if err := db.FlushRedisScripts(); err != nil {
+			return err
+		}
+		log.Println("脚本缓存已清空")
+	case "list":
+		return luaScriptListCMD(asJSON)
+	default:
+		return fmt.Errorf("script不支持【%s】操作，仅支持load|exists|flush|list", cmdParams[1])
+	}
+	return nil
+}
+
+//列出脚本库中的脚本及是否已缓存在服务器
+func luaScriptListCMD(asJSON bool) error {
+	dir := conf.ScriptLibraryPath()
+	files, err := filepath.Glob(filepath.Join(dir, "*.lua"))
+	if err != nil || len(files) == 0 {
+		log.Printf("脚本库%s中没有lua脚本", dir)
+		return nil
+	}
+	rows := make([]luaScriptRow, 0, len(files))
+	shas := make([]string, 0, len(files))
+	failed := 0
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Println(err)
+			failed++
+			continue
+		}
+		row := luaScriptRow{Name: strings.TrimSuffix(filepath.Base(file), ".lua"), SHA: db.RedisScriptSHA(string(content)), Size: util.FormatByteSize(int64(len(content)))}
+		rows = append(rows, row)
+		shas = append(shas, row.SHA)
+	}
+	exists, err := db.ExistsRedisScripts(shas)
+	for i := range rows {
+		rows[i].Loaded = err == nil && i < len(exists) && exists[i]
+	}
+	printRows(rows, asJSON)
+	if err != nil {
+		return fmt.Errorf("检查脚本缓存失败：%s", err.Error())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d个脚本读取失败", failed)
+	}
+	return nil
+}
+
+//管理和调用redis7的函数 function load|list|call|delete|flush
+func functionCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "replace", "ro", "json")
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) < 2 {
+		return fmt.Errorf("function需要load|list|call|delete|flush参数，请重新输入")
+	}
+	_, asJSON := flags["json"]
+	switch cmdParams[1] {
+	case "load":
+		if err := checkCMDFlags(flags, "replace"); err != nil {
+			return err
+		}
+		if len(cmdParams) != 3 {
+			return fmt.Errorf("function load需要lua脚本文件或脚本库中的脚本名称参数")
+		}
+		if err := checkScriptWritable("加载函数库"); err != nil {
+			return err
+		}
+		_, code, err := readLuaScript(cmdParams[2])
+		if err != nil {
+			return err
+		}
+		_, replace := flags["replace"]
+		library, err := db.LoadRedisFunction(code, replace)
+		if err != nil {
+			return err
+		}
+		log.Printf("函数库%s已加载", library)
+	case "list":
+		if err := checkCMDFlags(flags, "json"); err != nil {
+			return err
+		}
+		pattern := ""
+		if len(cmdParams) > 2 {
+			pattern = cmdParams[2]
+		}
+		functions, err := db.ListRedisFunctions(pattern)
+		if err != nil {
+			return err
+		}
+		if len(functions) == 0 {
+			log.Println("没有匹配的函数")
+			return nil
+		}
+		printRows(functions, asJSON)
+	case "call":
+		if err := checkCMDFlags(flags, "keys", "args", "ro", "json"); err != nil {
+			return err
+		}
+		if len(cmdParams) != 3 {
+			return fmt.Errorf("function call需要函数名称参数")
+		}
+		_, readOnly := flags["ro"]
+		if err := checkScriptReadOnly(readOnly); err != nil {
+			return err
+		}
+		reply, err := db.CallRedisFunction(cmdParams[2], splitCMDList(flags["keys"]), splitCMDList(flags["args"]), readOnly)
+		if err != nil {
+			return fmt.Errorf("调用函数%s出错：%s", cmdParams[2], err.Error())
+		}
+		printRedisReply(reply, asJSON)
+	case "delete", "flush":
+		if err := checkCMDFlags(flags); err != nil {
+			return err
+		}
+		return functionDeleteCMD(cmdParams)
+	default:
+		return fmt.Errorf("function不支持【%s】操作，仅支持load|list|call|delete|flush", cmdParams[1])
+	}
+	return nil
+}
+
+//删除指定的函数库或全部函数库
+func functionDeleteCMD(cmdParams []string) error {
+	if cmdParams[1] == "delete" && len(cmdParams) != 3 {
+		return fmt.Errorf("function delete需要函数库名称参数")
+	}
+	if err := checkScriptWritable("删除函数库"); err != nil {
+		return err
+	}
+	msg := "此操作将删除服务器上的所有函数库，请确认是否执行(y/n):"
+	if cmdParams[1] == "delete" {
+		msg = fmt.Sprintf("请确认是否删除函数库%s(y/n):", cmdParams[2])
+	}
+	isSure, _ := util.ReadValueFromConsole(msg, false)
+	if isSure != "y" {
+		return nil
+	}
+	var err error
+	if cmdParams[1] == "delete" {
+		err = db.DeleteRedisFunction(cmdParams[2])
+	} else {
+		err = db.FlushRedisFunctions()
+	}
+	if err != nil {
+		return err
+	}
+	log.Println("函数库已删除")
+	return nil
+}
+
+//只读配置下只能以只读方式执行脚本和函数
+func checkScriptReadOnly(readOnly bool) error {
+	if db.IsRedisReadOnly() && !readOnly {
+		return fmt.Errorf("当前配置%s为只读配置，只能通过--ro以只读方式执行（需要redis7）", conf.RedisConfName())
+	}
+	return nil
+}
+
+//只读配置下禁止修改服务器上的脚本和函数
+func checkScriptWritable(action string) error {
+	if db.IsRedisReadOnly() {
+		return fmt.Errorf("当前配置%s为只读配置，禁止%s", conf.RedisConfName(), action)
+	}
+	return nil
+}
+
+//读取lua脚本，参数不是已存在的文件时从脚本库目录中按名称查找（可省略.lua后缀），返回脚本名称和内容
+func readLuaScript(nameOrFile string) (string, string, error) {
+	file := nameOrFile
+	if _, err := os.Stat(file); err != nil {
+		file = filepath.Join(conf.ScriptLibraryPath(), nameOrFile)
+		if !strings.HasSuffix(file, ".lua") {
+			file += ".lua"
+		}
+		if _, err := os.Stat(file); err != nil {
+			return "", "", fmt.Errorf("%s既不是lua脚本文件也不在脚本库%s中", nameOrFile, conf.ScriptLibraryPath())
+		}
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSuffix(filepath.Base(file), ".lua"), string(content), nil
+}
+
+//拆分逗号分隔的参数列表，为空时返回nil
+func splitCMDList(str string) []string {
+	if str == "" {
+		return nil
+	}
+	return strings.Split(str, ",")
+}
+
+//按redis-cli的格式或json格式输出脚本和函数的返回值
+func printRedisReply(reply interface{}, asJSON bool) {
+	if asJSON {
+		content, _ := json.MarshalIndent(reply, "", "  ")
+		fmt.Println(string(content))
+		return
+	}
+	fmt.Println(strings.Join(formatRedisReply(reply), "\n"))
+}
+
+//将返回值格式化为多行，数组元素按序号缩进
+func formatRedisReply(reply interface{}) []string {
+	switch v := reply.(type) {
+	case nil:
+		return []string{"(nil)"}
+	case int64:
+		return []string{fmt.Sprintf("(integer) %d", v)}
+	case string:
+		if strings.HasPrefix(v, "(error) ") {
+			return []string{v}
+		}
+		return []string{fmt.Sprintf("%q", v)}
+	case []interface{}:
+		if len(v) == 0 {
+			return []string{"(empty array)"}
+		}
+		lines := []string{}
+		for i, item := range v {
+			prefix := fmt.Sprintf("%d) ", i+1)
+			for j, line := range formatRedisReply(item) {
+				if j == 0 {
+					lines = append(lines, prefix+line)
+				} else {
+					lines = append(lines, strings.Repeat(" ", len(prefix))+line)
+				}
+			}
+		}
+		return lines
+	default:
+		return []string{fmt.Sprintf("%v", v)}
+	}
+}
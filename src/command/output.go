@@ -0,0 +1,76 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"rediscmd/src/model"
+	"rediscmd/src/rdb"
+	"reflect"
+	"strings"
+
+	"github.com/modood/table"
+)
+
+//以表格或json格式输出数据，rows为结构体切片
+func printRows(rows interface{}, asJSON bool) {
+	if asJSON {
+		content, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		fmt.Println(string(content))
+		return
+	}
+	fmt.Println(table.AsciiTable(rows))
+}
+
+//以表格格式输出列不固定的数据，通过table标签设置表头，rows中每行的值与columns一一对应
+func printColumns(columns []string, rows [][]string) {
+	fields := make([]reflect.StructField, 0, len(columns))
+	for i, column := range columns {
+		fields = append(fields, reflect.StructField{Name: fmt.Sprintf("F%d", i), Type: reflect.TypeOf(""), Tag: reflect.StructTag(fmt.Sprintf("table:%q", column))})
+	}
+	rowType := reflect.StructOf(fields)
+	items := reflect.MakeSlice(reflect.SliceOf(rowType), 0, len(rows))
+	for _, row := range rows {
+		item := reflect.New(rowType).Elem()
+		for i := 0; i < len(columns) && i < len(row); i++ {
+			item.Field(i).SetString(row[i])
+		}
+		items = reflect.Append(items, item)
+	}
+	fmt.Println(table.AsciiTable(items.Interface()))
+}
+
+//将缓存值格式化为字符串，集合类型输出为json
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []model.KV:
+		return formatKVs(v)
+	case []rdb.StreamEntry:
+		items := make([]string, 0, len(v))
+		for _, entry := range v {
+			id, _ := json.Marshal(entry.ID)
+			items = append(items, fmt.Sprintf(`{"id":%s,"fields":%s}`, id, formatKVs(entry.Fields)))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	default:
+		content, _ := json.Marshal(v)
+		return string(content)
+	}
+}
+
+//将键值对按原有顺序格式化为json对象
+func formatKVs(kvs []model.KV) string {
+	items := make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		key, _ := json.Marshal(kv.Key)
+		value, _ := json.Marshal(kv.Value)
+		items = append(items, string(key)+":"+string(value))
+	}
+	return "{" + strings.Join(items, ",") + "}"
+}
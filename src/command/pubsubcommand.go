@@ -0,0 +1,151 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+//频道订阅者数量的行
+type channelRow struct {
+	Channel     string `json:"channel"`
+	Subscribers int64  `json:"subscribers"`
+}
+
+//订阅消息的行
+type messageRow struct {
+	Time    string `json:"time"`
+	Pattern string `json:"pattern,omitempty"`
+	Channel string `json:"channel"`
+	Data    string `json:"data"`
+}
+
+//发布消息，消息中的多个参数以空格拼接
+func publishCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams)
+	if err == nil {
+		err = checkCMDFlags(flags, "encode")
+	}
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) < 3 {
+		return fmt.Errorf("发布消息需要频道和消息两个参数，请重新输入")
+	}
+	message, err := encodeValue(flags, strings.Join(cmdParams[2:], " "))
+	if err != nil {
+		return err
+	}
+	count, err := db.PublishRedis(cmdParams[1], message)
+	if err != nil {
+		return err
+	}
+	log.Printf("消息已发布到%s，%d个订阅者收到消息", cmdParams[1], count)
+	return nil
+}
+
+//订阅频道，pattern为true时按模式订阅，按Ctrl+C结束
+func subscribeCMD(cmdParams []string, pattern bool) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "json")
+	if err == nil {
+		err = checkCMDFlags(flags, append(valueCodecFlags, "json")...)
+	}
+	if err != nil {
+		return err
+	}
+	decode, err := parseValueDecoder(flags)
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) < 2 {
+		return fmt.Errorf("订阅需要至少一个频道参数，请重新输入")
+	}
+	_, asJSON := flags["json"]
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	msgChan := make(chan model.PubSubMessage, 1000)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- db.SubscribeRedis(ctx, pattern, cmdParams[1:], msgChan)
+	}()
+	log.Printf("已订阅%s，按Ctrl+C结束", strings.Join(cmdParams[1:], " "))
+	count := 0
+	for msg := range msgChan {
+		count++
+		row := messageRow{Time: time.Now().Format("15:04:05.000"), Pattern: msg.Pattern, Channel: msg.Channel, Data: decode(msg.Data)}
+		if asJSON {
+			content, _ := json.Marshal(row)
+			fmt.Println(string(content))
+		} else {
+			log.Printf("%s [%s] %s", row.Time, row.Channel, row.Data)
+		}
+	}
+	log.Printf("订阅结束，共收到%d条消息", count)
+	return <-errChan
+}
+
+//查询频道信息 pubsub channels [pattern]|numsub [channel...]|numpat
+func pubsubCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "json")
+	if err == nil {
+		err = checkCMDFlags(flags, "json")
+	}
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) < 2 {
+		return fmt.Errorf("pubsub需要channels|numsub|numpat参数，请重新输入")
+	}
+	_, asJSON := flags["json"]
+	switch cmdParams[1] {
+	case "channels", "numsub":
+		channels := cmdParams[2:]
+		if cmdParams[1] == "channels" {
+			if len(channels) > 1 {
+				return fmt.Errorf("pubsub channels最多一个模式参数")
+			}
+			pattern := ""
+			if len(channels) == 1 {
+				pattern = channels[0]
+			}
+			if channels, err = db.PubSubRedisChannels(pattern); err != nil {
+				return err
+			}
+			sort.Strings(channels)
+		}
+		if len(channels) == 0 {
+			log.Println("没有活跃的频道")
+			return nil
+		}
+		counts, err := db.PubSubRedisNumSub(channels)
+		if err != nil {
+			return err
+		}
+		rows := make([]channelRow, 0, len(channels))
+		for i, channel := range channels {
+			row := channelRow{Channel: channel}
+			if i < len(counts) {
+				row.Subscribers = counts[i]
+			}
+			rows = append(rows, row)
+		}
+		printRows(rows, asJSON)
+	case "numpat":
+		count, err := db.PubSubRedisNumPat()
+		if err != nil {
+			return err
+		}
+		log.Printf("当前共有%d个模式订阅", count)
+	default:
+		return fmt.Errorf("pubsub不支持【%s】操作，仅支持channels|numsub|numpat", cmdParams[1])
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package command
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"rediscmd/src/model"
+	"rediscmd/src/rdb"
+	"rediscmd/src/resp"
+	"rediscmd/src/util"
+	"strconv"
+	"time"
+)
+
+//离线解析rdb文件
+func rdbCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "no-ttl", "json")
+	if err == nil {
+		err = checkCMDFlags(flags, append(append(keyFilterFlags, analyzeFlags...), "db", "out", "format")...)
+	}
+	if err != nil {
+		return err
+	}
+	if !checkCMDParamsCount(cmdParams, 3) {
+		return fmt.Errorf("解析rdb文件需要至少3个参数：rdb [keys|get|analyze|export] [file.rdb]，请重新输入")
+	}
+	filter, err := parseKeyFilter(flags)
+	if err != nil {
+		return err
+	}
+	match, err := parseKeyMatcher(cmdParams[3:])
+	if err != nil {
+		return err
+	}
+	dbid := -1
+	if str, ok := flags["db"]; ok {
+		if dbid, err = strconv.Atoi(str); err != nil || dbid < 0 {
+			return fmt.Errorf("无法解析您输入的数据库编号")
+		}
+	}
+	file := cmdParams[2]
+	switch cmdParams[1] {
+	case "keys":
+		return rdbKeysCMD(file, dbid, match, filter)
+	case "get":
+		return rdbGetCMD(file, dbid, match, filter)
+	case "analyze":
+		opts, err := parseAnalyzeOptions(flags)
+		if err != nil {
+			return err
+		}
+		return rdbAnalyzeCMD(file, dbid, match, filter, opts)
+	case "export":
+		return rdbExportCMD(file, dbid, match, filter, flags["out"], flags["format"])
+	}
+	return fmt.Errorf("rdb不支持【%s】操作，仅支持keys|get|analyze|export", cmdParams[1])
+}
+
+//遍历rdb文件中满足条件的key
+func walkRDBFile(file string, dbid int, match func(string) bool, filter *model.KeyFilter, handle func(entry *rdb.Entry, now int64) bool) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	parser := rdb.NewParser(f)
+	start := time.Now()
+	err = parser.Parse(func(entry *rdb.Entry) bool {
+		if dbid >= 0 && entry.DB != dbid {
+			return true
+		}
+		if !match(entry.Key) {
+			return true
+		}
+		now := time.Now().UnixNano() / int64(time.Millisecond)
+		if ctime, err := strconv.ParseInt(parser.Aux["ctime"], 10, 64); err == nil {
+			now = ctime * 1000 //按生成rdb文件的时间计算剩余过期时间
+		}
+		if !matchEntryFilter(entry, filter, now) {
+			return true
+		}
+		return handle(entry, now)
+	})
+	if err != nil {
+		return fmt.Errorf("解析rdb文件出错：%s", err.Error())
+	}
+	log.Printf("rdb文件(版本%d)解析结束，耗时%d毫秒", parser.Version, time.Since(start).Milliseconds())
+	return nil
+}
+
+//按过滤条件检查rdb文件中的key，内存占用按rdb文件中的大小计算
+func matchEntryFilter(entry *rdb.Entry, filter *model.KeyFilter, now int64) bool {
+	if filter.IsEmpty() {
+		return true
+	}
+	if filter.Type != "" && filter.Type != entry.Type {
+		return false
+	}
+	if filter.Encoding != "" && filter.Encoding != entry.Encoding {
+		return false
+	}
+	if filter.NoTTL && entry.ExpireAt != 0 {
+		return false
+	}
+	if filter.TTL != nil && (entry.ExpireAt == 0 || !filter.TTL.Match(entry.ExpireAt-now)) {
+		return false
+	}
+	if filter.Idle != nil && (entry.Idle < 0 || !filter.Idle.Match(entry.Idle)) {
+		return false
+	}
+	if filter.Mem != nil && !filter.Mem.Match(entry.Size) {
+		return false
+	}
+	return true
+}
+
+//查询rdb文件中的key
+func rdbKeysCMD(file string, dbid int, match func(string) bool, filter *model.KeyFilter) error {
+	count := 0
+	err := walkRDBFile(file, dbid, match, filter, func(entry *rdb.Entry, now int64) bool {
+		count++
+		log.Printf("db(%d) %s", entry.DB, entry.Key)
+		return true
+	})
+	log.Printf("共查询到%d个key", count)
+	return err
+}
+
+//查询rdb文件中key的值
+func rdbGetCMD(file string, dbid int, match func(string) bool, filter *model.KeyFilter) error {
+	return walkRDBFile(file, dbid, match, filter, func(entry *rdb.Entry, now int64) bool {
+		log.Println(fmt.Sprintf("db(%d) %s=%s", entry.DB, entry.Key, formatValue(entry.Value)))
+		return true
+	})
+}
+
+//分析rdb文件的内存占用
+func rdbAnalyzeCMD(file string, dbid int, match func(string) bool, filter *model.KeyFilter, opts *analyzeOptions) error {
+	keyInfoChan := make(chan model.RedisKeyInfo, 1000)
+	var walkErr error
+	go func() {
+		defer close(keyInfoChan)
+		count := 0
+		walkErr = walkRDBFile(file, dbid, match, filter, func(entry *rdb.Entry, now int64) bool {
+			keyInfo := model.RedisKeyInfo{Key: entry.Key, Type: entry.Type, Memory: entry.Size, Elements: entry.Elements, TTL: -1}
+			if entry.ExpireAt != 0 {
+				keyInfo.TTL = entry.ExpireAt - now
+			}
+			keyInfoChan <- keyInfo
+			count++
+			return opts.sample <= 0 || count < opts.sample
+		})
+	}()
+	report := buildAnalyzeReport(keyInfoChan, opts)
+	if !opts.asJSON {
+		log.Println("内存占用按key在rdb文件中的大小统计")
+	}
+	printAnalyzeReport(report, opts)
+	return walkErr
+}
+
+//导出rdb文件中的key，json格式每行一个key，resp格式可通过redis-cli --pipe导入
+func rdbExportCMD(file string, dbid int, match func(string) bool, filter *model.KeyFilter, out, format string) error {
+	if out == "" {
+		return fmt.Errorf("请通过--out指定导出文件")
+	}
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "resp" {
+		return fmt.Errorf("导出格式仅支持json|resp")
+	}
+	if _, err := os.Stat(out); err == nil {
+		isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("文件%s已存在，请确认是否覆盖(y/n):", out), false)
+		if isSure != "y" {
+			return nil
+		}
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	writer := bufio.NewWriter(f)
+	defer writer.Flush()
+	count, skipCount, lastDB := 0, 0, -1
+	var writeErr error
+	err = walkRDBFile(file, dbid, match, filter, func(entry *rdb.Entry, now int64) bool {
+		if format == "json" {
+			content, err := json.Marshal(entry)
+			if err != nil {
+				writeErr = err
+				return false
+			}
+			writer.Write(content)
+			writer.WriteString("\n")
+			count++
+			return true
+		}
+		if entry.Type == "module" {
+			skipCount++
+			return true
+		}
+		if entry.DB != lastDB {
+			resp.WriteCommand(writer, "select", strconv.Itoa(entry.DB))
+			lastDB = entry.DB
+		}
+		if err := writeEntryCommands(writer, entry); err != nil {
+			writeErr = err
+			return false
+		}
+		count++
+		return true
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	log.Printf("共导出%d个key到%s", count, out)
+	if skipCount > 0 {
+		log.Printf("跳过%d个模块类型的key", skipCount)
+	}
+	return err
+}
+
+//将rdb文件中的key以RESP格式写入
+func writeEntryCommands(writer *bufio.Writer, entry *rdb.Entry) error {
+	args, err := entry.Commands()
+	if err != nil {
+		return err
+	}
+	for _, item := range args {
+		if err := resp.WriteCommand(writer, item...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
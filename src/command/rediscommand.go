@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"rediscmd/src/conf"
 	"rediscmd/src/db"
 	"rediscmd/src/model"
@@ -12,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/modood/table"
@@ -19,7 +21,14 @@ import (
 
 var InputReader *bufio.Reader
 
-type cmdParamfunc func([]string)
+type cmdParamfunc func([]string, *model.KeyFilter) error
+
+//无需连接redis即可执行的命令，可以直接通过命令行参数执行
+var offlineCMDs = map[string]bool{"rdb": true, "aof": true}
+
+//修改缓存的命令，只读配置下禁止执行
+var writeCMDs = map[string]bool{"del": true, "set": true, "edit": true, "expire": true, "persist": true, "rename": true, "publish": true,
+	"hset": true, "hdel": true, "lpush": true, "rpush": true, "lset": true, "lrem": true, "sadd": true, "srem": true, "zadd": true, "zrem": true, "zincrby": true, "incr": true, "decr": true, "incrbyfloat": true}
 
 //启动程序
 func RedisCMDStart() {
@@ -32,6 +41,10 @@ func RedisCMDStart() {
 			os.Exit(1)
 		}
 	}()
+	if cmdParams := dealCMDParams(os.Args[1:]); len(cmdParams) > 0 {
+		runCMDArgs(cmdParams) //通过命令行参数执行的命令执行完直接退出
+		return
+	}
 	db.InitRedisInfo(true) //初始化redis信息
 	funcOptionMsg()        //功能提示语
 	for {
@@ -39,6 +52,60 @@ func RedisCMDStart() {
 	}
 }
 
+//非交互方式执行命令行参数中的命令，--conf指定配置名称[:数据库编号]，不指定时与交互方式一样选择配置文件
+func runCMDArgs(cmdParams []string) {
+	if offlineCMDs[cmdParams[0]] {
+		if err := execCMD(cmdParams); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	cmdParams, endpoint, hasConf, err := takeGlobalFlag(cmdParams, "conf")
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	cmdParams, profiles, hasProfiles, err := takeGlobalFlag(cmdParams, "profiles")
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	if len(cmdParams) == 0 {
+		log.Println("请输入需要执行的命令")
+		os.Exit(1)
+	}
+	dbid := 0
+	if hasConf {
+		var profile string
+		if profile, dbid, err = parseProfileEndpoint(endpoint); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		conf.SetRedisConfName(filepath.Base(conf.ProfileConfAbsPath(profile)))
+	}
+	if hasProfiles { //分发到多个配置执行，当前进程无需连接redis
+		if err := fanOutCMD(profiles, cmdParams); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if !hasConf {
+		db.InitRedisInfo(true)
+	} else if err := db.InitRedisProfile(); err != nil {
+		log.Printf("初始化%s的redis连接失败：%s", endpoint, err.Error())
+		os.Exit(1)
+	}
+	if dbid >= db.RedisDBCount() {
+		log.Printf("数据库编号%d不在[0~%d)范围内", dbid, db.RedisDBCount())
+		os.Exit(1)
+	}
+	db.ChangeRedisOptionDBId(dbid)
+	if err := execCMD(cmdParams); err != nil {
+		os.Exit(1) //便于脚本或多配置执行时判断执行结果
+	}
+}
+
 //功能列表选择
 func funcOptionMsg() {
 	log.Println("https://github.com/pwzos/rediscmd")
@@ -50,10 +117,47 @@ func funcOptionMsg() {
 	log.Println(conf.RedisConfName() + "\r\n" + content)
 	msg := []model.KV{
 		{Key: "cls", Value: "清屏"},
-		{Key: "keys", Value: "模糊查询缓存key [y:忽略大小写|不传或n:精确] [keypattern]"},
-		{Key: "get", Value: "查询模糊key的值 [y:忽略大小写|不传或n:精确] [keypattern]"},
-		{Key: "del", Value: "写删除模糊key的值 [y:忽略大小写|不传或n:精确] [keypattern]"},
-		{Key: "set", Value: "设置精确key的值 [key] [value]"},
+		{Key: "keys", Value: "模糊查询缓存key [y:忽略大小写|不传或n:精确] [keypattern] " + keyFilterUsage},
+		{Key: "get", Value: "查询模糊key的值 [y:忽略大小写|不传或n:精确] [keypattern] " + valueCodecUsage + " [--out 文件或目录，将值的原始字节写入文件，目录时每个key写入单独的文件] [--max-size 跳过超过大小的值，--decode解码后的大小同样受限，默认512MB] " + keyFilterUsage},
+		{Key: "del", Value: "写删除模糊key的值 [y:忽略大小写|不传或n:精确] [keypattern] " + keyFilterUsage},
+		{Key: "set", Value: "设置精确key的值 [key] [value，@文件表示读取文件的原始字节，@@开头表示以@开头的值] [--max-size 文件大小限制，默认512MB] [--ex 过期时间|--px 过期毫秒数|--keepttl] [--nx|--xx] [--encode 编解码链，与--decode顺序相同，例如gzip,json]"},
+		{Key: "hset|hdel", Value: "写入或删除hash字段 [key] [field] [value]...|[key] [field...]，hset支持--file 文件 [--format lines|json]，每行为field value或json对象"},
+		{Key: "lpush|rpush|lset|lrem", Value: "写入list [key] [value...]|[key] [index] [value]|[key] [count] [value]，lpush、rpush支持--file 文件 [--format lines|json]，每行一个值或json数组"},
+		{Key: "sadd|srem", Value: "写入或删除set成员 [key] [member...]，支持--file 文件 [--format lines|json]"},
+		{Key: "zadd|zrem|zincrby", Value: "写入sorted set [key] [score member...] [--nx|--xx] [--gt|--lt] [--ch]|[key] [member...]|[key] [increment] [member]，zadd支持--file 文件，每行为score member或{member:score}格式的json对象"},
+		{Key: "incr|decr|incrbyfloat", Value: "增减计数器的值 [key] [增量，默认1]|[key] [浮点增量]"},
+		{Key: "edit", Value: "在$EDITOR中编辑key的值，集合类型以json格式编辑，保留过期时间 [key]"},
+		{Key: "expire", Value: "批量设置模糊key的过期时间 [y:忽略大小写|不传或n:精确] [keypattern] [过期时间，例如60、10m、7d] " + keyFilterUsage},
+		{Key: "persist", Value: "批量移除模糊key的过期时间 [y:忽略大小写|不传或n:精确] [keypattern] " + keyFilterUsage},
+		{Key: "ttl", Value: "查看模糊key的过期时间及分布 [y:忽略大小写|不传或n:精确] [keypattern] " + keyFilterUsage},
+		{Key: "watch", Value: "通过keyspace通知实时监视key的变化，按Ctrl+C结束（事务中watch为redis的WATCH，见begin） [keypattern] [--value 同时输出新值] [--poll 轮询间隔，例如1s，不使用通知] [--json] " + valueCodecUsage},
+		{Key: "publish", Value: "发布消息 [channel] [message] [--encode 编解码链]"},
+		{Key: "subscribe", Value: "订阅频道，按Ctrl+C结束 [channel...] [--json] " + valueCodecUsage},
+		{Key: "psubscribe", Value: "按模式订阅频道，按Ctrl+C结束 [pattern...] [--json] " + valueCodecUsage},
+		{Key: "pubsub", Value: "查询频道信息 [channels [pattern]|numsub [channel...]|numpat] [--json]"},
+		{Key: "stream", Value: "查看和管理stream及消费组 [info|groups] [key]|[range] [key] [start] [end] [--count 数量] [--rev] " + valueCodecUsage + "|[consumers] [key] [group]|[pending] [key] [group] [start] [end] [--count 数量] [--consumer 消费者] [--idle 最小空闲时间]|[claim] [key] [group] [consumer] [最小空闲时间] [id...]|[ack] [key] [group] [id...]|[del] [key] [id...]|[trim] [key] [maxlen|minid] [阈值] [--approx]，查询支持--json"},
+		{Key: "grep", Value: "在缓存值中查找内容（包括hash字段、list/set/zset成员和stream消息），可按Ctrl+C中止 [y:忽略大小写|不传或n:精确] [值的正则表达式] [模糊key，默认全部] [--limit 最大匹配数] [--json] " + keyFilterUsage},
+		{Key: "rename", Value: "按正则批量重命名key，key中所有匹配的部分都会被替换，只替换开头时以^锚定，以^开头时只查询字面量前缀的key [y:忽略大小写|不传或n:精确] [正则表达式] [替换内容，例如order:v2:${1}] [--copy] [--to-db 编号] [--force] " + keyFilterUsage},
+		{Key: "diff", Value: "对比两个配置或数据库中的key，报告缺失、类型、过期时间和值的差异，可按Ctrl+C中止 [keypattern] [--right 配置名称[:数据库编号]] [--left 配置名称[:数据库编号]，默认当前] [--typed 按类型对比值] [--ttl-tolerance 5s] [--workers 4] [--patch 同步命令文件] [--limit 最大差异数] [--json] " + keyFilterUsage},
+		{Key: "sync", Value: "将源中匹配的key复制到目标，再通过keyspace通知持续同步变化，按Ctrl+C结束 [--to 配置名称[:数据库编号]] [--from 配置名称[:数据库编号]，默认当前] [--pattern keypattern] [--state 进度文件，中断后继续初始复制]"},
+		{Key: "browse", Value: "全屏浏览keyspace，按:拆分目录，支持搜索、切换数据库和查看值（仅支持linux/macos终端）"},
+		{Key: "analyze", Value: "分析当前数据库的大key和内存占用 [keypattern] [--sample 数量] [--delimiter :] [--depth 2] [--top 10] [--json] " + keyFilterUsage},
+		{Key: "rdb", Value: "离线解析rdb文件 [keys|get|analyze|export] [file.rdb] [y:忽略大小写|不传或n:精确] [keypattern] [--db 编号]，analyze支持analyze命令的参数，export需要--out 文件 [--format json|resp] " + keyFilterUsage},
+		{Key: "aof", Value: "解析aof文件或命令流 [inspect|replay] [file|dir|manifest] [y:忽略大小写|不传或n:精确] [keypattern] [--db 编号] [--cmd set,del]，replay需要--to 配置名称 [--rate 每秒命令数]"},
+		{Key: "info", Value: "按分组查看服务器信息 [分组...，默认memory clients persistence replication stats keyspace commandstats，all为全部] [--json]"},
+		{Key: "top", Value: "定时刷新服务器的命令数、命中率、内存、连接数和淘汰数，按Ctrl+C结束 [--interval 刷新间隔，默认2s] [--count 刷新次数] [--json]"},
+		{Key: "slowlog", Value: "查看慢查询日志 [数量，默认128，all为全部] [--sort time|duration] [--group 按命令汇总] [--reset 清空] [--json]"},
+		{Key: "latency", Value: "查看延迟监控 [latest|history 事件名称|doctor] [--json]"},
+		{Key: "clients", Value: "查看客户端连接，kill断开匹配的连接 [kill [地址]] [--addr ip或glob] [--name glob] [--idle '>300'] [--db 编号] [--group ip|name] [--json]"},
+		{Key: "config", Value: "查看、修改和对比服务器配置 [get] [pattern] [--json]|[set] [名称] [值] [--rewrite 写入配置文件]|[diff] [pattern] [--against 配置名称或redis.conf文件] [--json]"},
+		{Key: "eval", Value: "执行lua脚本，优先通过evalsha执行服务器缓存的脚本 [lua脚本文件或脚本库scripts目录中的名称] [--keys k1,k2] [--args a,b] [--ro 只读方式执行，需要redis7] [--json]"},
+		{Key: "script", Value: "管理服务器的脚本缓存 [load] [脚本]|[exists] [sha1或脚本...]|[flush]|[list 列出脚本库中的脚本] [--json]"},
+		{Key: "function", Value: "管理和调用redis7的函数 [load] [脚本] [--replace]|[list] [库名称pattern] [--json]|[call] [函数名称] [--keys k1,k2] [--args a,b] [--ro] [--json]|[delete] [库名称]|[flush]"},
+		{Key: "begin", Value: "开始事务，之后的set、del、expire、persist、publish等写命令以精确key排队，commit时通过multi/exec原子执行；事务中watch [key...]不再实时监视，而是通过redis的WATCH监视key，commit前被修改则事务不执行；事务中不能执行eval、function call（--ro除外）、stream claim|ack|del|trim、config set、clients kill、script flush、slowlog --reset等无法排队的写操作"},
+		{Key: "commit", Value: "提交事务，输出每条命令的执行结果"},
+		{Key: "discard", Value: "放弃事务中排队的命令"},
+		{Key: "run", Value: "逐行执行脚本文件中的命令并记录每行的执行结果，交互方式下也可使用source [脚本文件] [--vars 名称=值,...] [--on-error stop|continue，默认stop] [--yes 自动确认脚本中的操作]，脚本中#开头为注释，var 名称 值定义变量，${名称}引用变量或环境变量，on-error stop|continue切换出错后的处理方式"},
+		{Key: "--profiles", Value: "在多个配置上并发执行命令，输出按配置名称区分并汇总执行结果，可加在除cls、edit、browse、diff、sync等命令外的任意命令后 [配置名称[:数据库编号],...或配置组名称] [--merge 将各配置的--json输出合并为一个表格]，配置组在当前配置文件中以[group \"名称\"]和Profiles=a,b,c定义"},
 		{Key: "ldb", Value: fmt.Sprintf("加载数据库列表 [y|n] [0~%d)", db.RedisDBCount())},
 		{Key: "resetconf", Value: fmt.Sprintf("重新配置%s文件内容", conf.RedisConfName())},
 		{Key: "changeconf", Value: "切换配置文件"},
@@ -76,36 +180,144 @@ func funcOption() {
 			return
 		}
 	}()
-	option, _ := util.ReadValueFromConsole("请输出操作命令(回车结束输入)", false)
+	notice := "请输出操作命令(回车结束输入)"
+	if currentTx != nil {
+		notice = fmt.Sprintf("请输出操作命令(回车结束输入)[事务中，已排队%d条命令]", len(txQueued))
+	}
+	option, eof := util.ReadValueFromConsole(notice, false)
+	if eof < 0 {
+		os.Exit(0) //输入已结束，退出程序
+	}
 	cmdParams := strings.Split(option, " ")
 	cmdParams = dealCMDParams(cmdParams) //处理命令行参数
+	execCMD(cmdParams)
+}
+
+//执行一条命令，执行失败时输出并返回错误，脚本和多配置执行的子进程以此判断执行结果
+func execCMD(cmdParams []string) error {
+	err := runCMD(cmdParams)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+//分发命令到对应的处理函数
+func runCMD(cmdParams []string) error {
+	cmdParams, profiles, hasProfiles, err := takeGlobalFlag(cmdParams, "profiles")
+	if err != nil {
+		return err
+	}
+	if hasProfiles {
+		return fanOutCMD(profiles, cmdParams)
+	}
+	if len(cmdParams) == 0 {
+		return fmt.Errorf("请输入需要执行的命令")
+	}
+	if writeCMDs[cmdParams[0]] && db.IsRedisReadOnly() {
+		return fmt.Errorf("当前配置%s为只读配置，禁止执行【%s】操作", conf.RedisConfName(), cmdParams[0])
+	}
+	if currentTx != nil { //事务进行中，写命令排队到commit时执行
+		switch {
+		case writeCMDs[cmdParams[0]]:
+			return queueTxCMD(cmdParams)
+		case cmdParams[0] == "watch":
+			return txWatchCMD(cmdParams)
+		case isTxBlocked(cmdParams):
+			return fmt.Errorf("事务进行中，不能执行【%s】，请先commit或discard", strings.Join(cmdParams, " "))
+		}
+	}
 	switch cmdParams[0] {
 	case "cls":
 		util.ClearConsoleScreen()
 		funcOptionMsg()
+		return nil
 	case "keys":
-		keysOptionCMD("模糊查询缓存Key的列表需要2~3个参数，请重新输入", cmdParams, keysCMD, keysIgnoreCaseCMD)
+		return keysOptionCMD("模糊查询缓存Key的列表需要2~3个参数，请重新输入", cmdParams, keysCMD, keysIgnoreCaseCMD)
 	case "get":
-		keysOptionCMD("模糊查询缓存Key的值需要2~2个参数，请重新输入", cmdParams, getCMD, getIgnoreCaseCMD)
+		return getOptionCMD(cmdParams)
 	case "del":
-		keysOptionCMD("模糊批量删除缓存需要2~3个参数，请重新输入", cmdParams, delCMD, delIgnoreCaseCMD)
+		return keysOptionCMD("模糊批量删除缓存需要2~3个参数，请重新输入", cmdParams, delCMD, delIgnoreCaseCMD)
 	case "set":
-		setCMD(cmdParams)
+		return setCMD(cmdParams)
+	case "edit":
+		return editCMD(cmdParams)
+	case "hset", "hdel", "lpush", "rpush", "lset", "lrem", "sadd", "srem", "zadd", "zrem", "zincrby", "incr", "decr", "incrbyfloat":
+		return structWriteCMD(cmdParams)
+	case "expire":
+		return expireCMD(cmdParams)
+	case "persist":
+		return persistCMD(cmdParams)
+	case "ttl":
+		return ttlCMD(cmdParams)
+	case "watch":
+		return watchCMD(cmdParams)
+	case "publish":
+		return publishCMD(cmdParams)
+	case "subscribe":
+		return subscribeCMD(cmdParams, false)
+	case "psubscribe":
+		return subscribeCMD(cmdParams, true)
+	case "pubsub":
+		return pubsubCMD(cmdParams)
+	case "stream":
+		return streamCMD(cmdParams)
+	case "grep":
+		return grepCMD(cmdParams)
+	case "rename":
+		return renameCMD(cmdParams)
+	case "diff":
+		return diffCMD(cmdParams)
+	case "sync":
+		return syncCMD(cmdParams)
+	case "browse":
+		return browseCMD(cmdParams)
+	case "analyze":
+		return analyzeCMD(cmdParams)
+	case "rdb":
+		return rdbCMD(cmdParams)
+	case "aof":
+		return aofCMD(cmdParams)
 	case "ldb":
-		loadDBCMD(cmdParams)
+		return loadDBCMD(cmdParams)
+	case "info":
+		return infoCMD(cmdParams)
+	case "top":
+		return topCMD(cmdParams)
+	case "slowlog":
+		return slowlogCMD(cmdParams)
+	case "latency":
+		return latencyCMD(cmdParams)
+	case "clients":
+		return clientsCMD(cmdParams)
+	case "config":
+		return configCMD(cmdParams)
 	case "resetconf":
-		resetConfCMD()
+		return resetConfCMD()
 	case "changeconf":
-		changeConfCMD()
+		return changeConfCMD()
 	case "addconf":
-		addConfCMD()
+		return addConfCMD()
 	case "changeoptdbid":
-		changeOptDbIdCMD(cmdParams)
+		return changeOptDbIdCMD(cmdParams)
 	case "quit":
 		os.Exit(1)
-	default:
-		log.Printf("您输入的操作【%s】不支持！请重新输入", cmdParams[0])
+	case "eval":
+		return evalCMD(cmdParams)
+	case "script":
+		return luaScriptCMD(cmdParams)
+	case "function":
+		return functionCMD(cmdParams)
+	case "begin":
+		return beginCMD(cmdParams)
+	case "commit":
+		return commitCMD(cmdParams)
+	case "discard":
+		return discardCMD(cmdParams)
+	case "run", "source":
+		return runScriptCMD(cmdParams)
 	}
+	return fmt.Errorf("您输入的操作【%s】不支持！请重新输入", cmdParams[0])
 }
 
 //处理输入的命令行参数
@@ -121,16 +333,73 @@ func dealCMDParams(cmdParams []string) []string {
 	return retCmdParams
 }
 
+//拆分命令行中的--参数，boolFlags中的参数不需要值
+func parseCMDFlags(cmdParams []string, boolFlags ...string) ([]string, map[string]string, error) {
+	args := []string{}
+	flags := map[string]string{}
+	for i := 0; i < len(cmdParams); i++ {
+		item := cmdParams[i]
+		if !strings.HasPrefix(item, "--") || len(item) == 2 {
+			args = append(args, item)
+			continue
+		}
+		name := strings.TrimPrefix(item, "--")
+		if isBoolFlag(name, boolFlags) {
+			flags[name] = ""
+			continue
+		}
+		if i+1 >= len(cmdParams) {
+			return nil, nil, fmt.Errorf("参数--%s缺少值", name)
+		}
+		i++
+		flags[name] = cmdParams[i]
+	}
+	return args, flags, nil
+}
+
+//取出可以加在任意命令后的全局参数，例如--conf、--profiles，命令自身的--参数原样保留
+func takeGlobalFlag(cmdParams []string, name string) ([]string, string, bool, error) {
+	for i, item := range cmdParams {
+		if item != "--"+name {
+			continue
+		}
+		if i+1 >= len(cmdParams) {
+			return nil, "", false, fmt.Errorf("参数--%s缺少值", name)
+		}
+		args := append(append([]string{}, cmdParams[:i]...), cmdParams[i+2:]...)
+		return args, cmdParams[i+1], true, nil
+	}
+	return cmdParams, "", false, nil
+}
+
+func isBoolFlag(name string, boolFlags []string) bool {
+	for _, item := range boolFlags {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+//检查是否包含不支持的--参数
+func checkCMDFlags(flags map[string]string, allowFlags ...string) error {
+	for name := range flags {
+		if !isBoolFlag(name, allowFlags) {
+			return fmt.Errorf("不支持的参数--%s", name)
+		}
+	}
+	return nil
+}
+
 //检查命令行参数个数是否符合规则
 func checkCMDParamsCount(cmdParams []string, count int) bool {
 	return count <= len(cmdParams)
 }
 
 //加载数据库列表信息
-func loadDBCMD(cmdParams []string) {
+func loadDBCMD(cmdParams []string) error {
 	if !checkCMDParamsCount(cmdParams, 2) {
-		log.Println("加载数据库列表信息至少需要两个参数，请重新输入")
-		return
+		return fmt.Errorf("加载数据库列表信息至少需要两个参数，请重新输入")
 	}
 	opt := cmdParams[1]
 	loadDbCount := db.RedisDBCount()
@@ -140,19 +409,16 @@ func loadDBCMD(cmdParams []string) {
 		log.Println("正在加载全部数据库信息，请稍候...")
 	case "n":
 		if !checkCMDParamsCount(cmdParams, 3) {
-			log.Println("请输入需要加载前前多少个数据库的信息")
-			return
+			return fmt.Errorf("请输入需要加载前前多少个数据库的信息")
 		}
 		count, err := strconv.Atoi(cmdParams[2])
 		if err != nil || count <= 0 {
-			log.Println("您的输入的数量无法解析，请重来")
-			return
+			return fmt.Errorf("您的输入的数量无法解析，请重来")
 		}
 		loadDbCount = count
 		isLoadAll = false
 	default:
-		log.Println("加载方式仅允许输入y/n")
-		return
+		return fmt.Errorf("加载方式仅允许输入y/n")
 	}
 
 	dbInfoChan := make(chan model.RedisDBInfo, loadDbCount)
@@ -176,52 +442,65 @@ func loadDBCMD(cmdParams []string) {
 			log.Printf("db(%d)=%d\r\n", i, keysCount)
 		}
 	}
+	return nil
+}
+
+func keysOptionCMD(paramErrMsg string, cmdParams []string, cmdFunc cmdParamfunc, ignoreCaseCmdFunc cmdParamfunc) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "no-ttl")
+	if err == nil {
+		err = checkCMDFlags(flags, keyFilterFlags...)
+	}
+	if err != nil {
+		return err
+	}
+	return keysFilterOptionCMD(paramErrMsg, cmdParams, flags, cmdFunc, ignoreCaseCmdFunc)
 }
 
-func keysOptionCMD(paramErrMsg string, cmdParams []string, cmdFunc cmdParamfunc, ignoreCaseCmdFunc cmdParamfunc) {
+//按已解析的--参数过滤key后执行命令
+func keysFilterOptionCMD(paramErrMsg string, cmdParams []string, flags map[string]string, cmdFunc cmdParamfunc, ignoreCaseCmdFunc cmdParamfunc) error {
+	filter, err := parseKeyFilter(flags)
+	if err != nil {
+		return err
+	}
 	if !checkCMDParamsCount(cmdParams, 2) && !checkCMDParamsCount(cmdParams, 3) {
-		log.Println(paramErrMsg)
-		return
+		return fmt.Errorf("%s", paramErrMsg)
 	}
 	if len(cmdParams) == 3 && cmdParams[1] == "y" {
-		ignoreCaseCmdFunc(append(cmdParams[0:1], cmdParams[2]))
-	} else if len(cmdParams) == 2 && cmdParams[1] == "n" {
-		cmdFunc(append(cmdParams[0:1], cmdParams[2]))
+		return ignoreCaseCmdFunc(append(cmdParams[0:1], cmdParams[2]), filter)
+	} else if len(cmdParams) == 3 && cmdParams[1] == "n" {
+		return cmdFunc(append(cmdParams[0:1], cmdParams[2]), filter)
 	} else if len(cmdParams) == 2 {
-		cmdFunc(cmdParams)
-	} else {
-		log.Println("参数不符合规则")
+		return cmdFunc(cmdParams, filter)
 	}
+	return fmt.Errorf("参数不符合规则")
 }
 
 //加载缓存key
-func keysCMD(cmdParams []string) {
+func keysCMD(cmdParams []string, filter *model.KeyFilter) error {
 	if !checkCMDParamsCount(cmdParams, 2) {
-		log.Println("模糊查询缓存Key的列表需要2个参数，请重新输入")
-		return
+		return fmt.Errorf("模糊查询缓存Key的列表需要2个参数，请重新输入")
 	}
-	keys := db.SearchRedisKeys(cmdParams[1])
+	keys := db.SearchRedisKeys(cmdParams[1], filter)
 	for _, key := range keys {
 		log.Println(key)
 	}
-
+	return nil
 }
 
 //不区分大小写加载缓存key
-func keysIgnoreCaseCMD(cmdParams []string) {
+func keysIgnoreCaseCMD(cmdParams []string, filter *model.KeyFilter) error {
 	if !checkCMDParamsCount(cmdParams, 2) {
-		log.Println("模糊查询缓存Key的列表需要两个参数，请重新输入")
-		return
+		return fmt.Errorf("模糊查询缓存Key的列表需要两个参数，请重新输入")
 	}
 	keysChan := make(chan string, 1000)
-	go db.SearchRedisKeysIgnoreCase(cmdParams[1], keysChan) //查询redis缓存key
+	go db.SearchRedisKeysIgnoreCase(cmdParams[1], filter, keysChan) //查询redis缓存key
 	for {
 		select {
 		case key, ok := <-keysChan:
 			{
 				if !ok {
 					log.Println("查询结束")
-					return //方法结束
+					return nil //方法结束
 				}
 				key = strings.ReplaceAll(key, " ", "")
 				if key != "" {
@@ -237,38 +516,69 @@ func keysIgnoreCaseCMD(cmdParams []string) {
 	}
 }
 
+//解析值的解码参数后查询模糊key的值
+func getOptionCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "no-ttl")
+	if err == nil {
+		err = checkCMDFlags(flags, append(append(keyFilterFlags, valueCodecFlags...), "out", "max-size")...)
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := flags["out"]; ok {
+		return getOutCMD(cmdParams, flags) //将值的原始字节写入文件
+	}
+	if _, ok := flags["max-size"]; ok {
+		return fmt.Errorf("--max-size需要与--out一起使用")
+	}
+	decode, err := parseValueDecoder(flags)
+	if err != nil {
+		return err
+	}
+	return keysFilterOptionCMD("模糊查询缓存Key的值需要2~2个参数，请重新输入", cmdParams, flags, func(cmdParams []string, filter *model.KeyFilter) error {
+		return getCMD(cmdParams, filter, decode)
+	}, func(cmdParams []string, filter *model.KeyFilter) error {
+		return getIgnoreCaseCMD(cmdParams, filter, decode)
+	})
+}
+
 //区分大小写的方式获取模糊key的值
-func getCMD(cmdParams []string) {
+func getCMD(cmdParams []string, filter *model.KeyFilter, decode func(value string) string) error {
 	if !checkCMDParamsCount(cmdParams, 2) {
-		log.Println("模糊查询缓存Key的值需要2个参数，请重新输入")
-		return
+		return fmt.Errorf("模糊查询缓存Key的值需要2个参数，请重新输入")
 	}
-	keys := db.SearchRedisKeys(cmdParams[1])
+	keys := db.SearchRedisKeys(cmdParams[1], filter)
 	var wg sync.WaitGroup
+	var failed int32
 	for _, key := range keys {
 		wg.Add(1)
 		go func(itemKey string, waitG *sync.WaitGroup) {
 			defer waitG.Done()
 			value, err := db.GetRedisValue(itemKey)
 			if err != nil {
+				atomic.AddInt32(&failed, 1)
 				log.Println(fmt.Sprintf("%s=%s", itemKey, err.Error()))
 				return
 			}
-			log.Println(fmt.Sprintf("%s=%s", itemKey, value))
+			log.Println(fmt.Sprintf("%s=%s", itemKey, decode(value)))
 		}(key, &wg)
 	}
 	wg.Wait()
+	if failed > 0 {
+		return fmt.Errorf("%d个key的值查询失败", failed)
+	}
+	return nil
 }
 
 //不区分大小写获取指定key的值
-func getIgnoreCaseCMD(cmdParams []string) {
+func getIgnoreCaseCMD(cmdParams []string, filter *model.KeyFilter, decode func(value string) string) error {
 	if !checkCMDParamsCount(cmdParams, 2) {
-		log.Println("模糊查询缓存Key的值需要两个参数，请重新输入")
-		return
+		return fmt.Errorf("模糊查询缓存Key的值需要两个参数，请重新输入")
 	}
 	keysChan := make(chan string, 1000)
-	go db.SearchRedisKeysIgnoreCase(cmdParams[1], keysChan) //查询redis缓存key
+	go db.SearchRedisKeysIgnoreCase(cmdParams[1], filter, keysChan) //查询redis缓存key
 	var wg sync.WaitGroup
+	var failed int32
 	for {
 		select {
 		case key, ok := <-keysChan:
@@ -276,17 +586,21 @@ func getIgnoreCaseCMD(cmdParams []string) {
 				if !ok {
 					wg.Wait()
 					log.Println("查询结束")
-					return //方法结束
+					if failed > 0 {
+						return fmt.Errorf("%d个key的值查询失败", failed)
+					}
+					return nil //方法结束
 				}
 				wg.Add(1)
 				go func(itemKey string, waitG *sync.WaitGroup) {
 					defer waitG.Done()
 					value, err := db.GetRedisValue(itemKey)
 					if err != nil {
+						atomic.AddInt32(&failed, 1)
 						log.Println(fmt.Sprintf("%s=%s", itemKey, err.Error()))
 						return
 					}
-					log.Println(fmt.Sprintf("%s=%s", itemKey, value))
+					log.Println(fmt.Sprintf("%s=%s", itemKey, decode(value)))
 				}(key, &wg)
 			}
 		default:
@@ -298,58 +612,70 @@ func getIgnoreCaseCMD(cmdParams []string) {
 	}
 }
 
+//清空当前数据库，需要确认
+func flushDBCMD(pattern string) error {
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("根据您输入的模糊Key=%s此次操作将清空数据库dbid=%d中的所有缓存！请确认是否执行此操作(y/n):", pattern, db.RedisDBCount()), false)
+	if isSure != "y" {
+		return nil
+	}
+	log.Println("正在处理，请稍候...")
+	return db.FlushRedisDB() //清空数据库
+}
+
+//删除一个key并输出耗时，失败时计数
+func deleteKey(key string, failed *int32) {
+	start := time.Now()
+	if err := db.DeleteRedisKey(key); err != nil {
+		atomic.AddInt32(failed, 1)
+		log.Printf("%s 删除失败：%s", key, err.Error())
+		return
+	}
+	log.Println(fmt.Sprintf("%s 删除成功，耗时%d毫秒", key, time.Since(start).Milliseconds()))
+}
+
 //区分大小写的方式模糊删除key的值
-func delCMD(cmdParams []string) {
+func delCMD(cmdParams []string, filter *model.KeyFilter) error {
 	if !checkCMDParamsCount(cmdParams, 2) {
-		log.Println("模糊批量删除缓存需要2个参数，请重新输入")
-		return
+		return fmt.Errorf("模糊批量删除缓存需要2个参数，请重新输入")
 	}
 	pattern := cmdParams[1]
-	if pattern == "*" {
-		isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("根据您输入的模糊Key=%s此次操作将清空数据库dbid=%d中的所有缓存！请确认是否执行此操作(y/n):", pattern, db.RedisDBCount()), false)
-		if isSure == "y" {
-			log.Println("正在处理，请稍候...")
-			db.FlushRedisDB() //清空数据库
-		}
-		return
+	if pattern == "*" && filter.IsEmpty() {
+		return flushDBCMD(pattern)
 	}
 
-	keys := db.SearchRedisKeys(cmdParams[1])
+	keys := db.SearchRedisKeys(cmdParams[1], filter)
 	var wg sync.WaitGroup
+	var failed int32
 	delKeysCount := 0
 	for _, key := range keys {
 		delKeysCount++
 		wg.Add(1)
 		go func(itemKey string, waitG *sync.WaitGroup) {
 			defer waitG.Done()
-			start := time.Now()
-			db.DeleteRedisKey(itemKey)
-			log.Println(fmt.Sprintf("%s 删除成功，耗时%d毫秒", itemKey, time.Since(start).Milliseconds()))
+			deleteKey(itemKey, &failed)
 		}(key, &wg)
 	}
 	wg.Wait()
-	log.Printf("共删除%d个缓存", delKeysCount)
-
+	log.Printf("共删除%d个缓存", delKeysCount-int(failed))
+	if failed > 0 {
+		return fmt.Errorf("%d个key删除失败", failed)
+	}
+	return nil
 }
 
 //不区分大小写删除模糊key的值
-func delIgnoreCaseCMD(cmdParams []string) {
+func delIgnoreCaseCMD(cmdParams []string, filter *model.KeyFilter) error {
 	if !checkCMDParamsCount(cmdParams, 2) {
-		log.Println("模糊批量删除缓存需要两个参数，请重新输入")
-		return
+		return fmt.Errorf("模糊批量删除缓存需要两个参数，请重新输入")
 	}
 	pattern := cmdParams[1]
-	if pattern == "*" {
-		isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("根据您输入的模糊Key=%s此次操作将清空数据库dbid=%d中的所有缓存！请确认是否执行此操作(y/n):", pattern, db.RedisDBCount()), false)
-		if isSure == "y" {
-			log.Println("正在处理，请稍候...")
-			db.FlushRedisDB() //清空数据库
-		}
-		return
+	if pattern == "*" && filter.IsEmpty() {
+		return flushDBCMD(pattern)
 	}
 	keysChan := make(chan string, 1000)
-	go db.SearchRedisKeysIgnoreCase(cmdParams[1], keysChan) //查询redis缓存key
+	go db.SearchRedisKeysIgnoreCase(cmdParams[1], filter, keysChan) //查询redis缓存key
 	var wg sync.WaitGroup
+	var failed int32
 	delKeysCount := 0
 
 	for {
@@ -358,16 +684,17 @@ func delIgnoreCaseCMD(cmdParams []string) {
 			{
 				if !ok {
 					wg.Wait()
-					log.Printf("共删除%d个缓存", delKeysCount)
-					return //方法结束
+					log.Printf("共删除%d个缓存", delKeysCount-int(failed))
+					if failed > 0 {
+						return fmt.Errorf("%d个key删除失败", failed)
+					}
+					return nil //方法结束
 				}
 				delKeysCount++
 				wg.Add(1)
 				go func(itemKey string, waitG *sync.WaitGroup) {
 					defer waitG.Done()
-					start := time.Now()
-					db.DeleteRedisKey(itemKey)
-					log.Println(fmt.Sprintf("%s 删除成功，耗时%d毫秒", itemKey, time.Since(start).Milliseconds()))
+					deleteKey(itemKey, &failed)
 				}(key, &wg)
 			}
 		default:
@@ -379,51 +706,123 @@ func delIgnoreCaseCMD(cmdParams []string) {
 	}
 }
 
-func setCMD(cmdParams []string) {
+func setCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "nx", "xx", "keepttl")
+	if err == nil {
+		err = checkCMDFlags(flags, "ex", "px", "nx", "xx", "keepttl", "encode", "max-size")
+	}
+	if err != nil {
+		return err
+	}
 	if !checkCMDParamsCount(cmdParams, 3) {
-		log.Println("给指定key设置值需要三个参数，请重新输入")
-		return
+		return fmt.Errorf("给指定key设置值需要三个参数，请重新输入")
 	}
 	key := cmdParams[1]
-	value := cmdParams[2]
-	db.SetRedisValue(key, value)
+	value, fromFile, err := readSetValue(flags, cmdParams[2])
+	if err != nil {
+		return err
+	}
+	options, err := setOptions(flags)
+	if err != nil {
+		return err
+	}
+	_, nx := flags["nx"]
+	_, xx := flags["xx"]
+	if fromFile && !nx && !xx {
+		if exists, _ := db.ExistsRedisKey(key); exists {
+			isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("%s已存在，请确认是否用文件%s的内容覆盖(y/n):", key, cmdParams[2][1:]), false)
+			if isSure != "y" {
+				return nil
+			}
+		}
+	}
+	ok, err := db.SetRedisValue(key, value, options...)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		log.Printf("%s 不满足nx/xx条件，未设置值", key)
+		return nil
+	}
+	if fromFile {
+		log.Printf("%s已写入%s", key, util.FormatByteSize(int64(len(value))))
+	}
+	return nil
+}
+
+//将set命令的--参数转换为redis的set参数
+func setOptions(flags map[string]string) ([]interface{}, error) {
+	options := []interface{}{}
+	_, hasEx := flags["ex"]
+	_, hasPx := flags["px"]
+	_, keepTTL := flags["keepttl"]
+	if (hasEx && hasPx) || ((hasEx || hasPx) && keepTTL) {
+		return nil, fmt.Errorf("--ex、--px、--keepttl只能使用其中一个")
+	}
+	if hasEx {
+		ttl, err := util.ParseDuration(flags["ex"])
+		if err != nil || ttl < time.Second {
+			return nil, fmt.Errorf("无法解析--ex的过期时间")
+		}
+		options = append(options, "ex", int64(ttl.Seconds()))
+	}
+	if hasPx {
+		ttl, err := strconv.ParseInt(flags["px"], 10, 64)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("无法解析--px的过期毫秒数")
+		}
+		options = append(options, "px", ttl)
+	}
+	if keepTTL {
+		options = append(options, "keepttl")
+	}
+	_, nx := flags["nx"]
+	_, xx := flags["xx"]
+	if nx && xx {
+		return nil, fmt.Errorf("--nx和--xx不能同时使用")
+	}
+	if nx {
+		options = append(options, "nx")
+	}
+	if xx {
+		options = append(options, "xx")
+	}
+	return options, nil
 }
 
 //重新配置当前设置当前配置文件的内容
-func resetConfCMD() {
-	initErr := conf.InitRedisConf() //重新配置当前redis连接信息
-	if initErr != nil {
-		log.Println(initErr)
-		return
+func resetConfCMD() error {
+	if err := conf.InitRedisConf(); err != nil { //重新配置当前redis连接信息
+		return err
 	}
-	checkErr := conf.CheckRedisConf()
-	if checkErr != nil {
-		log.Println(checkErr)
-		return
+	if err := conf.CheckRedisConf(); err != nil {
+		return err
 	}
 	db.InitRedisInfo(false) //因为重新配置了redis的连接信息，所以需要重新初始化redis连接信息
+	return nil
 }
 
 //切换配置文件
-func changeConfCMD() {
+func changeConfCMD() error {
 	db.InitRedisInfo(true)
+	return nil
 }
 
 //添加配置文件
-func addConfCMD() {
+func addConfCMD() error {
 	conf.CreateRedisConfFile()
+	return nil
 }
 
 //切换操作数据
-func changeOptDbIdCMD(cmdParams []string) {
+func changeOptDbIdCMD(cmdParams []string) error {
 	if !checkCMDParamsCount(cmdParams, 2) {
-		log.Println("切换操作数据库需要两个参数，请重新输入")
-		return
+		return fmt.Errorf("切换操作数据库需要两个参数，请重新输入")
 	}
 	dbid, err := strconv.Atoi(cmdParams[1])
 	if err != nil || dbid <= 0 {
-		log.Println("无法解析您输入的数据库编号")
-		return
+		return fmt.Errorf("无法解析您输入的数据库编号")
 	}
 	db.ChangeRedisOptionDBId(dbid)
+	return nil
 }
@@ -0,0 +1,221 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const renamePreviewCount = 50 //重命名预览时最多展示的数量
+
+//重命名预览的行
+type renameRow struct {
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	Status string `json:"status"`
+}
+
+//提取以^锚定的正则表达式开头的字面量部分，用于缩小查询key的范围，未锚定或包含顶层的|时返回空，需要查询全部key
+func regexLiteralPrefix(expr string) string {
+	if !strings.HasPrefix(expr, "^") || hasTopLevelAlternation(expr) {
+		return ""
+	}
+	expr = expr[1:]
+	prefix := []byte{}
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if strings.IndexByte(`\.+*?()|[]{}^$`, c) >= 0 {
+			if strings.IndexByte("*?{", c) >= 0 && len(prefix) > 0 { //量词作用于前一个字符
+				prefix = prefix[:len(prefix)-1]
+			}
+			break
+		}
+		prefix = append(prefix, c)
+	}
+	return string(prefix)
+}
+
+//正则表达式是否包含不在括号和字符集中的|，例如^a|b中的b不以a开头
+func hasTopLevelAlternation(expr string) bool {
+	depth := 0
+	inClass := false
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\\':
+			i++ //跳过转义的字符
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+			if i+1 < len(expr) && expr[i+1] == '^' {
+				i++
+			}
+			if i+1 < len(expr) && expr[i+1] == ']' { //字符集开头的]是字面量
+				i++
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			return true
+		}
+	}
+	return false
+}
+
+//按正则表达式批量重命名key
+func renameCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "copy", "force", "no-ttl")
+	if err == nil {
+		err = checkCMDFlags(flags, append(keyFilterFlags, "copy", "force", "to-db")...)
+	}
+	if err != nil {
+		return err
+	}
+	filter, err := parseKeyFilter(flags)
+	if err != nil {
+		return err
+	}
+	ignoreCase, args := splitKeysMode(cmdParams[1:])
+	if len(args) != 2 {
+		return fmt.Errorf("批量重命名需要正则表达式和替换内容两个参数，请重新输入")
+	}
+	expr := args[0]
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	keyReg, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("正则表达式错误：%s", err.Error())
+	}
+	_, copyMode := flags["copy"]
+	_, force := flags["force"]
+	opts := db.RenameOptions{Copy: copyMode, Force: force, ToDB: -1}
+	if str, ok := flags["to-db"]; ok {
+		toDB, err := strconv.Atoi(str)
+		if err != nil || toDB < 0 || toDB >= db.RedisDBCount() {
+			return fmt.Errorf("目标数据库编号需要在[0~%d)之间", db.RedisDBCount())
+		}
+		if toDB != db.RedisOptionDBId() {
+			opts.ToDB = toDB
+		}
+	}
+
+	pairs := []model.KV{}
+	for key := range searchKeysChan(ignoreCase, regexLiteralPrefix(args[0])+"*", filter) {
+		if !keyReg.MatchString(key) {
+			continue
+		}
+		newKey := keyReg.ReplaceAllString(key, args[1]) //替换key中所有匹配的部分
+		if newKey == key && opts.ToDB < 0 {
+			continue
+		}
+		pairs = append(pairs, model.KV{Key: key, Value: newKey})
+	}
+	if len(pairs) == 0 {
+		log.Println("没有需要重命名的key")
+		return nil
+	}
+	rows, err := checkRenameConflicts(pairs, opts)
+	if err != nil {
+		return err
+	}
+	executable := []model.KV{}
+	for i, row := range rows {
+		if row.Status == "" || row.Status == "覆盖" {
+			executable = append(executable, pairs[i])
+		}
+	}
+	if len(rows) > renamePreviewCount {
+		printRows(rows[:renamePreviewCount], false)
+		log.Printf("仅展示前%d个", renamePreviewCount)
+	} else {
+		printRows(rows, false)
+	}
+	action := "重命名"
+	if copyMode {
+		action = "复制"
+	}
+	if opts.ToDB >= 0 {
+		action += fmt.Sprintf("到db(%d)", opts.ToDB)
+	}
+	log.Printf("共匹配%d个key，可%s%d个，冲突%d个（冲突的key将跳过）", len(pairs), action, len(executable), len(pairs)-len(executable))
+	if len(executable) == 0 {
+		return nil
+	}
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("请确认是否%s以上%d个key(y/n):", action, len(executable)), false)
+	if isSure != "y" {
+		return nil
+	}
+	succeed := 0
+	for i := 0; i < len(executable); i += keysBatchCount {
+		end := i + keysBatchCount
+		if end > len(executable) {
+			end = len(executable)
+		}
+		batch := executable[i:end]
+		for j, err := range db.RenameRedisKeys(batch, opts) {
+			if err != nil {
+				log.Printf("%s -> %s 失败：%s", batch[j].Key, batch[j].Value, err.Error())
+				continue
+			}
+			succeed++
+		}
+	}
+	log.Printf("成功%s%d个key", action, succeed)
+	if succeed < len(executable) {
+		return fmt.Errorf("%d个key%s失败", len(executable)-succeed, action)
+	}
+	return nil
+}
+
+//检查重命名冲突：多个key重命名为同一个key、目标key也在重命名列表中、目标key已存在
+func checkRenameConflicts(pairs []model.KV, opts db.RenameOptions) ([]renameRow, error) {
+	targets := map[string]int{}
+	sources := map[string]bool{}
+	for _, pair := range pairs {
+		targets[pair.Value]++
+		sources[pair.Key] = true
+	}
+	rows := make([]renameRow, len(pairs))
+	newKeys := make([]string, len(pairs))
+	for i, pair := range pairs {
+		rows[i] = renameRow{Old: pair.Key, New: pair.Value}
+		newKeys[i] = pair.Value
+		switch {
+		case targets[pair.Value] > 1:
+			rows[i].Status = "冲突：多个key重命名为同一个key"
+		case pair.Value != pair.Key && sources[pair.Value] && opts.ToDB < 0:
+			rows[i].Status = "冲突：目标key也在重命名列表中"
+		}
+	}
+	for i := 0; i < len(newKeys); i += keysBatchCount {
+		end := i + keysBatchCount
+		if end > len(newKeys) {
+			end = len(newKeys)
+		}
+		exists, err := db.ExistsRedisKeys(newKeys[i:end], opts.ToDB)
+		if err != nil {
+			return nil, err
+		}
+		for j, exist := range exists {
+			if !exist || rows[i+j].Status != "" {
+				continue
+			}
+			if opts.Force {
+				rows[i+j].Status = "覆盖"
+			} else {
+				rows[i+j].Status = "冲突：目标key已存在"
+			}
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,54 @@
+package command
+
+import "testing"
+
+func TestRegexLiteralPrefix(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"^user:", "user:"},
+		{"^user:\\d+", "user:"},
+		{"^user:.*", "user:"},
+		{"^ab*", "a"},
+		{"^ab?", "a"},
+		{"^ab{2}", "a"},
+		{"^ab+", "ab"},
+		{"^a(b|c)", "a"},
+		{"^a[|]", "a"},
+		{"^a$", "a"},
+		{"^*", ""},
+		{"^", ""},
+		{"user:", ""},
+		{"^a|b", ""},
+		{"^(a)|b", ""},
+	}
+	for _, tt := range tests {
+		if got := regexLiteralPrefix(tt.expr); got != tt.want {
+			t.Errorf("regexLiteralPrefix(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestHasTopLevelAlternation(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"a|b", true},
+		{"(a)|b", true},
+		{"(a|b)", false},
+		{"((a|b)c)", false},
+		{"[|]", false},
+		{"[]|]", false},
+		{"[^]|]", false},
+		{"a\\|b", false},
+		{"[a]|b", true},
+		{"ab", false},
+	}
+	for _, tt := range tests {
+		if got := hasTopLevelAlternation(tt.expr); got != tt.want {
+			t.Errorf("hasTopLevelAlternation(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"rediscmd/src/util"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//脚本中引用变量的格式，例如${name}
+var scriptVarReg = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+//脚本中不支持执行的命令
+var scriptExcludeCMDs = map[string]bool{"cls": true, "quit": true, "browse": true, "edit": true, "resetconf": true, "changeconf": true, "addconf": true, "run": true, "source": true}
+
+//脚本每行的执行结果
+type scriptLineRow struct {
+	Line     int    `json:"line"`
+	Command  string `json:"command"`
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+}
+
+//逐行执行脚本文件中的命令 run|source [file]
+func runScriptCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "yes")
+	if err == nil {
+		err = checkCMDFlags(flags, "vars", "on-error", "yes")
+	}
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) != 2 {
+		return fmt.Errorf("%s需要脚本文件参数，请重新输入", cmdParams[0])
+	}
+	vars := map[string]string{}
+	if str, ok := flags["vars"]; ok {
+		for _, item := range strings.Split(str, ",") {
+			index := strings.Index(item, "=")
+			if index <= 0 {
+				return fmt.Errorf("无法解析变量%s，格式应为名称=值", item)
+			}
+			vars[strings.TrimSpace(item[:index])] = item[index+1:]
+		}
+	}
+	stopOnError := true
+	if mode, ok := flags["on-error"]; ok {
+		if stopOnError, err = parseOnErrorMode(mode); err != nil {
+			return err
+		}
+	}
+	content, err := util.ReadFileAsString(cmdParams[1])
+	if err != nil {
+		return fmt.Errorf("读取脚本文件%s失败：%s", cmdParams[1], err.Error())
+	}
+	if _, ok := flags["yes"]; ok {
+		util.SetAutoConfirm(true)
+		defer util.SetAutoConfirm(false)
+	}
+
+	log.Printf("开始执行脚本%s", cmdParams[1])
+	inTx := currentTx != nil //执行脚本前是否已开始事务
+	rows := []scriptLineRow{}
+	failed := false
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		row := scriptLineRow{Line: i + 1, Command: util.Truncate(line, 60), Status: "成功"}
+		start := time.Now()
+		if ok := runScriptLine(line, vars, &stopOnError); !ok {
+			row.Status = "失败"
+			failed = true
+		}
+		row.Duration = time.Since(start).Truncate(time.Millisecond).String()
+		rows = append(rows, row)
+		if row.Status == "失败" && stopOnError {
+			log.Printf("第%d行执行失败，停止执行脚本", row.Line)
+			break
+		}
+	}
+	if currentTx != nil && !inTx {
+		log.Println("脚本中开始的事务未提交，自动放弃")
+		discardCMD(nil)
+		failed = true
+	}
+	if len(rows) == 0 {
+		log.Printf("脚本%s中没有需要执行的命令", cmdParams[1])
+		return nil
+	}
+	printRows(rows, false)
+	if failed {
+		return fmt.Errorf("脚本%s执行完成，部分命令执行失败", cmdParams[1])
+	}
+	log.Printf("脚本%s执行完成，共执行%d条命令", cmdParams[1], len(rows))
+	return nil
+}
+
+//执行脚本中的一行，包括变量定义和on-error指令，执行失败时返回false
+func runScriptLine(line string, vars map[string]string, stopOnError *bool) (ok bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("执行出错：%v", err)
+			ok = false
+		}
+	}()
+	line, err := expandScriptVars(line, vars)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	cmdParams := dealCMDParams(strings.Split(line, " "))
+	if len(cmdParams) == 0 {
+		return true //变量替换后为空行
+	}
+	log.Printf(">> %s", strings.Join(cmdParams, " "))
+	switch cmdParams[0] {
+	case "var":
+		if len(cmdParams) < 3 {
+			log.Println("定义变量需要名称和值两个参数，例如var name value")
+			return false
+		}
+		if !scriptVarReg.MatchString("${" + cmdParams[1] + "}") {
+			log.Printf("变量名称%s只能包含字母、数字和下划线", cmdParams[1])
+			return false
+		}
+		vars[cmdParams[1]] = strings.Join(cmdParams[2:], " ")
+		return true
+	case "on-error":
+		if len(cmdParams) != 2 {
+			log.Println("on-error需要stop或continue参数")
+			return false
+		}
+		if *stopOnError, err = parseOnErrorMode(cmdParams[1]); err != nil {
+			log.Println(err)
+			return false
+		}
+		return true
+	}
+	if scriptExcludeCMDs[cmdParams[0]] {
+		log.Printf("脚本中不支持执行【%s】", cmdParams[0])
+		return false
+	}
+	return execCMD(cmdParams) == nil
+}
+
+//替换行中引用的变量，脚本中未定义时使用同名环境变量
+func expandScriptVars(line string, vars map[string]string) (string, error) {
+	var err error
+	line = scriptVarReg.ReplaceAllStringFunc(line, func(ref string) string {
+		name := scriptVarReg.FindStringSubmatch(ref)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		err = fmt.Errorf("变量%s未定义", name)
+		return ref
+	})
+	return line, err
+}
+
+//解析出错后的处理方式，stop时返回true
+func parseOnErrorMode(mode string) (bool, error) {
+	switch mode {
+	case "stop":
+		return true, nil
+	case "continue":
+		return false, nil
+	}
+	return false, fmt.Errorf("不支持的出错处理方式%s，仅支持stop|continue", mode)
+}
@@ -0,0 +1,228 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"rediscmd/src/conf"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	slowlogDefaultCount = 128 //默认获取的慢查询日志数量，与slowlog-max-len的默认值相同
+	slowlogArgsWidth    = 80  //表格中命令参数最多显示的字符数
+)
+
+//慢查询日志的行
+type slowlogRow struct {
+	ID       int64  `json:"id"`
+	Time     string `json:"time"`
+	Duration string `json:"duration"`
+	Client   string `json:"client"`
+	Name     string `json:"name"`
+	Command  string `json:"command"`
+}
+
+//按命令汇总慢查询的行
+type slowlogGroupRow struct {
+	Command string `json:"command"`
+	Count   int64  `json:"count"`
+	Total   string `json:"total"`
+	Avg     string `json:"avg"`
+	Max     string `json:"max"`
+}
+
+//延迟事件的行
+type latencyRow struct {
+	Event  string `json:"event"`
+	Time   string `json:"time"`
+	Latest string `json:"latest"`
+	Max    string `json:"max"`
+}
+
+//延迟历史的行
+type latencyHistoryRow struct {
+	Time    string `json:"time"`
+	Latency string `json:"latency"`
+}
+
+func formatUnixTime(sec int64) string {
+	return time.Unix(sec, 0).Format("2006-01-02 15:04:05")
+}
+
+func formatMicroseconds(us int64) string {
+	return (time.Duration(us) * time.Microsecond).String()
+}
+
+//查看慢查询日志 slowlog [n|all] [--sort time|duration] [--group] [--reset]
+func slowlogCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "group", "reset", "json")
+	if err == nil {
+		err = checkCMDFlags(flags, "sort", "group", "reset", "json")
+	}
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) > 2 {
+		return fmt.Errorf("slowlog最多需要一个数量参数，请重新输入")
+	}
+	if _, ok := flags["reset"]; ok {
+		if db.IsRedisReadOnly() {
+			return fmt.Errorf("当前配置%s为只读配置，禁止清空慢查询日志", conf.RedisConfName())
+		}
+		isSure, _ := util.ReadValueFromConsole("请确认是否清空慢查询日志(y/n):", false)
+		if isSure != "y" {
+			return nil
+		}
+		if err := db.ResetRedisSlowlog(); err != nil {
+			return err
+		}
+		log.Println("慢查询日志已清空")
+		return nil
+	}
+	count := slowlogDefaultCount
+	if len(cmdParams) == 2 {
+		if cmdParams[1] == "all" {
+			count = -1
+		} else if count, err = strconv.Atoi(cmdParams[1]); err != nil || count <= 0 {
+			return fmt.Errorf("数量必须是正整数或all")
+		}
+	}
+	sortBy := flags["sort"]
+	if sortBy != "" && sortBy != "time" && sortBy != "duration" {
+		return fmt.Errorf("参数--sort仅支持time|duration")
+	}
+	_, asJSON := flags["json"]
+	entries, err := db.GetRedisSlowlog(count)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		log.Println("没有慢查询日志")
+		return nil
+	}
+	if _, ok := flags["group"]; ok {
+		printRows(slowlogGroupRows(entries), asJSON)
+		return nil
+	}
+	if sortBy == "duration" {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Duration > entries[j].Duration })
+	}
+	rows := make([]slowlogRow, 0, len(entries))
+	for _, entry := range entries {
+		command := util.FormatCommand(entry.Args)
+		if !asJSON {
+			command = util.Truncate(command, slowlogArgsWidth)
+		}
+		rows = append(rows, slowlogRow{ID: entry.ID, Time: formatUnixTime(entry.Time), Duration: formatMicroseconds(entry.Duration),
+			Client: entry.Client, Name: entry.ClientName, Command: command})
+	}
+	printRows(rows, asJSON)
+	return nil
+}
+
+//按命令名称汇总慢查询，按总耗时倒序排列
+func slowlogGroupRows(entries []model.SlowlogEntry) []slowlogGroupRow {
+	type group struct {
+		command    string
+		count      int64
+		total, max int64
+	}
+	groups := map[string]*group{}
+	for _, entry := range entries {
+		name := ""
+		if len(entry.Args) > 0 {
+			name = strings.ToLower(entry.Args[0])
+		}
+		item, ok := groups[name]
+		if !ok {
+			item = &group{command: name}
+			groups[name] = item
+		}
+		item.count++
+		item.total += entry.Duration
+		if entry.Duration > item.max {
+			item.max = entry.Duration
+		}
+	}
+	items := make([]*group, 0, len(groups))
+	for _, item := range groups {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].total != items[j].total {
+			return items[i].total > items[j].total
+		}
+		return items[i].command < items[j].command
+	})
+	rows := make([]slowlogGroupRow, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, slowlogGroupRow{Command: item.command, Count: item.count, Total: formatMicroseconds(item.total),
+			Avg: formatMicroseconds(item.total / item.count), Max: formatMicroseconds(item.max)})
+	}
+	return rows
+}
+
+//查看延迟监控 latency latest|history [event]|doctor
+func latencyCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "json")
+	if err == nil {
+		err = checkCMDFlags(flags, "json")
+	}
+	if err == nil && len(cmdParams) < 2 {
+		err = errors.New("latency需要latest|history|doctor参数，请重新输入")
+	}
+	if err != nil {
+		return err
+	}
+	_, asJSON := flags["json"]
+	switch cmdParams[1] {
+	case "latest":
+		events, err := db.GetRedisLatencyLatest()
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			log.Println("没有延迟事件，请确认latency-monitor-threshold是否大于0")
+			return nil
+		}
+		rows := make([]latencyRow, 0, len(events))
+		for _, event := range events {
+			rows = append(rows, latencyRow{Event: event.Event, Time: formatUnixTime(event.Time),
+				Latest: fmt.Sprintf("%dms", event.Latest), Max: fmt.Sprintf("%dms", event.Max)})
+		}
+		printRows(rows, asJSON)
+	case "history":
+		if len(cmdParams) != 3 {
+			return fmt.Errorf("latency history需要事件名称参数，请重新输入")
+		}
+		samples, err := db.GetRedisLatencyHistory(cmdParams[2])
+		if err != nil {
+			return err
+		}
+		if len(samples) == 0 {
+			log.Printf("事件%s没有延迟记录", cmdParams[2])
+			return nil
+		}
+		rows := make([]latencyHistoryRow, 0, len(samples))
+		for _, sample := range samples {
+			rows = append(rows, latencyHistoryRow{Time: formatUnixTime(sample.Time), Latency: fmt.Sprintf("%dms", sample.Latest)})
+		}
+		printRows(rows, asJSON)
+	case "doctor":
+		report, err := db.GetRedisLatencyDoctor()
+		if err != nil {
+			return err
+		}
+		fmt.Println(report)
+	default:
+		return fmt.Errorf("latency不支持【%s】操作，仅支持latest|history|doctor", cmdParams[1])
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"rediscmd/src/conf"
+	"rediscmd/src/db"
+	"rediscmd/src/util"
+	"strconv"
+	"time"
+)
+
+const streamDefaultCount = 100 //range、pending默认查询的消息数量
+
+//修改stream的子命令，只读配置下禁止执行
+var streamWriteCMDs = map[string]bool{"claim": true, "ack": true, "del": true, "trim": true}
+
+//stream消息的行
+type streamEntryRow struct {
+	ID     string `json:"id"`
+	Fields string `json:"fields"`
+}
+
+//消费者的行
+type streamConsumerRow struct {
+	Name    string `json:"name"`
+	Pending int64  `json:"pending"`
+	Idle    string `json:"idle"`
+}
+
+//未确认消息的行
+type streamPendingRow struct {
+	ID         string `json:"id"`
+	Consumer   string `json:"consumer"`
+	Idle       string `json:"idle"`
+	Deliveries int64  `json:"deliveries"`
+}
+
+//查看和管理stream及消费组
+func streamCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "json", "rev", "approx")
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) < 3 {
+		return fmt.Errorf("stream需要info|range|groups|consumers|pending|claim|ack|del|trim和key两个参数，请重新输入")
+	}
+	sub, key, args := cmdParams[1], cmdParams[2], cmdParams[3:]
+	if streamWriteCMDs[sub] && db.IsRedisReadOnly() {
+		return fmt.Errorf("当前配置%s为只读配置，禁止执行【stream %s】操作", conf.RedisConfName(), sub)
+	}
+	if hasValueCodecFlags(flags) && sub != "range" {
+		return fmt.Errorf("只有stream range支持--decode参数")
+	}
+	_, asJSON := flags["json"]
+	switch sub {
+	case "info":
+		err = streamInfoCMD(key, args, flags, asJSON)
+	case "range":
+		var decode func(value string) string
+		if decode, err = parseValueDecoder(flags); err == nil {
+			err = streamRangeCMD(key, args, flags, asJSON, decode)
+		}
+	case "groups":
+		err = streamGroupsCMD(key, args, flags, asJSON)
+	case "consumers":
+		err = streamConsumersCMD(key, args, flags, asJSON)
+	case "pending":
+		err = streamPendingCMD(key, args, flags, asJSON)
+	case "claim":
+		err = streamClaimCMD(key, args, flags)
+	case "ack":
+		err = streamAckCMD(key, args, flags)
+	case "del":
+		err = streamDelCMD(key, args, flags)
+	case "trim":
+		err = streamTrimCMD(key, args, flags)
+	default:
+		err = fmt.Errorf("stream不支持【%s】操作，仅支持info|range|groups|consumers|pending|claim|ack|del|trim", sub)
+	}
+	return err
+}
+
+//解析--count参数，未指定时返回默认值
+func parseStreamCount(flags map[string]string) (int64, error) {
+	str, ok := flags["count"]
+	if !ok {
+		return streamDefaultCount, nil
+	}
+	count, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || count <= 0 {
+		return 0, errors.New("参数--count必须是正整数")
+	}
+	return count, nil
+}
+
+//将毫秒数格式化为时长
+func formatIdle(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+//stream info [key]
+func streamInfoCMD(key string, args []string, flags map[string]string, asJSON bool) error {
+	if err := checkCMDFlags(flags, "json"); err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("stream info只需要key参数")
+	}
+	info, err := db.GetRedisStreamInfo(key)
+	if err != nil {
+		return err
+	}
+	printRows(info, asJSON)
+	return nil
+}
+
+//stream range [key] [start] [end]
+func streamRangeCMD(key string, args []string, flags map[string]string, asJSON bool, decode func(value string) string) error {
+	if err := checkCMDFlags(flags, append(valueCodecFlags, "json", "rev", "count")...); err != nil {
+		return err
+	}
+	if len(args) > 2 {
+		return errors.New("stream range最多需要起始和结束id两个参数")
+	}
+	start, end := "-", "+"
+	if len(args) > 0 {
+		start = args[0]
+	}
+	if len(args) > 1 {
+		end = args[1]
+	}
+	count, err := parseStreamCount(flags)
+	if err != nil {
+		return err
+	}
+	_, rev := flags["rev"]
+	entries, err := db.GetRedisStreamRange(key, start, end, count, rev)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		log.Println("没有查询到消息")
+		return nil
+	}
+	rows := make([]streamEntryRow, 0, len(entries))
+	for _, entry := range entries {
+		for i := range entry.Fields {
+			entry.Fields[i].Value = decode(entry.Fields[i].Value)
+		}
+		rows = append(rows, streamEntryRow{ID: entry.ID, Fields: formatKVs(entry.Fields)})
+	}
+	printRows(rows, asJSON)
+	if int64(len(entries)) >= count {
+		log.Printf("仅显示前%d条消息，可通过--count调整", count)
+	}
+	return nil
+}
+
+//stream groups [key]
+func streamGroupsCMD(key string, args []string, flags map[string]string, asJSON bool) error {
+	if err := checkCMDFlags(flags, "json"); err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("stream groups只需要key参数")
+	}
+	groups, err := db.GetRedisStreamGroups(key)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		log.Println("没有消费组")
+		return nil
+	}
+	printRows(groups, asJSON)
+	return nil
+}
+
+//stream consumers [key] [group]
+func streamConsumersCMD(key string, args []string, flags map[string]string, asJSON bool) error {
+	if err := checkCMDFlags(flags, "json"); err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("stream consumers需要key和消费组两个参数")
+	}
+	consumers, err := db.GetRedisStreamConsumers(key, args[0])
+	if err != nil {
+		return err
+	}
+	if len(consumers) == 0 {
+		log.Println("消费组中没有消费者")
+		return nil
+	}
+	rows := make([]streamConsumerRow, 0, len(consumers))
+	for _, consumer := range consumers {
+		rows = append(rows, streamConsumerRow{Name: consumer.Name, Pending: consumer.Pending, Idle: formatIdle(consumer.Idle)})
+	}
+	printRows(rows, asJSON)
+	return nil
+}
+
+//stream pending [key] [group] [start] [end]
+func streamPendingCMD(key string, args []string, flags map[string]string, asJSON bool) error {
+	if err := checkCMDFlags(flags, "json", "count", "consumer", "idle"); err != nil {
+		return err
+	}
+	if len(args) < 1 || len(args) > 3 {
+		return errors.New("stream pending需要key和消费组参数，可选起始和结束id")
+	}
+	start, end := "-", "+"
+	if len(args) > 1 {
+		start = args[1]
+	}
+	if len(args) > 2 {
+		end = args[2]
+	}
+	count, err := parseStreamCount(flags)
+	if err != nil {
+		return err
+	}
+	var minIdle time.Duration
+	if str, ok := flags["idle"]; ok {
+		if minIdle, err = util.ParseDuration(str); err != nil {
+			return err
+		}
+	}
+	pendings, err := db.GetRedisStreamPending(key, args[0], start, end, count, flags["consumer"], minIdle.Milliseconds())
+	if err != nil {
+		return err
+	}
+	if len(pendings) == 0 {
+		log.Println("没有未确认的消息")
+		return nil
+	}
+	rows := make([]streamPendingRow, 0, len(pendings))
+	for _, pending := range pendings {
+		rows = append(rows, streamPendingRow{ID: pending.ID, Consumer: pending.Consumer, Idle: formatIdle(pending.Idle), Deliveries: pending.Deliveries})
+	}
+	printRows(rows, asJSON)
+	if int64(len(pendings)) >= count {
+		log.Printf("仅显示前%d条消息，可通过--count调整", count)
+	}
+	return nil
+}
+
+//stream claim [key] [group] [consumer] [最小空闲时间] [id...]
+func streamClaimCMD(key string, args []string, flags map[string]string) error {
+	if err := checkCMDFlags(flags); err != nil {
+		return err
+	}
+	if len(args) < 4 {
+		return errors.New("stream claim需要key、消费组、消费者、最小空闲时间和消息id参数")
+	}
+	minIdle, err := util.ParseDuration(args[2])
+	if err != nil {
+		return err
+	}
+	ids, err := db.ClaimRedisStreamEntries(key, args[0], args[1], minIdle.Milliseconds(), args[3:])
+	if err != nil {
+		return err
+	}
+	log.Printf("%d条消息已转移给%s", len(ids), args[1])
+	for _, id := range ids {
+		log.Println(id)
+	}
+	return nil
+}
+
+//stream ack [key] [group] [id...]
+func streamAckCMD(key string, args []string, flags map[string]string) error {
+	if err := checkCMDFlags(flags); err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return errors.New("stream ack需要key、消费组和消息id参数")
+	}
+	count, err := db.AckRedisStreamEntries(key, args[0], args[1:])
+	if err != nil {
+		return err
+	}
+	log.Printf("已确认%d条消息", count)
+	return nil
+}
+
+//stream del [key] [id...]
+func streamDelCMD(key string, args []string, flags map[string]string) error {
+	if err := checkCMDFlags(flags); err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return errors.New("stream del需要key和消息id参数")
+	}
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("请确认是否从%s中删除%d条消息(y/n):", key, len(args)), false)
+	if isSure != "y" {
+		return nil
+	}
+	count, err := db.DeleteRedisStreamEntries(key, args)
+	if err != nil {
+		return err
+	}
+	log.Printf("已删除%d条消息", count)
+	return nil
+}
+
+//stream trim [key] [maxlen|minid] [阈值]
+func streamTrimCMD(key string, args []string, flags map[string]string) error {
+	if err := checkCMDFlags(flags, "approx"); err != nil {
+		return err
+	}
+	if len(args) != 2 || (args[0] != "maxlen" && args[0] != "minid") {
+		return errors.New("stream trim需要key、maxlen|minid和阈值参数")
+	}
+	_, approx := flags["approx"]
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("请确认是否按%s %s裁剪%s(y/n):", args[0], args[1], key), false)
+	if isSure != "y" {
+		return nil
+	}
+	count, err := db.TrimRedisStream(key, args[0], args[1], approx)
+	if err != nil {
+		return err
+	}
+	log.Printf("已裁剪%d条消息", count)
+	return nil
+}
@@ -0,0 +1,244 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"rediscmd/src/db"
+	"rediscmd/src/util"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const structWriteBatch = 1000 //批量写入时每批次的元素数量
+
+//hash、list、set、zset和计数器的写命令
+type structWriteSpec struct {
+	group   int      //每个元素的参数数量，例如hset为字段和值两个
+	fixed   bool     //参数数量固定为group个，不能批量写入
+	options []string //命令支持的选项，例如zadd的nx、xx
+	usage   string
+}
+
+var structWriteSpecs = map[string]structWriteSpec{
+	"hset":        {group: 2, usage: "hset key field value [field value...]，--file每行为field value或json对象"},
+	"hdel":        {group: 1, usage: "hdel key field [field...]"},
+	"lpush":       {group: 1, usage: "lpush key value [value...]，--file每行一个值或json数组"},
+	"rpush":       {group: 1, usage: "rpush key value [value...]，--file每行一个值或json数组"},
+	"lset":        {group: 2, fixed: true, usage: "lset key index value"},
+	"lrem":        {group: 2, fixed: true, usage: "lrem key count value"},
+	"sadd":        {group: 1, usage: "sadd key member [member...]，--file每行一个成员或json数组"},
+	"srem":        {group: 1, usage: "srem key member [member...]"},
+	"zadd":        {group: 2, options: []string{"nx", "xx", "gt", "lt", "ch"}, usage: "zadd key score member [score member...]，--file每行为score member或{member:score}格式的json对象"},
+	"zrem":        {group: 1, usage: "zrem key member [member...]"},
+	"zincrby":     {group: 2, fixed: true, usage: "zincrby key increment member"},
+	"incr":        {group: 0, usage: "incr key [increment]"},
+	"decr":        {group: 0, usage: "decr key [decrement]"},
+	"incrbyfloat": {group: 1, fixed: true, usage: "incrbyfloat key increment"},
+}
+
+//执行hash、list、set、zset和计数器的写命令，批量写入时分批通过管道执行
+func structWriteCMD(cmdParams []string) error {
+	cmd, head, items, err := parseStructWrite(cmdParams)
+	if err != nil {
+		return err
+	}
+	spec := structWriteSpecs[cmdParams[0]]
+	batch := len(items)
+	if !spec.fixed && spec.group > 0 {
+		batch = structWriteBatch * spec.group
+	}
+	results, err := db.WriteRedisItems(cmd, head, items, batch)
+	if err != nil {
+		return err
+	}
+	var total int64
+	failed := 0
+	for _, result := range results {
+		switch v := result.(type) {
+		case int64:
+			total += v
+		case string:
+			if strings.HasPrefix(v, "(error) ") {
+				log.Println(v)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s %s共%d批，失败%d批", cmd, head[0], len(results), failed)
+	}
+	result := strings.Join(formatRedisReply(results[len(results)-1]), " ")
+	switch cmd {
+	case "lpush", "rpush":
+		result = fmt.Sprintf("列表长度%s", result) //返回值为写入后的长度，取最后一批
+	case "hset", "hdel", "sadd", "srem", "zadd", "zrem":
+		result = fmt.Sprintf("(integer) %d", total)
+	}
+	if spec.fixed || spec.group == 0 {
+		log.Printf("%s %s：%s", cmd, head[0], result)
+		return nil
+	}
+	log.Printf("%s %s共写入%d项：%s", cmd, head[0], len(items)/spec.group, result)
+	return nil
+}
+
+//事务中排队时一次发送所有元素
+func txStructWriteArgs(cmdParams []string) (string, []interface{}, error) {
+	cmd, head, items, err := parseStructWrite(cmdParams)
+	if err != nil {
+		return "", nil, err
+	}
+	return cmd, append(head, items...), nil
+}
+
+//解析写命令的参数，返回redis命令、key和选项以及元素参数
+func parseStructWrite(cmdParams []string) (string, []interface{}, []interface{}, error) {
+	spec := structWriteSpecs[cmdParams[0]]
+	cmdParams, flags, err := parseCMDFlags(cmdParams, spec.options...)
+	if err == nil {
+		allowFlags := append([]string{}, spec.options...)
+		if !spec.fixed && spec.group > 0 {
+			allowFlags = append(allowFlags, "file", "format")
+		}
+		err = checkCMDFlags(flags, allowFlags...)
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(cmdParams) < 2 {
+		return "", nil, nil, fmt.Errorf("参数不正确，格式为%s", spec.usage)
+	}
+	cmd, key, values := cmdParams[0], cmdParams[1], cmdParams[2:]
+	if file, ok := flags["file"]; ok {
+		if len(values) > 0 {
+			return "", nil, nil, fmt.Errorf("使用--file时不能再输入%s的元素", cmd)
+		}
+		if values, err = readStructWriteFile(cmd, file, flags["format"]); err != nil {
+			return "", nil, nil, err
+		}
+		if len(values) == 0 {
+			return "", nil, nil, fmt.Errorf("文件%s中没有可写入的内容", file)
+		}
+	} else if _, ok := flags["format"]; ok {
+		return "", nil, nil, fmt.Errorf("--format需要与--file一起使用")
+	}
+	switch {
+	case spec.group == 0: //incr、decr可选增量参数
+		if len(values) > 1 {
+			return "", nil, nil, fmt.Errorf("参数不正确，格式为%s", spec.usage)
+		}
+		if len(values) == 1 {
+			if _, err := strconv.ParseInt(values[0], 10, 64); err != nil {
+				return "", nil, nil, fmt.Errorf("增量%s必须是整数", values[0])
+			}
+			cmd += "by"
+		}
+	case spec.fixed && len(values) != spec.group, !spec.fixed && (len(values) == 0 || len(values)%spec.group != 0):
+		return "", nil, nil, fmt.Errorf("参数不正确，格式为%s", spec.usage)
+	}
+	if err := checkStructWriteNumbers(cmd, values); err != nil {
+		return "", nil, nil, err
+	}
+	head := []interface{}{key}
+	for _, option := range spec.options {
+		if _, ok := flags[option]; ok {
+			head = append(head, option)
+		}
+	}
+	return cmd, head, stringArgs(values), nil
+}
+
+//检查命令中需要为数字的参数
+func checkStructWriteNumbers(cmd string, values []string) error {
+	switch cmd {
+	case "lset", "lrem":
+		if _, err := strconv.ParseInt(values[0], 10, 64); err != nil {
+			return fmt.Errorf("%s的第一个参数%s必须是整数", cmd, values[0])
+		}
+	case "zincrby", "incrbyfloat":
+		if _, err := strconv.ParseFloat(values[0], 64); err != nil {
+			return fmt.Errorf("增量%s必须是数字", values[0])
+		}
+	case "zadd":
+		for i := 0; i < len(values); i += 2 {
+			if _, err := strconv.ParseFloat(values[i], 64); err != nil {
+				return fmt.Errorf("分数%s必须是数字", values[i])
+			}
+		}
+	}
+	return nil
+}
+
+//从文件读取批量写入的元素，format为lines时每行一个元素，hset、zadd每行以第一个空白分隔为两部分，json时hset、zadd为对象，其他为数组
+func readStructWriteFile(cmd, file, format string) ([]string, error) {
+	if format == "" {
+		format = "lines"
+		if strings.EqualFold(filepath.Ext(file), ".json") {
+			format = "json"
+		}
+	}
+	content, err := util.ReadFileAsString(file)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "lines":
+		values := []string{}
+		for _, line := range strings.Split(content, "\n") {
+			line = strings.TrimRight(line, "\r")
+			if line == "" {
+				continue
+			}
+			if cmd != "hset" && cmd != "zadd" {
+				values = append(values, line)
+				continue
+			}
+			index := strings.IndexAny(line, " \t")
+			if index <= 0 {
+				return nil, fmt.Errorf("无法解析%s：%s，每行应为两部分并以空格或tab分隔", file, util.Truncate(line, 40))
+			}
+			values = append(values, line[:index], strings.TrimLeft(line[index+1:], " \t"))
+		}
+		return values, nil
+	case "json":
+		return readStructWriteJSON(cmd, file, content)
+	}
+	return nil, fmt.Errorf("不支持的文件格式%s，仅支持lines|json", format)
+}
+
+//解析json格式的批量写入文件，非字符串的值以json格式写入
+func readStructWriteJSON(cmd, file, content string) ([]string, error) {
+	if cmd == "hset" || cmd == "zadd" {
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal([]byte(content), &fields); err != nil {
+			return nil, fmt.Errorf("无法解析%s，%s需要json对象：%s", file, cmd, err.Error())
+		}
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		values := make([]string, 0, len(names)*2)
+		for _, name := range names {
+			value := formatJSONCell(fields[name])
+			if cmd == "zadd" { //对象为成员和分数，写入时分数在前
+				values = append(values, value, name)
+				continue
+			}
+			values = append(values, name, value)
+		}
+		return values, nil
+	}
+	items := []json.RawMessage{}
+	if err := json.Unmarshal([]byte(content), &items); err != nil {
+		return nil, fmt.Errorf("无法解析%s，%s需要json数组：%s", file, cmd, err.Error())
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		values = append(values, formatJSONCell(item))
+	}
+	return values, nil
+}
@@ -0,0 +1,111 @@
+package command
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStructWrite(t *testing.T) {
+	dir := t.TempDir()
+	lines := filepath.Join(dir, "members.txt")
+	ioutil.WriteFile(lines, []byte("a\nb c\r\n\n"), 0644)
+	empty := filepath.Join(dir, "empty.txt")
+	ioutil.WriteFile(empty, []byte("\n\n"), 0644)
+	tests := []struct {
+		params string
+		cmd    string
+		head   []interface{}
+		items  []interface{}
+		errMsg string
+	}{
+		{params: "hset user name tom age 18", cmd: "hset", head: []interface{}{"user"}, items: []interface{}{"name", "tom", "age", "18"}},
+		{params: "hset user name", errMsg: "参数不正确"},
+		{params: "hset user", errMsg: "参数不正确"},
+		{params: "hset", errMsg: "参数不正确"},
+		{params: "lpush queue a b", cmd: "lpush", head: []interface{}{"queue"}, items: []interface{}{"a", "b"}},
+		{params: "lset queue 0 a", cmd: "lset", head: []interface{}{"queue"}, items: []interface{}{"0", "a"}},
+		{params: "lset queue x a", errMsg: "必须是整数"},
+		{params: "lset queue 0 a b c", errMsg: "参数不正确"},
+		{params: "zadd rank --nx --ch 1 a 2.5 b", cmd: "zadd", head: []interface{}{"rank", "nx", "ch"}, items: []interface{}{"1", "a", "2.5", "b"}},
+		{params: "zadd rank x a", errMsg: "分数x必须是数字"},
+		{params: "zadd rank --xx", errMsg: "参数不正确"},
+		{params: "zincrby rank 1.5 a", cmd: "zincrby", head: []interface{}{"rank"}, items: []interface{}{"1.5", "a"}},
+		{params: "zincrby rank y a", errMsg: "必须是数字"},
+		{params: "incr counter", cmd: "incr", head: []interface{}{"counter"}, items: []interface{}{}},
+		{params: "incr counter 5", cmd: "incrby", head: []interface{}{"counter"}, items: []interface{}{"5"}},
+		{params: "decr counter 2", cmd: "decrby", head: []interface{}{"counter"}, items: []interface{}{"2"}},
+		{params: "incr counter 1.5", errMsg: "必须是整数"},
+		{params: "incr counter 1 2", errMsg: "参数不正确"},
+		{params: "incrbyfloat counter 0.5", cmd: "incrbyfloat", head: []interface{}{"counter"}, items: []interface{}{"0.5"}},
+		{params: "sadd tags --file " + lines, cmd: "sadd", head: []interface{}{"tags"}, items: []interface{}{"a", "b c"}},
+		{params: "sadd tags x --file " + lines, errMsg: "使用--file时不能再输入"},
+		{params: "sadd tags --file " + empty, errMsg: "没有可写入的内容"},
+		{params: "sadd tags a --format json", errMsg: "--format需要与--file一起使用"},
+		{params: "sadd tags --nx a", errMsg: "不支持的参数--nx"},
+		{params: "lset queue --file " + lines, errMsg: "不支持的参数--file"},
+		{params: "incr counter --file " + lines, errMsg: "不支持的参数--file"},
+	}
+	for _, tt := range tests {
+		cmd, head, items, err := parseStructWrite(strings.Fields(tt.params))
+		if tt.errMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("%s: 错误为%v，期望包含%s", tt.params, err, tt.errMsg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.params, err)
+			continue
+		}
+		if cmd != tt.cmd || !reflect.DeepEqual(head, tt.head) || !reflect.DeepEqual(items, tt.items) {
+			t.Errorf("%s: 解析结果为%s %v %v，期望%s %v %v", tt.params, cmd, head, items, tt.cmd, tt.head, tt.items)
+		}
+	}
+}
+
+func TestReadStructWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	tests := []struct {
+		name    string
+		cmd     string
+		file    string
+		format  string
+		want    []string
+		wantErr bool
+	}{
+		{"每行一个值", "rpush", write("list.txt", "a\r\n\nb c\n"), "", []string{"a", "b c"}, false},
+		{"hset每行两部分", "hset", write("hash.txt", "name tom smith\nage\t 18\n"), "", []string{"name", "tom smith", "age", "18"}, false},
+		{"zadd每行两部分", "zadd", write("zset.txt", "1 a\n2 b\n"), "", []string{"1", "a", "2", "b"}, false},
+		{"hset缺少值", "hset", write("bad.txt", "name\n"), "", nil, true},
+		{"hset行首为空白", "hset", write("space.txt", " name tom\n"), "", nil, true},
+		{"按扩展名识别json数组", "sadd", write("set.json", `["a", 1, {"b": 2}, null]`), "", []string{"a", "1", `{"b":2}`, ""}, false},
+		{"hset的json对象按字段排序", "hset", write("hash.json", `{"b": "2", "a": 1}`), "", []string{"a", "1", "b", "2"}, false},
+		{"zadd的json对象分数在前", "zadd", write("zset.json", `{"m1": 1.5, "m2": 2}`), "", []string{"1.5", "m1", "2", "m2"}, false},
+		{"指定json格式", "lpush", write("list.data", `["x"]`), "json", []string{"x"}, false},
+		{"指定lines格式", "lpush", write("list2.json", `["x"]`), "lines", []string{`["x"]`}, false},
+		{"hset需要json对象", "hset", write("array.json", `["a"]`), "", nil, true},
+		{"sadd需要json数组", "sadd", write("object.json", `{"a": 1}`), "", nil, true},
+		{"不支持的格式", "sadd", write("set.csv", "a"), "csv", nil, true},
+		{"文件不存在", "sadd", filepath.Join(dir, "missing.txt"), "", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := readStructWriteFile(tt.cmd, tt.file, tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: 错误为%v", tt.name, err)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 读取结果为%q，期望%q", tt.name, got, tt.want)
+		}
+	}
+}
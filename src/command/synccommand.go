@@ -0,0 +1,391 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"rediscmd/src/conf"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	syncBatchCount     = 100                    //每批次同步的key数量
+	syncApplyInterval  = 100 * time.Millisecond //检查待同步key的间隔
+	syncReportInterval = 10 * time.Second       //输出同步进度的间隔
+	syncRetryInterval  = 3 * time.Second        //连接出错后重试的间隔
+)
+
+//同步进度，通过--state保存到文件，用于中断后继续初始复制
+type syncState struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Pattern string `json:"pattern"`
+	Cursor  string `json:"cursor"` //初始复制的scan游标
+	Copied  int64  `json:"copied"`
+	Done    bool   `json:"done"` //初始复制是否已完成
+}
+
+//通过keyspace通知收集的待同步key
+type syncTracker struct {
+	lock   sync.Mutex
+	dirty  map[string]time.Time //key及第一次收到通知的时间
+	rescan bool                 //订阅断开期间可能丢失通知，需要重新全量复制
+}
+
+func (t *syncTracker) add(key string, at time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.dirty[key]; !ok {
+		t.dirty[key] = at
+	}
+}
+
+//取出所有待同步的key
+func (t *syncTracker) take() map[string]time.Time {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	dirty := t.dirty
+	t.dirty = map[string]time.Time{}
+	return dirty
+}
+
+func (t *syncTracker) pending() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return len(t.dirty)
+}
+
+func (t *syncTracker) setRescan() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.rescan = true
+}
+
+func (t *syncTracker) takeRescan() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	rescan := t.rescan
+	t.rescan = false
+	return rescan
+}
+
+//将源中匹配的key复制到目标，再通过keyspace通知持续同步变化，按Ctrl+C结束
+func syncCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams)
+	if err == nil {
+		err = checkCMDFlags(flags, "from", "to", "pattern", "state")
+	}
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) != 1 || flags["to"] == "" {
+		return fmt.Errorf("同步需要--to 配置名称[:数据库编号]参数，请重新输入")
+	}
+	options := db.SyncOptions{Pattern: "*"}
+	if pattern, ok := flags["pattern"]; ok {
+		options.Pattern = pattern
+	}
+	if options.FromProfile, options.FromDB, err = parseProfileEndpoint(flags["from"]); err != nil {
+		return err
+	}
+	if options.ToProfile, options.ToDB, err = parseProfileEndpoint(flags["to"]); err != nil {
+		return err
+	}
+	if options.FromProfile == options.ToProfile && options.FromDB == options.ToDB {
+		return fmt.Errorf("源和目标是同一个数据库，无法同步")
+	}
+	targetConf, err := conf.GetProfileRedisConf(options.ToProfile)
+	if err != nil {
+		return err
+	}
+	if targetConf.Redis.ReadOnly {
+		return fmt.Errorf("目标配置%s为只读配置，禁止同步", options.ToProfile)
+	}
+	state := &syncState{From: fmt.Sprintf("%s:%d", options.FromProfile, options.FromDB), To: fmt.Sprintf("%s:%d", options.ToProfile, options.ToDB), Pattern: options.Pattern, Cursor: "0"}
+	stateFile := flags["state"]
+	targetSynced := false //目标此前是否已同步过，中断期间源中删除的key可能残留在目标中
+	if stateFile != "" {
+		targetSynced = loadSyncState(stateFile, state)
+	}
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("此操作将把%s中匹配%s的key持续同步到%s，目标中的同名key将被覆盖，请确认是否执行此操作(y/n):", state.From, options.Pattern, state.To), false)
+	if isSure != "y" {
+		return nil
+	}
+	restore, err := enableSyncKeyspaceEvents(options.FromProfile)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	tracker := &syncTracker{dirty: map[string]time.Time{}}
+	go followSyncEvents(ctx, options, tracker) //先订阅通知，避免遗漏初始复制期间的变化
+	syncer := db.NewRedisSyncer(options)
+	defer syncer.Close()
+	saveState := func() {
+		if stateFile != "" {
+			saveSyncState(stateFile, state)
+		}
+	}
+	defer saveState()
+
+	if !state.Done {
+		resumed := state.Cursor != "0"
+		if resumed {
+			log.Printf("从上次中断的位置继续初始复制，已复制%d个key", state.Copied)
+		}
+		if !copySyncKeys(ctx, syncer, state, saveState) {
+			return nil
+		}
+		if resumed && !reconcileSyncKeys(ctx, syncer, state) {
+			return nil
+		}
+		if targetSynced && !pruneSyncKeys(ctx, syncer, state) {
+			return nil
+		}
+	}
+	log.Printf("开始同步%s中的变化，按Ctrl+C结束", state.From)
+	var synced int64
+	var maxLag time.Duration
+	ticker := time.NewTicker(syncApplyInterval)
+	defer ticker.Stop()
+	lastReport := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("同步结束，共同步%d个变化", synced)
+			return nil
+		case <-ticker.C:
+		}
+		if tracker.takeRescan() {
+			log.Println("订阅断开期间可能遗漏了变化，重新全量复制")
+			state.Cursor, state.Copied, state.Done = "0", 0, false
+			if !copySyncKeys(ctx, syncer, state, saveState) || !pruneSyncKeys(ctx, syncer, state) {
+				return nil
+			}
+		}
+		dirty := tracker.take()
+		keys := make([]string, 0, len(dirty))
+		for key := range dirty {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return dirty[keys[i]].Before(dirty[keys[j]]) })
+		for start := 0; start < len(keys); start += syncBatchCount {
+			end := start + syncBatchCount
+			if end > len(keys) {
+				end = len(keys)
+			}
+			if _, _, err := syncer.Copy(keys[start:end]); err != nil {
+				log.Printf("同步出错：%s，%s后重试", err.Error(), syncRetryInterval)
+				for _, key := range keys[start:] {
+					tracker.add(key, dirty[key])
+				}
+				time.Sleep(syncRetryInterval)
+				break
+			}
+			synced += int64(end - start)
+			if lag := time.Since(dirty[keys[start]]); lag > maxLag {
+				maxLag = lag
+			}
+		}
+		if time.Since(lastReport) >= syncReportInterval {
+			log.Printf("已同步%d个变化，待同步%d个，最大延迟%s", synced, tracker.pending(), maxLag.Truncate(time.Millisecond))
+			lastReport, maxLag = time.Now(), 0
+		}
+	}
+}
+
+//从state中的游标开始复制匹配的key，每批次后保存进度，ctx取消时返回false
+func copySyncKeys(ctx context.Context, syncer *db.RedisSyncer, state *syncState, saveState func()) bool {
+	log.Printf("开始复制%s中匹配%s的key", state.From, state.Pattern)
+	start := time.Now()
+	for ctx.Err() == nil {
+		keys, next, err := syncer.Scan(state.Cursor)
+		if err == nil {
+			_, _, err = syncer.Copy(keys)
+		}
+		if err != nil {
+			log.Printf("复制出错：%s，%s后重试", err.Error(), syncRetryInterval)
+			time.Sleep(syncRetryInterval)
+			continue
+		}
+		state.Cursor = next
+		state.Copied += int64(len(keys))
+		state.Done = next == "0"
+		saveState()
+		if state.Done {
+			log.Printf("初始复制完成，共复制%d个key，耗时%s", state.Copied, time.Since(start).Truncate(time.Millisecond))
+			return true
+		}
+	}
+	log.Printf("初始复制已中止，已复制%d个key", state.Copied)
+	return false
+}
+
+//继续复制时游标之前的key可能在中断期间被修改，重新扫描源中匹配的key，只复制与目标不一致的key，ctx取消时返回false
+func reconcileSyncKeys(ctx context.Context, syncer *db.RedisSyncer, state *syncState) bool {
+	log.Printf("中断期间已复制的key可能已变化，开始对比%s和%s中匹配%s的key", state.From, state.To, state.Pattern)
+	cursor := "0"
+	var copied int64
+	for ctx.Err() == nil {
+		keys, next, err := syncer.Scan(cursor)
+		var changed []string
+		if err == nil {
+			changed, err = syncer.Changed(keys)
+		}
+		if err == nil {
+			_, _, err = syncer.Copy(changed)
+		}
+		if err != nil {
+			log.Printf("对比出错：%s，%s后重试", err.Error(), syncRetryInterval)
+			time.Sleep(syncRetryInterval)
+			continue
+		}
+		cursor = next
+		copied += int64(len(changed))
+		if cursor == "0" {
+			log.Printf("对比完成，共复制%d个不一致的key", copied)
+			return true
+		}
+	}
+	log.Printf("对比已中止，已复制%d个不一致的key", copied)
+	return false
+}
+
+//扫描目标中匹配的key，删除源中已不存在的key，ctx取消时返回false
+func pruneSyncKeys(ctx context.Context, syncer *db.RedisSyncer, state *syncState) bool {
+	log.Printf("开始删除%s中%s已不存在的key", state.To, state.From)
+	cursor := "0"
+	var deleted int64
+	for ctx.Err() == nil {
+		keys, next, err := syncer.ScanTarget(cursor)
+		count := 0
+		if err == nil {
+			count, err = syncer.DeleteMissing(keys)
+		}
+		if err != nil {
+			log.Printf("删除出错：%s，%s后重试", err.Error(), syncRetryInterval)
+			time.Sleep(syncRetryInterval)
+			continue
+		}
+		cursor = next
+		deleted += int64(count)
+		if cursor == "0" {
+			log.Printf("共删除%d个key", deleted)
+			return true
+		}
+	}
+	log.Printf("删除已中止，已删除%d个key", deleted)
+	return false
+}
+
+//订阅源的keyspace通知，断开后自动重新订阅并要求重新全量复制
+func followSyncEvents(ctx context.Context, options db.SyncOptions, tracker *syncTracker) {
+	channelPrefix := fmt.Sprintf("__keyspace@%d__:", options.FromDB)
+	for {
+		msgChan := make(chan model.PubSubMessage, 1000)
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- db.SubscribeProfileRedis(ctx, options.FromProfile, true, []string{channelPrefix + options.Pattern}, msgChan)
+		}()
+		for msg := range msgChan {
+			tracker.add(strings.TrimPrefix(msg.Channel, channelPrefix), time.Now())
+		}
+		err := <-errChan
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("订阅%s的keyspace通知出错：%s，%s后重新订阅", options.FromProfile, err.Error(), syncRetryInterval)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(syncRetryInterval):
+		}
+		tracker.setRescan()
+	}
+}
+
+//检查并开启源的keyspace通知，返回恢复原配置的函数
+func enableSyncKeyspaceEvents(profile string) (func(), error) {
+	items, err := db.GetProfileRedisConfig(profile, "notify-keyspace-events")
+	if err != nil || len(items) == 0 {
+		return nil, fmt.Errorf("无法读取%s的notify-keyspace-events配置：%v", profile, err)
+	}
+	events := items[0].Value
+	newEvents := events
+	for _, flag := range []string{"K", "A"} {
+		if !strings.Contains(newEvents, flag) {
+			newEvents += flag
+		}
+	}
+	if newEvents == events {
+		return func() {}, nil
+	}
+	sourceConf, err := conf.GetProfileRedisConf(profile)
+	if err != nil {
+		return nil, err
+	}
+	if sourceConf.Redis.ReadOnly {
+		return nil, fmt.Errorf("源配置%s为只读配置，无法将notify-keyspace-events从%q修改为%q", profile, events, newEvents)
+	}
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("%s的notify-keyspace-events=%q，需要修改为%q才能同步变化，请确认是否修改(y/n):", profile, events, newEvents), false)
+	if isSure != "y" {
+		return nil, fmt.Errorf("未开启keyspace通知，无法同步")
+	}
+	if err := db.SetProfileRedisConfigValue(profile, "notify-keyspace-events", newEvents); err != nil {
+		return nil, err
+	}
+	return func() {
+		isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("请确认是否将%s的notify-keyspace-events恢复为%q(y/n):", profile, events), false)
+		if isSure != "y" {
+			return
+		}
+		if err := db.SetProfileRedisConfigValue(profile, "notify-keyspace-events", events); err != nil {
+			log.Printf("恢复notify-keyspace-events失败：%s", err.Error())
+		}
+	}, nil
+}
+
+//读取同步进度，源、目标或模糊key不同时忽略，返回目标此前是否已同步过
+func loadSyncState(file string, state *syncState) bool {
+	content, err := util.ReadFileAsString(file)
+	if err != nil {
+		return false
+	}
+	saved := syncState{}
+	if err := json.Unmarshal([]byte(content), &saved); err != nil {
+		log.Printf("无法解析同步进度文件%s：%s", file, err.Error())
+		return false
+	}
+	if saved.From != state.From || saved.To != state.To || saved.Pattern != state.Pattern {
+		log.Printf("同步进度文件%s中的源、目标或模糊key与本次不同，重新全量复制", file)
+		return false
+	}
+	if saved.Done {
+		log.Println("上次已完成初始复制，但中断期间的变化无法通过通知获取，重新全量复制")
+		return true
+	}
+	*state = saved
+	return true
+}
+
+func saveSyncState(file string, state *syncState) {
+	content, _ := json.Marshal(state)
+	if err := ioutil.WriteFile(file, content, 0644); err != nil {
+		log.Printf("保存同步进度失败：%s", err.Error())
+	}
+}
+
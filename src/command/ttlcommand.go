@@ -0,0 +1,182 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"time"
+)
+
+const keysBatchCount = 1000 //批量操作key时每批次的数量
+
+//过期时间分布的区间
+var ttlBuckets = []struct {
+	name string
+	max  time.Duration
+}{
+	{"<1分钟", time.Minute},
+	{"1分钟~1小时", time.Hour},
+	{"1小时~1天", 24 * time.Hour},
+	{"1天~7天", 7 * 24 * time.Hour},
+	{"7天~30天", 30 * 24 * time.Hour},
+	{">30天", 1<<63 - 1},
+}
+
+//过期时间分布的行
+type ttlHistogramRow struct {
+	TTL     string `json:"ttl"`
+	Keys    int64  `json:"keys"`
+	Percent string `json:"percent"`
+	Bar     string `json:"-"`
+}
+
+//解析过期时间相关命令的参数，返回是否忽略大小写、模糊key、剩余参数和过滤条件
+func parseTTLCMDParams(cmdParams []string, argsCount int, paramErrMsg string) (bool, string, []string, *model.KeyFilter, error) {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "no-ttl")
+	if err == nil {
+		err = checkCMDFlags(flags, keyFilterFlags...)
+	}
+	if err != nil {
+		return false, "", nil, nil, err
+	}
+	filter, err := parseKeyFilter(flags)
+	if err != nil {
+		return false, "", nil, nil, err
+	}
+	ignoreCase, args := splitKeysMode(cmdParams[1:])
+	if len(args) != argsCount+1 {
+		return false, "", nil, nil, fmt.Errorf("%s", paramErrMsg)
+	}
+	return ignoreCase, args[0], args[1:], filter, nil
+}
+
+//确认是否对所有key进行操作
+func confirmAllKeys(pattern string, filter *model.KeyFilter, action string) bool {
+	if pattern != "*" || !filter.IsEmpty() {
+		return true
+	}
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("根据您输入的模糊Key=%s此次操作将%s数据库dbid=%d中的所有缓存！请确认是否执行此操作(y/n):", pattern, action, db.RedisOptionDBId()), false)
+	return isSure == "y"
+}
+
+//批量设置模糊key的过期时间
+func expireCMD(cmdParams []string) error {
+	ignoreCase, pattern, args, filter, err := parseTTLCMDParams(cmdParams, 1, "批量设置过期时间需要模糊key和过期时间两个参数，请重新输入")
+	if err != nil {
+		return err
+	}
+	ttl, err := util.ParseDuration(args[0])
+	if err != nil || ttl < time.Millisecond {
+		return fmt.Errorf("无法解析您输入的过期时间")
+	}
+	if !confirmAllKeys(pattern, filter, "设置过期时间") {
+		return nil
+	}
+	total, succeed, failed := 0, 0, 0
+	batchKeys(searchKeysChan(ignoreCase, pattern, filter), keysBatchCount, func(keys []string) {
+		count, err := db.ExpireRedisKeys(keys, ttl)
+		if err != nil {
+			log.Println(err)
+			failed += len(keys)
+		}
+		total += len(keys)
+		succeed += count
+		log.Printf("已处理%d个key", total)
+	})
+	log.Printf("共匹配%d个key，成功设置%d个key的过期时间为%s", total, succeed, ttl)
+	if failed > 0 {
+		return fmt.Errorf("%d个key设置过期时间失败", failed)
+	}
+	return nil
+}
+
+//批量移除模糊key的过期时间
+func persistCMD(cmdParams []string) error {
+	ignoreCase, pattern, _, filter, err := parseTTLCMDParams(cmdParams, 0, "批量移除过期时间需要模糊key参数，请重新输入")
+	if err != nil {
+		return err
+	}
+	if !confirmAllKeys(pattern, filter, "移除过期时间") {
+		return nil
+	}
+	total, succeed, failed := 0, 0, 0
+	batchKeys(searchKeysChan(ignoreCase, pattern, filter), keysBatchCount, func(keys []string) {
+		count, err := db.PersistRedisKeys(keys)
+		if err != nil {
+			log.Println(err)
+			failed += len(keys)
+		}
+		total += len(keys)
+		succeed += count
+	})
+	log.Printf("共匹配%d个key，成功移除%d个key的过期时间", total, succeed)
+	if failed > 0 {
+		return fmt.Errorf("%d个key移除过期时间失败", failed)
+	}
+	return nil
+}
+
+//查看模糊key的过期时间及分布
+func ttlCMD(cmdParams []string) error {
+	ignoreCase, pattern, _, filter, err := parseTTLCMDParams(cmdParams, 0, "查看过期时间需要模糊key参数，请重新输入")
+	if err != nil {
+		return err
+	}
+	counts := make([]int64, len(ttlBuckets))
+	var total, noTTL, failed int64
+	batchKeys(searchKeysChan(ignoreCase, pattern, filter), keysBatchCount, func(keys []string) {
+		ttls, err := db.TTLRedisKeys(keys)
+		if err != nil {
+			log.Println(err)
+			failed += int64(len(keys))
+			return
+		}
+		for i, key := range keys {
+			ttl := ttls[i]
+			switch {
+			case ttl == -2: //查询过程中key已被删除
+				continue
+			case ttl == -1:
+				noTTL++
+				log.Printf("%s 无过期时间", key)
+			default:
+				log.Printf("%s %s", key, time.Duration(ttl)*time.Millisecond)
+				for j, bucket := range ttlBuckets {
+					if time.Duration(ttl)*time.Millisecond < bucket.max {
+						counts[j]++
+						break
+					}
+				}
+			}
+			total++
+		}
+	})
+	rows := []ttlHistogramRow{{TTL: "无过期时间", Keys: noTTL}}
+	for i, bucket := range ttlBuckets {
+		rows = append(rows, ttlHistogramRow{TTL: bucket.name, Keys: counts[i]})
+	}
+	for i := range rows {
+		rows[i].Percent = percent(rows[i].Keys, total)
+		if total > 0 {
+			rows[i].Bar = histogramBar(rows[i].Keys, total)
+		}
+	}
+	log.Printf("共查询到%d个key，过期时间分布：", total)
+	printRows(rows, false)
+	if failed > 0 {
+		return fmt.Errorf("%d个key的过期时间查询失败", failed)
+	}
+	return nil
+}
+
+//按占比生成直方图的条形
+func histogramBar(count, total int64) string {
+	const width = 40
+	bar := make([]byte, count*width/total)
+	for i := range bar {
+		bar[i] = '#'
+	}
+	return string(bar)
+}
@@ -0,0 +1,270 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"rediscmd/src/db"
+	"rediscmd/src/util"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	currentTx *db.RedisTx //当前打开的事务，为nil时没有事务
+	txQueued  []string    //事务中已排队的命令
+)
+
+//事务中可以排队的写命令，返回对应的redis命令和参数，key需要精确指定
+var txCMDs = map[string]func(cmdParams []string) (string, []interface{}, error){
+	"set":         txSetArgs,
+	"del":         txDelArgs,
+	"expire":      txExpireArgs,
+	"persist":     txPersistArgs,
+	"publish":     txPublishArgs,
+	"hset":        txStructWriteArgs,
+	"hdel":        txStructWriteArgs,
+	"lpush":       txStructWriteArgs,
+	"rpush":       txStructWriteArgs,
+	"lset":        txStructWriteArgs,
+	"lrem":        txStructWriteArgs,
+	"sadd":        txStructWriteArgs,
+	"srem":        txStructWriteArgs,
+	"zadd":        txStructWriteArgs,
+	"zrem":        txStructWriteArgs,
+	"zincrby":     txStructWriteArgs,
+	"incr":        txStructWriteArgs,
+	"decr":        txStructWriteArgs,
+	"incrbyfloat": txStructWriteArgs,
+}
+
+//事务进行中不能执行的命令，切换配置或数据库后事务的连接将与当前配置不一致
+var txBlockedCMDs = map[string]bool{"changeconf": true, "resetconf": true, "changeoptdbid": true, "browse": true}
+
+//事务进行中不能执行的子命令，这些写操作无法排队，会在commit前直接修改缓存或服务器状态
+var txBlockedSubCMDs = map[string]map[string]bool{
+	"stream":   streamWriteCMDs,
+	"config":   {"set": true},
+	"clients":  {"kill": true},
+	"script":   {"flush": true},
+	"function": {"load": true, "delete": true, "flush": true},
+}
+
+//事务进行中是否禁止执行，eval和function call只有--ro只读方式执行时才允许
+func isTxBlocked(cmdParams []string) bool {
+	if txBlockedCMDs[cmdParams[0]] {
+		return true
+	}
+	flags := map[string]bool{}
+	for _, item := range cmdParams[1:] {
+		if strings.HasPrefix(item, "--") {
+			flags[item[2:]] = true
+		}
+	}
+	sub := ""
+	if len(cmdParams) > 1 {
+		sub = cmdParams[1]
+	}
+	switch {
+	case cmdParams[0] == "eval", cmdParams[0] == "function" && sub == "call":
+		return !flags["ro"]
+	case cmdParams[0] == "slowlog":
+		return flags["reset"]
+	}
+	return txBlockedSubCMDs[cmdParams[0]][sub]
+}
+
+//事务中命令执行结果的行
+type txResultRow struct {
+	No      int    `json:"no"`
+	Command string `json:"command"`
+	Result  string `json:"result"`
+}
+
+//开始事务，之后的写命令在单独的连接上排队，commit时原子执行
+func beginCMD(cmdParams []string) error {
+	if len(cmdParams) != 1 {
+		return fmt.Errorf("begin不需要参数")
+	}
+	if currentTx != nil {
+		return fmt.Errorf("事务已经开始，已排队%d条命令，请先commit或discard", len(txQueued))
+	}
+	if db.IsRedisReadOnly() {
+		return fmt.Errorf("当前配置为只读配置，无法开始事务")
+	}
+	tx, err := db.BeginRedisTx()
+	if err != nil {
+		return fmt.Errorf("建立事务连接失败：%s", err.Error())
+	}
+	currentTx, txQueued = tx, nil
+	log.Printf("事务已开始（db%d），可先通过watch key...监视key，之后的%s命令将排队到commit时执行，discard放弃", db.RedisOptionDBId(), strings.Join(txCMDNames(), "、"))
+	return nil
+}
+
+//监视key，commit前key被其他客户端修改时事务不执行
+func txWatchCMD(cmdParams []string) error {
+	if len(cmdParams) < 2 {
+		return fmt.Errorf("事务中的watch需要key参数")
+	}
+	if err := currentTx.Watch(cmdParams[1:]); err != nil {
+		return err
+	}
+	log.Printf("已监视%s", strings.Join(cmdParams[1:], " "))
+	return nil
+}
+
+//将写命令加入事务队列
+func queueTxCMD(cmdParams []string) error {
+	toArgs, ok := txCMDs[cmdParams[0]]
+	if !ok {
+		return fmt.Errorf("事务中不支持【%s】，仅支持%s", cmdParams[0], strings.Join(txCMDNames(), "、"))
+	}
+	cmd, args, err := toArgs(cmdParams)
+	if err != nil {
+		return err
+	}
+	if err := currentTx.Queue(cmd, args...); err != nil {
+		return fmt.Errorf("命令排队失败：%s，commit时事务将不会执行", err.Error())
+	}
+	items := []string{cmd}
+	for _, arg := range args {
+		items = append(items, fmt.Sprint(arg))
+	}
+	txQueued = append(txQueued, util.FormatCommand(items))
+	log.Printf("已排队，共%d条命令", len(txQueued))
+	return nil
+}
+
+//原子执行事务中排队的命令
+func commitCMD(cmdParams []string) error {
+	if currentTx == nil {
+		return fmt.Errorf("没有进行中的事务，请先begin")
+	}
+	tx, queued := currentTx, txQueued
+	currentTx, txQueued = nil, nil
+	start := time.Now()
+	results, err := tx.Exec()
+	if err == db.ErrRedisTxAborted {
+		return fmt.Errorf("%s，%d条命令均未执行", err.Error(), len(queued))
+	}
+	if err != nil {
+		return fmt.Errorf("事务执行失败：%s", err.Error())
+	}
+	if len(queued) == 0 {
+		log.Println("事务中没有排队的命令")
+		return nil
+	}
+	rows := make([]txResultRow, 0, len(queued))
+	for i, command := range queued {
+		row := txResultRow{No: i + 1, Command: util.Truncate(command, 60)}
+		if i < len(results) {
+			row.Result = util.Truncate(strings.Join(formatRedisReply(results[i]), " "), 60)
+		}
+		rows = append(rows, row)
+	}
+	printRows(rows, false)
+	log.Printf("事务已提交，共执行%d条命令，耗时%s", len(queued), time.Since(start).Truncate(time.Millisecond))
+	return nil
+}
+
+//放弃事务中排队的命令
+func discardCMD(cmdParams []string) error {
+	if currentTx == nil {
+		return fmt.Errorf("没有进行中的事务")
+	}
+	tx, count := currentTx, len(txQueued)
+	currentTx, txQueued = nil, nil
+	if err := tx.Discard(); err != nil {
+		log.Println(err)
+	}
+	log.Printf("事务已放弃，%d条命令未执行", count)
+	return nil
+}
+
+//事务中支持的命令名称
+func txCMDNames() []string {
+	names := make([]string, 0, len(txCMDs))
+	for name := range txCMDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//set key value|@file [--ex|--px|--keepttl] [--nx|--xx] [--encode] [--max-size]
+func txSetArgs(cmdParams []string) (string, []interface{}, error) {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "nx", "xx", "keepttl")
+	if err == nil {
+		err = checkCMDFlags(flags, "ex", "px", "nx", "xx", "keepttl", "encode", "max-size")
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if len(cmdParams) != 3 {
+		return "", nil, fmt.Errorf("事务中的set需要key和值两个参数")
+	}
+	value, _, err := readSetValue(flags, cmdParams[2])
+	if err != nil {
+		return "", nil, err
+	}
+	options, err := setOptions(flags)
+	if err != nil {
+		return "", nil, err
+	}
+	return "set", append([]interface{}{cmdParams[1], value}, options...), nil
+}
+
+//del key [key...]
+func txDelArgs(cmdParams []string) (string, []interface{}, error) {
+	if len(cmdParams) < 2 {
+		return "", nil, fmt.Errorf("事务中的del需要精确的key参数")
+	}
+	return "del", stringArgs(cmdParams[1:]), nil
+}
+
+//expire key 过期时间
+func txExpireArgs(cmdParams []string) (string, []interface{}, error) {
+	if len(cmdParams) != 3 {
+		return "", nil, fmt.Errorf("事务中的expire需要精确的key和过期时间两个参数")
+	}
+	ttl, err := util.ParseDuration(cmdParams[2])
+	if err != nil || ttl < time.Millisecond {
+		return "", nil, fmt.Errorf("无法解析您输入的过期时间")
+	}
+	return "pexpire", []interface{}{cmdParams[1], ttl.Milliseconds()}, nil
+}
+
+//persist key
+func txPersistArgs(cmdParams []string) (string, []interface{}, error) {
+	if len(cmdParams) != 2 {
+		return "", nil, fmt.Errorf("事务中的persist需要精确的key参数")
+	}
+	return "persist", []interface{}{cmdParams[1]}, nil
+}
+
+//publish channel message [--encode]
+func txPublishArgs(cmdParams []string) (string, []interface{}, error) {
+	cmdParams, flags, err := parseCMDFlags(cmdParams)
+	if err == nil {
+		err = checkCMDFlags(flags, "encode")
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if len(cmdParams) < 3 {
+		return "", nil, fmt.Errorf("发布消息需要频道和消息两个参数")
+	}
+	message, err := encodeValue(flags, strings.Join(cmdParams[2:], " "))
+	if err != nil {
+		return "", nil, err
+	}
+	return "publish", []interface{}{cmdParams[1], message}, nil
+}
+
+func stringArgs(items []string) []interface{} {
+	args := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		args = append(args, item)
+	}
+	return args
+}
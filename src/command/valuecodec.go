@@ -0,0 +1,102 @@
+package command
+
+import (
+	"fmt"
+	"rediscmd/src/codec"
+	"strings"
+)
+
+const valueCodecUsage = "[--decode auto|gzip,json|msgpack|protobuf|hex...] [--proto-desc descriptor set文件 --proto-type 消息类型]"
+
+//值的解码参数
+var valueCodecFlags = []string{"decode", "proto-desc", "proto-type"}
+
+//是否指定了值的解码参数
+func hasValueCodecFlags(flags map[string]string) bool {
+	for _, name := range valueCodecFlags {
+		if _, ok := flags[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+//根据--proto-desc和--proto-type创建本次命令使用的protobuf解码器，未指定时返回nil
+func loadProtobufCodec(flags map[string]string) (*codec.Codec, error) {
+	desc, msgType := flags["proto-desc"], flags["proto-type"]
+	if desc == "" && msgType == "" {
+		return nil, nil
+	}
+	if desc == "" || msgType == "" {
+		return nil, fmt.Errorf("--proto-desc和--proto-type需要同时使用")
+	}
+	schema, err := codec.LoadProtoSchema(desc)
+	if err != nil {
+		return nil, err
+	}
+	return codec.NewProtobufCodec(schema, msgType), nil
+}
+
+//解析--decode的编解码链，指定了descriptor set文件时链中的protobuf按对应的消息类型解码
+func parseDecodeChain(flags map[string]string) (codec.Chain, error) {
+	protoCodec, err := loadProtobufCodec(flags)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := codec.ParseChain(flags["decode"])
+	if err != nil {
+		return nil, err
+	}
+	if protoCodec != nil {
+		chain = chain.Replace(protoCodec)
+	}
+	return chain, nil
+}
+
+//根据--decode参数生成缓存值的解码函数，未指定时原样输出
+func parseValueDecoder(flags map[string]string) (func(value string) string, error) {
+	names, ok := flags["decode"]
+	if !ok {
+		if _, err := loadProtobufCodec(flags); err != nil {
+			return nil, err
+		}
+		return func(value string) string { return value }, nil
+	}
+	if names == "auto" {
+		return func(value string) string {
+			decoded, names := codec.AutoDecode([]byte(value))
+			if len(names) == 0 {
+				return value
+			}
+			return fmt.Sprintf("(%s)\n%s", strings.Join(names, ","), decoded)
+		}, nil
+	}
+	chain, err := parseDecodeChain(flags)
+	if err != nil {
+		return nil, err
+	}
+	return func(value string) string {
+		decoded, err := chain.Decode([]byte(value))
+		if err != nil {
+			return fmt.Sprintf("%s，原始内容：%q", err.Error(), value)
+		}
+		return string(decoded)
+	}, nil
+}
+
+//根据--encode参数编码要写入的值，编解码链与--decode的顺序相同，编码时按相反顺序执行
+func encodeValue(flags map[string]string, value string) (string, error) {
+	names, ok := flags["encode"]
+	if !ok {
+		return value, nil
+	}
+	chain, err := codec.ParseChain(names)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := chain.Encode([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,123 @@
+package command
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//protobuf长度类型的字段
+func protoBytes(num int, data []byte) []byte {
+	return append([]byte{byte(num<<3 | 2), byte(len(data))}, data...)
+}
+
+//protobuf varint类型的字段，只支持小于128的值
+func protoVarint(num int, value byte) []byte {
+	return []byte{byte(num << 3), value}
+}
+
+//生成包含消息test.User{int64 id = 1; string name = 2;}的descriptor set文件
+func writeUserDescriptorSet(t *testing.T) string {
+	field := func(name string, number, fieldType byte) []byte {
+		data := protoBytes(1, []byte(name))
+		data = append(data, protoVarint(3, number)...)
+		data = append(data, protoVarint(4, 1)...)
+		return append(data, protoVarint(5, fieldType)...)
+	}
+	message := protoBytes(1, []byte("User"))
+	message = append(message, protoBytes(2, field("id", 1, 3))...)
+	message = append(message, protoBytes(2, field("name", 2, 9))...)
+	file := append(protoBytes(2, []byte("test")), protoBytes(4, message)...)
+	path := filepath.Join(t.TempDir(), "user.desc")
+	if err := ioutil.WriteFile(path, protoBytes(1, file), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseValueDecoder(t *testing.T) {
+	desc := writeUserDescriptorSet(t)
+	user := string(append(protoVarint(1, 7), protoBytes(2, []byte("tom"))...))
+	tests := []struct {
+		name    string
+		flags   map[string]string
+		value   string
+		want    []string //解码结果中应包含的内容
+		wantErr bool
+	}{
+		{"未指定", map[string]string{}, "raw", []string{"raw"}, false},
+		{"auto普通文本", map[string]string{"decode": "auto"}, "raw", []string{"raw"}, false},
+		{"auto识别json", map[string]string{"decode": "auto"}, `{"a":1}`, []string{"(json)\n", `"a": 1`}, false},
+		{"解码失败输出原始内容", map[string]string{"decode": "base64"}, "!!", []string{"base64解码失败", `原始内容："!!"`}, false},
+		{"按字段编号解码", map[string]string{"decode": "protobuf"}, user, []string{`"1": 7`, `"2": "tom"`}, false},
+		{"按消息类型解码", map[string]string{"decode": "protobuf", "proto-desc": desc, "proto-type": "test.User"}, user, []string{`"id": 7`, `"name": "tom"`}, false},
+		{"消息类型不存在", map[string]string{"decode": "protobuf", "proto-desc": desc, "proto-type": "test.Order"}, user, []string{"descriptor set中不存在消息类型test.Order"}, false},
+		{"缺少proto-type", map[string]string{"decode": "protobuf", "proto-desc": desc}, "", nil, true},
+		{"只指定proto-type", map[string]string{"proto-type": "test.User"}, "", nil, true},
+		{"descriptor set不存在", map[string]string{"decode": "protobuf", "proto-desc": desc + ".missing", "proto-type": "test.User"}, "", nil, true},
+		{"不支持的编解码器", map[string]string{"decode": "snappy"}, "", nil, true},
+	}
+	for _, tt := range tests {
+		decoder, err := parseValueDecoder(tt.flags)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: 错误为%v", tt.name, err)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		got := decoder(tt.value)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: 解码结果%q中没有%q", tt.name, got, want)
+			}
+		}
+	}
+}
+
+//指定的消息类型只在本次命令中生效，不影响之后没有指定的命令
+func TestParseDecodeChainPerCall(t *testing.T) {
+	desc := writeUserDescriptorSet(t)
+	user := append(protoVarint(1, 7), protoBytes(2, []byte("tom"))...)
+	typed, err := parseDecodeChain(map[string]string{"decode": "base64,protobuf", "proto-desc": desc, "proto-type": "test.User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := parseDecodeChain(map[string]string{"decode": "base64,protobuf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := encodeValue(map[string]string{"encode": "base64"}, string(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := typed.Decode([]byte(encoded)); err != nil || !strings.Contains(string(got), `"name": "tom"`) {
+		t.Errorf("指定消息类型时解码结果为%s, %v", got, err)
+	}
+	if got, err := plain.Decode([]byte(encoded)); err != nil || !strings.Contains(string(got), `"2": "tom"`) {
+		t.Errorf("未指定消息类型时解码结果为%s, %v", got, err)
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	tests := []struct {
+		flags   map[string]string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{map[string]string{}, "raw", "raw", false},
+		{map[string]string{"encode": "base64"}, "raw", "cmF3", false},
+		{map[string]string{"encode": "base64,json"}, `{ "a": 1 }`, "eyJhIjoxfQ==", false},
+		{map[string]string{"encode": "hex"}, "72 61 77", "raw", false},
+		{map[string]string{"encode": "protobuf"}, "{}", "", true},
+		{map[string]string{"encode": "json"}, "{", "", true},
+	}
+	for _, tt := range tests {
+		got, err := encodeValue(tt.flags, tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("encodeValue(%v, %q) = %q, %v，期望%q", tt.flags, tt.value, got, err, tt.want)
+		}
+	}
+}
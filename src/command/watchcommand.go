@@ -0,0 +1,207 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"rediscmd/src/db"
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	watchPollInterval = time.Second //默认的轮询间隔
+	watchPollMaxKeys  = 10000       //轮询方式最多监视的key数量
+)
+
+//key变化事件
+type keyEventRow struct {
+	Time  string `json:"time"`
+	Event string `json:"event"`
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+//删除类的事件，无法获取值
+var keyRemovedEvents = map[string]bool{"del": true, "expired": true, "evicted": true, "删除或过期": true}
+
+//通过keyspace通知监视key的变化，无法开启通知时改为轮询
+func watchCMD(cmdParams []string) error {
+	cmdParams, flags, err := parseCMDFlags(cmdParams, "value", "json")
+	if err == nil {
+		err = checkCMDFlags(flags, append(valueCodecFlags, "value", "poll", "json")...)
+	}
+	if err != nil {
+		return err
+	}
+	decode, err := parseValueDecoder(flags)
+	if err != nil {
+		return err
+	}
+	if len(cmdParams) != 2 {
+		return fmt.Errorf("监视key需要模糊key参数，请重新输入")
+	}
+	pattern := cmdParams[1]
+	_, showValue := flags["value"]
+	_, asJSON := flags["json"]
+	printEvent := func(event, key string) {
+		row := keyEventRow{Time: time.Now().Format("15:04:05.000"), Event: event, Key: key}
+		if showValue && !keyRemovedEvents[event] {
+			if value, err := db.GetRedisKeyValue(key, browseValueLimit); err == nil {
+				if str, ok := value.Value.(string); ok {
+					row.Value = decode(str)
+				} else {
+					row.Value = formatValue(value.Value)
+				}
+			}
+		}
+		if asJSON {
+			content, _ := json.Marshal(row)
+			fmt.Println(string(content))
+			return
+		}
+		if row.Value != "" {
+			log.Printf("%s %-8s %s = %s", row.Time, row.Event, row.Key, row.Value)
+		} else {
+			log.Printf("%s %-8s %s", row.Time, row.Event, row.Key)
+		}
+	}
+
+	interval := watchPollInterval
+	str, poll := flags["poll"]
+	if poll {
+		if interval, err = util.ParseDuration(str); err != nil || interval < 100*time.Millisecond {
+			return fmt.Errorf("轮询间隔不能小于100毫秒")
+		}
+	}
+	restore := func() {}
+	if !poll {
+		restore, poll = enableKeyspaceEvents()
+		if poll {
+			log.Printf("改为轮询方式监视，间隔%s", interval)
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if poll {
+		err = pollWatch(ctx, pattern, interval, printEvent)
+	} else {
+		err = notifyWatch(ctx, pattern, printEvent)
+	}
+	stop()
+	restore()
+	return err
+}
+
+//检查并开启keyspace通知，返回恢复原配置的函数，无法开启时返回true表示需要轮询
+func enableKeyspaceEvents() (func(), bool) {
+	events, err := db.GetRedisConfigValue("notify-keyspace-events")
+	if err != nil {
+		log.Printf("无法读取notify-keyspace-events配置：%s", err.Error())
+		return func() {}, true
+	}
+	newEvents := events
+	for _, flag := range []string{"K", "A"} {
+		if !strings.Contains(newEvents, flag) {
+			newEvents += flag
+		}
+	}
+	if newEvents == events {
+		return func() {}, false
+	}
+	if db.IsRedisReadOnly() {
+		log.Printf("当前配置为只读配置，不修改notify-keyspace-events（%q）", events)
+		return func() {}, true
+	}
+	isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("当前notify-keyspace-events=%q，需要修改为%q才能接收key的变化通知，请确认是否修改(y/n)，选择n将使用轮询方式:", events, newEvents), false)
+	if isSure != "y" {
+		return func() {}, true
+	}
+	if err := db.SetRedisConfigValue("notify-keyspace-events", newEvents); err != nil {
+		log.Printf("修改notify-keyspace-events失败：%s", err.Error())
+		return func() {}, true
+	}
+	return func() {
+		isSure, _ := util.ReadValueFromConsole(fmt.Sprintf("请确认是否将notify-keyspace-events恢复为%q(y/n):", events), false)
+		if isSure != "y" {
+			return
+		}
+		if err := db.SetRedisConfigValue("notify-keyspace-events", events); err != nil {
+			log.Printf("恢复notify-keyspace-events失败：%s", err.Error())
+		}
+	}, false
+}
+
+//订阅keyspace通知监视key的变化
+func notifyWatch(ctx context.Context, pattern string, printEvent func(event, key string)) error {
+	channelPrefix := fmt.Sprintf("__keyspace@%d__:", db.RedisOptionDBId())
+	msgChan := make(chan model.PubSubMessage, 1000)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- db.SubscribeRedis(ctx, true, []string{channelPrefix + pattern}, msgChan)
+	}()
+	log.Printf("开始监视db(%d)中匹配%s的key，按Ctrl+C结束", db.RedisOptionDBId(), pattern)
+	for msg := range msgChan {
+		printEvent(msg.Data, strings.TrimPrefix(msg.Channel, channelPrefix))
+	}
+	return <-errChan
+}
+
+//定时扫描key并比较值的摘要监视key的变化，无法区分删除和过期，也无法检测过期时间的变化
+func pollWatch(ctx context.Context, pattern string, interval time.Duration, printEvent func(event, key string)) error {
+	last, err := db.SnapshotRedisKeys(pattern, nil, watchPollMaxKeys)
+	if err != nil {
+		return fmt.Errorf("轮询方式需要使用dump命令：%s", err.Error())
+	}
+	if len(last) >= watchPollMaxKeys {
+		log.Printf("匹配的key超过%d个，仅监视其中%d个", watchPollMaxKeys, watchPollMaxKeys)
+	}
+	log.Printf("开始轮询db(%d)中匹配%s的key（当前%d个），按Ctrl+C结束", db.RedisOptionDBId(), pattern, len(last))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			if failed > 0 {
+				return fmt.Errorf("%d次轮询失败", failed)
+			}
+			return nil
+		case <-ticker.C:
+		}
+		current, err := db.SnapshotRedisKeys(pattern, nil, watchPollMaxKeys)
+		if err != nil {
+			log.Println(err)
+			failed++
+			continue
+		}
+		keys := make([]string, 0, len(current))
+		for key := range current {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if sum, ok := last[key]; !ok {
+				printEvent("新增", key)
+			} else if sum != current[key] {
+				printEvent("修改", key)
+			}
+		}
+		keys = keys[:0]
+		for key := range last {
+			if _, ok := current[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			printEvent("删除或过期", key)
+		}
+		last = current
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"rediscmd/src/model"
 	"rediscmd/src/util"
+	"strings"
 
 	"gopkg.in/gcfg.v1"
 )
@@ -157,3 +158,53 @@ func CreateRedisConfFile() {
 	InitRedisConf()                //初始化配置文件
 	SetRedisConfName(backConfName) //换回目前使用的配置文件
 }
+
+//根据配置名称获取配置文件的绝对路径，名称可以是完整的文件名，也可以是conf-*.ini中*的部分
+func ProfileConfAbsPath(profile string) string {
+	name := profile
+	if !strings.HasSuffix(name, ".ini") {
+		name = fmt.Sprintf("conf-%s.ini", profile)
+	}
+	execPath, err := util.ExecFilePath()
+	if err != nil {
+		return name
+	}
+	return filepath.Join(execPath, name)
+}
+
+//获取指定配置名称的配置
+func GetProfileRedisConf(profile string) (*model.RedisConf, error) {
+	confFileAbsPath := ProfileConfAbsPath(profile)
+	if _, err := os.Stat(confFileAbsPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("配置文件%s不存在", confFileAbsPath)
+	}
+	config := new(model.RedisConf)
+	if err := gcfg.ReadFileInto(config, confFileAbsPath); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+//获取当前配置文件中[group "名称"]定义的配置组包含的配置名称，配置组不存在时返回nil
+func GetRedisConfGroup(name string) []string {
+	config, err := GetRedisConf()
+	if err != nil || config.Group[name] == nil {
+		return nil
+	}
+	profiles := []string{}
+	for _, profile := range strings.Split(config.Group[name].Profiles, ",") {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles
+}
+
+//脚本库目录，与配置文件在同一目录下的scripts目录
+func ScriptLibraryPath() string {
+	execPath, err := util.ExecFilePath()
+	if err != nil {
+		return "scripts"
+	}
+	return filepath.Join(execPath, "scripts")
+}
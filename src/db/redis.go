@@ -13,6 +13,7 @@ import (
 	"rediscmd/src/conf"
 
 	"rediscmd/src/model"
+	"rediscmd/src/util"
 
 	"github.com/garyburd/redigo/redis"
 )
@@ -21,6 +22,7 @@ var (
 	redisPool              *redis.Pool //redis连接池
 	redisDBCount           int         //数据库数量
 	redisOptionDBId        = 0         //操作的redis数据库id
+	redisReadOnly          bool        //当前配置是否为只读配置
 	createRedisConnectLock sync.Mutex  //获取redis连接的锁对象
 )
 
@@ -30,16 +32,10 @@ func initRedisPool() error {
 	if err != nil {
 		return err
 	}
+	redisReadOnly = conf.Redis.ReadOnly
 	redisPool = &redis.Pool{
 		Dial: func() (conn redis.Conn, e error) {
-			c, err := redis.Dial("tcp", fmt.Sprintf("%s:%d", conf.Redis.AddRess, conf.Redis.Port), redis.DialConnectTimeout(30*time.Second))
-			if err != nil {
-				return nil, err
-			}
-			if _, err := c.Do("AUTH", conf.Redis.Password); err != nil {
-				return nil, err
-			} //认证
-			return c, nil
+			return dialRedis(conf)
 		},
 		MaxIdle:     1,                     //连接池中最小空闲连接数
 		MaxActive:   conf.Redis.MaxConnect, //线程此的最大连接数
@@ -48,6 +44,28 @@ func initRedisPool() error {
 	return nil
 }
 
+//根据配置创建一个redis连接
+func dialRedis(conf *model.RedisConf) (redis.Conn, error) {
+	c, err := redis.Dial("tcp", fmt.Sprintf("%s:%d", conf.Redis.AddRess, conf.Redis.Port), redis.DialConnectTimeout(30*time.Second))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Do("AUTH", conf.Redis.Password); err != nil {
+		c.Close()
+		return nil, err
+	} //认证
+	return c, nil
+}
+
+//创建指定配置文件的redis连接，此连接不经过连接池，使用完需要关闭
+func dialRedisProfile(profile string) (redis.Conn, error) {
+	conf, err := conf.GetProfileRedisConf(profile)
+	if err != nil {
+		return nil, err
+	}
+	return dialRedis(conf)
+}
+
 //获取连接要加锁
 func createRedisConnection() (redis.Conn, error) {
 	createRedisConnectLock.Lock()
@@ -93,6 +111,17 @@ func InitRedisInfo(isSelectConfName bool) {
 	}
 }
 
+//非交互方式初始化当前配置的redis信息，出错时直接返回错误，不重新填写配置
+func InitRedisProfile() error {
+	if err := conf.CheckRedisConf(); err != nil {
+		return err
+	}
+	if err := initRedisPool(); err != nil {
+		return err
+	}
+	return initRedisDBCount()
+}
+
 //获取数据库的数量
 func initRedisDBCount() error {
 	connection, err := createRedisConnection()
@@ -151,16 +180,18 @@ func AllRedisDBInfo(isAll bool, count int, dbInfoChan chan model.RedisDBInfo) {
 	wg.Wait()
 }
 
-func SearchRedisKeysIgnoreCase(pattern string, keysChan chan string) {
+func SearchRedisKeysIgnoreCase(pattern string, filter *model.KeyFilter, keysChan chan string) {
 	defer close(keysChan) //关闭通道
 	conf, err := conf.GetRedisConf()
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	pattern = strings.ToLower(pattern)
-	pattern = strings.ReplaceAll(pattern, ".", "\\.")
-	pattern = strings.ReplaceAll(pattern, "*", ".*")
+	patternReg, err := util.IgnoreCaseKeyRegexp(pattern)
+	if err != nil {
+		log.Println(err)
+		return
+	}
 
 	keyPrefixs := strings.Split(conf.Redis.KeyPrefix, ",")
 	var wg sync.WaitGroup
@@ -182,16 +213,17 @@ func SearchRedisKeysIgnoreCase(pattern string, keysChan chan string) {
 			if keysRet == nil { //当前数据库没有缓存key
 				return
 			}
-			matchPatternKeys(pattern, keysRet.([]interface{}), keysChan)
+			matchPatternKeys(conn, patternReg, keysRet.([]interface{}), filter, keysChan)
 		}(prefixItem, &wg)
 	}
 	wg.Wait() //等待结束，释放通道资源
 }
 
-func matchPatternKeys(pattern string, keys []interface{}, keysChan chan string) {
+func matchPatternKeys(conn redis.Conn, patternReg *regexp.Regexp, keys []interface{}, filter *model.KeyFilter, keysChan chan string) {
 	if len(keys) <= 0 {
 		return
 	}
+	matchedKeys := []string{}
 	for _, item := range keys {
 		key := string(item.([]uint8))
 		key = strings.ReplaceAll(key, " ", "")
@@ -199,24 +231,35 @@ func matchPatternKeys(pattern string, keys []interface{}, keysChan chan string)
 			continue
 		}
 		regKey := strings.ToLower(key)
-		if ok, _ := regexp.Match(pattern, []byte(regKey)); ok {
-			keysChan <- key
-			continue
+		if patternReg.MatchString(regKey) {
+			matchedKeys = append(matchedKeys, key)
 		}
 	}
+	for _, key := range matchKeyFilter(conn, matchedKeys, filter, true) {
+		keysChan <- key
+	}
 }
 
-//模糊查询缓存key
-func SearchRedisKeys(pattern string) []string {
+//模糊查询缓存key，有过滤条件时改为scan方式边扫描边过滤
+func SearchRedisKeys(pattern string, filter *model.KeyFilter) []string {
 	if pattern == "" {
 		pattern = "*"
 	}
 	retKeys := []string{}
+	if !filter.IsEmpty() {
+		keysChan := make(chan string, scanBatchCount)
+		go ScanRedisKeys(pattern, filter, keysChan)
+		for key := range keysChan {
+			retKeys = append(retKeys, key)
+		}
+		return retKeys
+	}
 	conn, err := createRedisConnection()
 	if err != nil {
 		log.Println(err)
 		return retKeys
 	}
+	defer conn.Close()
 	keysRet, err := conn.Do("keys", pattern)
 	if err != nil {
 		log.Println(err)
@@ -259,50 +302,50 @@ func GetRedisValue(key string) (string, error) {
 	return string(ret.([]uint8)), nil
 }
 
-//给指定key设置值
-func SetRedisValue(key, value string) {
+//给指定key设置值，options为set命令的附加参数（例如ex、nx），返回值表示是否设置成功
+func SetRedisValue(key, value string, options ...interface{}) (bool, error) {
 	if key == "" {
-		return
+		return false, errors.New("key不能为空")
 	}
 	conn, err := createRedisConnection()
 	if err != nil {
-		log.Println(err)
-		return
+		return false, err
 	}
 	defer conn.Close()
-	conn.Do("set", key, value)
+	ret, err := conn.Do("set", append([]interface{}{key, value}, options...)...)
+	if err != nil {
+		return false, err
+	}
+	return ret != nil, nil //nx、xx条件不满足时返回nil
 }
 
 //删除key的缓存
-func DeleteRedisKey(key ...string) {
+func DeleteRedisKey(key ...string) error {
 	if len(key) == 0 {
-		return
+		return nil
 	}
-	conn, connErr := createRedisConnection()
-	if connErr != nil {
-		log.Println(connErr)
-		return
+	conn, err := createRedisConnection()
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 	keys := make([]interface{}, 0, len(key))
 	for _, item := range key {
 		keys = append(keys, item)
 	}
-	_, err := conn.Do("del", keys...)
-	if err != nil {
-		log.Println(err)
-	}
+	_, err = conn.Do("del", keys...)
+	return err
 }
 
 //清空数据库中的所有缓存
-func FlushRedisDB() {
-	conn, connErr := createRedisConnection()
-	if connErr != nil {
-		log.Println(connErr)
-		return
+func FlushRedisDB() error {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
-	conn.Do("FLUSHDB")
+	_, err = conn.Do("FLUSHDB")
+	return err
 }
 
 //切换redis的操作数据库
@@ -334,3 +377,8 @@ func RedisDBCount() int {
 func RedisOptionDBId() int {
 	return redisOptionDBId
 }
+
+//当前配置是否为只读配置
+func IsRedisReadOnly() bool {
+	return redisReadOnly
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"fmt"
+	"log"
+
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//各数据类型获取元素数量的命令
+var elementsCountCmds = map[string]string{
+	"string": "strlen",
+	"hash":   "hlen",
+	"list":   "llen",
+	"set":    "scard",
+	"zset":   "zcard",
+	"stream": "xlen",
+}
+
+//扫描并分析缓存key的内存占用、元素数量和过期时间，sample大于0时只分析扫描到的前sample个key
+func AnalyzeRedisKeys(pattern string, sample int, filter *model.KeyFilter, keyInfoChan chan model.RedisKeyInfo) {
+	defer close(keyInfoChan) //关闭通道
+	conn, err := createRedisConnection()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer conn.Close()
+	analyzedCount := 0
+	scanRedisKeys(conn, pattern, filter, func(keys []string) bool {
+		if sample > 0 && analyzedCount+len(keys) > sample {
+			keys = keys[:sample-analyzedCount]
+		}
+		for _, keyInfo := range analyzeKeys(conn, keys) {
+			keyInfoChan <- keyInfo
+		}
+		analyzedCount += len(keys)
+		return sample <= 0 || analyzedCount < sample
+	})
+}
+
+//获取单个key的统计信息
+func GetRedisKeyInfo(key string) (*model.RedisKeyInfo, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	keyInfos := analyzeKeys(conn, []string{key})
+	if len(keyInfos) == 0 {
+		return nil, fmt.Errorf("key(%s)不存在", key)
+	}
+	return &keyInfos[0], nil
+}
+
+//通过管道批量获取key的统计信息
+func analyzeKeys(conn redis.Conn, keys []string) []model.RedisKeyInfo {
+	keyInfos := make([]model.RedisKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		conn.Send("type", key)
+		conn.Send("memory", "usage", key)
+		conn.Send("pttl", key)
+	}
+	if err := conn.Flush(); err != nil {
+		log.Println(err)
+		return keyInfos
+	}
+	for _, key := range keys {
+		keyType, _ := redis.String(conn.Receive())
+		memory, _ := redis.Int64(conn.Receive())
+		ttl, _ := redis.Int64(conn.Receive())
+		if keyType == "none" { //扫描过程中key已被删除
+			continue
+		}
+		keyInfos = append(keyInfos, model.RedisKeyInfo{Key: key, Type: keyType, Memory: memory, TTL: ttl})
+	}
+	for _, keyInfo := range keyInfos {
+		if cmd, ok := elementsCountCmds[keyInfo.Type]; ok {
+			conn.Send(cmd, keyInfo.Key)
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		log.Println(err)
+		return keyInfos
+	}
+	for i := range keyInfos {
+		if _, ok := elementsCountCmds[keyInfos[i].Type]; ok {
+			keyInfos[i].Elements, _ = redis.Int64(conn.Receive())
+		}
+	}
+	return keyInfos
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//获取并解析client list
+func GetRedisClients() ([]model.RedisClient, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	content, err := redis.String(conn.Do("client", "list"))
+	if err != nil {
+		return nil, err
+	}
+	clients := []model.RedisClient{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := map[string]string{}
+		for _, item := range strings.Split(line, " ") {
+			if index := strings.Index(item, "="); index > 0 {
+				fields[item[:index]] = item[index+1:]
+			}
+		}
+		client := model.RedisClient{Addr: fields["addr"], Name: fields["name"], Flags: fields["flags"], Cmd: fields["cmd"], User: fields["user"]}
+		client.ID, _ = strconv.ParseInt(fields["id"], 10, 64)
+		client.Age, _ = strconv.ParseInt(fields["age"], 10, 64)
+		client.Idle, _ = strconv.ParseInt(fields["idle"], 10, 64)
+		client.DB, _ = strconv.Atoi(fields["db"])
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+//通过client kill id断开客户端连接，跳过执行命令的连接本身，返回断开的连接数量
+func KillRedisClients(ids []int64) (int64, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	self, err := redis.Int64(conn.Do("client", "id"))
+	if err != nil {
+		return 0, err
+	}
+	var killed int64
+	for _, id := range ids {
+		if id == self {
+			continue
+		}
+		count, err := redis.Int64(conn.Do("client", "kill", "id", id))
+		if err != nil {
+			return killed, err
+		}
+		killed += count
+	}
+	return killed, nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"sort"
+
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//获取redis的配置项
+func GetRedisConfigValue(name string) (string, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	values, err := redis.Strings(conn.Do("config", "get", name))
+	if err != nil {
+		return "", err
+	}
+	if len(values) < 2 {
+		return "", redis.ErrNil
+	}
+	return values[1], nil
+}
+
+//修改redis的配置项
+func SetRedisConfigValue(name, value string) error {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("config", "set", name, value)
+	return err
+}
+
+//获取匹配pattern的所有配置项，按名称排序
+func GetRedisConfig(pattern string) ([]model.KV, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return readRedisConfig(conn, pattern)
+}
+
+//获取指定配置名称对应服务器的配置项
+func GetProfileRedisConfig(profile, pattern string) ([]model.KV, error) {
+	conn, err := dialRedisProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return readRedisConfig(conn, pattern)
+}
+
+func readRedisConfig(conn redis.Conn, pattern string) ([]model.KV, error) {
+	kvs, err := readKVs(conn.Do("config", "get", pattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs, nil
+}
+
+//修改指定配置名称对应服务器的配置项
+func SetProfileRedisConfigValue(profile, name, value string) error {
+	conn, err := dialRedisProfile(profile)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("config", "set", name, value)
+	return err
+}
+
+//将修改后的配置写入redis的配置文件（config rewrite）
+func RewriteRedisConfig() error {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("config", "rewrite")
+	return err
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const dumpTrailerLen = 10 //dump结果末尾的rdb版本号（2字节）和crc64校验码（8字节）
+
+//对比keyspace的参数
+type DiffOptions struct {
+	LeftProfile  string
+	LeftDB       int
+	RightProfile string
+	RightDB      int
+	Pattern      string
+	Filter       *model.KeyFilter
+	Typed        bool  //按数据类型读取值进行对比，不同版本的redis之间dump结果的编码可能不同
+	TTLTolerance int64 //过期时间允许的误差（毫秒）
+	Workers      int   //并发对比的连接数
+}
+
+//对比左右两侧匹配的key，差异写入通道，ctx取消时停止对比，leftScanned、rightScanned记录两侧已扫描的key数量，failed记录出错的次数
+func DiffRedisKeys(ctx context.Context, options DiffOptions, leftScanned, rightScanned, failed *int64, diffChan chan model.KeyDiff) {
+	defer close(diffChan) //关闭通道
+	batchChan := make(chan []string, options.Workers)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { //扫描左侧，交给多个连接并发对比
+		defer wg.Done()
+		defer close(batchChan)
+		conn, err := dialRedisEndpoint(options.LeftProfile, options.LeftDB)
+		if err != nil {
+			log.Printf("连接左侧%s失败：%s", options.LeftProfile, err.Error())
+			atomic.AddInt64(failed, 1)
+			return
+		}
+		defer conn.Close()
+		scanRedisKeys(conn, options.Pattern, options.Filter, func(keys []string) bool {
+			select {
+			case batchChan <- keys:
+				atomic.AddInt64(leftScanned, int64(len(keys)))
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	for i := 0; i < options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d, err := newKeyDiffer(options, failed, diffChan)
+			if err != nil {
+				log.Println(err)
+				atomic.AddInt64(failed, 1)
+				for range batchChan { //丢弃剩余的key，避免扫描阻塞
+				}
+				return
+			}
+			defer d.close()
+			for keys := range batchChan {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := d.compare(keys); err != nil {
+					log.Println(err)
+					atomic.AddInt64(failed, 1)
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() { //扫描右侧，查找左侧不存在的key
+		defer wg.Done()
+		d, err := newKeyDiffer(options, failed, diffChan)
+		if err != nil {
+			log.Println(err)
+			atomic.AddInt64(failed, 1)
+			return
+		}
+		defer d.close()
+		scanRedisKeys(d.right, options.Pattern, options.Filter, func(keys []string) bool {
+			atomic.AddInt64(rightScanned, int64(len(keys)))
+			if err := d.findRightOnly(keys); err != nil {
+				log.Println(err)
+				atomic.AddInt64(failed, 1)
+				return false
+			}
+			return ctx.Err() == nil
+		})
+	}()
+	wg.Wait()
+}
+
+//连接指定配置名称的redis并选择数据库
+func dialRedisEndpoint(profile string, dbid int) (redis.Conn, error) {
+	conn, err := dialRedisProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Do("select", dbid); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+//持有左右两侧连接的对比器
+type keyDiffer struct {
+	options  DiffOptions
+	left     redis.Conn
+	right    redis.Conn
+	failed   *int64 //出错的次数
+	diffChan chan model.KeyDiff
+}
+
+func newKeyDiffer(options DiffOptions, failed *int64, diffChan chan model.KeyDiff) (*keyDiffer, error) {
+	left, err := dialRedisEndpoint(options.LeftProfile, options.LeftDB)
+	if err != nil {
+		return nil, fmt.Errorf("连接左侧%s失败：%s", options.LeftProfile, err.Error())
+	}
+	right, err := dialRedisEndpoint(options.RightProfile, options.RightDB)
+	if err != nil {
+		left.Close()
+		return nil, fmt.Errorf("连接右侧%s失败：%s", options.RightProfile, err.Error())
+	}
+	return &keyDiffer{options: options, left: left, right: right, failed: failed, diffChan: diffChan}, nil
+}
+
+func (d *keyDiffer) close() {
+	d.left.Close()
+	d.right.Close()
+}
+
+//key的类型、过期时间和dump结果
+type keyState struct {
+	keyType string
+	ttl     int64
+	dump    []byte
+}
+
+//通过管道批量获取key的类型、过期时间和dump结果
+func readKeyStates(conn redis.Conn, keys []string) ([]keyState, error) {
+	for _, key := range keys {
+		conn.Send("type", key)
+		conn.Send("pttl", key)
+		conn.Send("dump", key)
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+	states := make([]keyState, 0, len(keys))
+	for range keys {
+		state := keyState{}
+		state.keyType, _ = redis.String(conn.Receive())
+		state.ttl, _ = redis.Int64(conn.Receive())
+		state.dump, _ = redis.Bytes(conn.Receive())
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+//对比左侧扫描到的一批key
+func (d *keyDiffer) compare(keys []string) error {
+	lefts, err := readKeyStates(d.left, keys)
+	if err != nil {
+		return err
+	}
+	rights, err := readKeyStates(d.right, keys)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for i, key := range keys {
+		left, right := lefts[i], rights[i]
+		if left.keyType == "none" || left.dump == nil {
+			continue //扫描过程中key已被删除
+		}
+		diff := model.KeyDiff{Key: key, Left: left.keyType, Right: right.keyType}
+		switch {
+		case right.keyType == "none":
+			diff.Kind, diff.Right = "仅左侧", ""
+		case left.keyType != right.keyType:
+			diff.Kind = "类型不同"
+		default:
+			equal, err := d.valueEqual(key, left, right)
+			if err != nil {
+				log.Printf("对比%s的值出错：%s", key, err.Error())
+				atomic.AddInt64(d.failed, 1)
+				continue
+			}
+			if !equal {
+				diff.Kind = "值不同"
+			} else if !ttlEqual(left.ttl, right.ttl, d.options.TTLTolerance) {
+				diff.Kind, diff.Left, diff.Right = "TTL不同", formatTTL(left.ttl), formatTTL(right.ttl)
+				diff.Patch = ttlPatch(key, left.ttl, now)
+				d.diffChan <- diff
+				continue
+			}
+		}
+		if diff.Kind == "" {
+			continue
+		}
+		diff.Patch = [][]string{{"restore", key, "0", string(left.dump), "replace"}}
+		if left.ttl >= 0 { //restore的过期时间为0时不会设置过期时间
+			diff.Patch = append(diff.Patch, ttlPatch(key, left.ttl, now)...)
+		}
+		d.diffChan <- diff
+	}
+	return nil
+}
+
+//对比两侧的值，dump结果忽略末尾的rdb版本号和校验码
+func (d *keyDiffer) valueEqual(key string, left, right keyState) (bool, error) {
+	if !d.options.Typed {
+		if len(left.dump) < dumpTrailerLen || len(right.dump) < dumpTrailerLen {
+			return bytes.Equal(left.dump, right.dump), nil
+		}
+		return bytes.Equal(left.dump[:len(left.dump)-dumpTrailerLen], right.dump[:len(right.dump)-dumpTrailerLen]), nil
+	}
+	leftValue, err := readRedisValue(d.left, key, 0)
+	if err != nil {
+		return false, err
+	}
+	rightValue, err := readRedisValue(d.right, key, 0)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(sortedValue(leftValue.Value), sortedValue(rightValue.Value)), nil
+}
+
+//hash字段的顺序与写入顺序有关，对比前按字段排序
+func sortedValue(value interface{}) interface{} {
+	if kvs, ok := value.([]model.KV); ok {
+		sorted := append([]model.KV{}, kvs...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+		return sorted
+	}
+	return value
+}
+
+//对比两侧的过期时间，都设置了过期时间时允许tolerance毫秒的误差
+func ttlEqual(left, right, tolerance int64) bool {
+	if left < 0 || right < 0 {
+		return left < 0 && right < 0
+	}
+	diff := left - right
+	return diff <= tolerance && diff >= -tolerance
+}
+
+func formatTTL(ttl int64) string {
+	if ttl < 0 {
+		return "永不过期"
+	}
+	return (time.Duration(ttl) * time.Millisecond).String()
+}
+
+//使右侧的过期时间与左侧一致的命令
+func ttlPatch(key string, ttl, now int64) [][]string {
+	if ttl < 0 {
+		return [][]string{{"persist", key}}
+	}
+	return [][]string{{"pexpireat", key, strconv.FormatInt(now+ttl, 10)}}
+}
+
+//查找右侧扫描到的key中左侧不存在的key
+func (d *keyDiffer) findRightOnly(keys []string) error {
+	for _, key := range keys {
+		d.left.Send("exists", key)
+	}
+	if err := d.left.Flush(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		count, err := redis.Int(d.left.Receive())
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			d.diffChan <- model.KeyDiff{Key: key, Kind: "仅右侧", Patch: [][]string{{"del", key}}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"log"
+	"strings"
+
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const scanBatchCount = 1000 //scan每批次扫描的数量
+
+//过滤条件对应的检查命令
+type filterCheck struct {
+	cmd   string
+	args  []interface{}
+	match func(reply interface{}) bool
+}
+
+//根据过滤条件生成需要对每个key执行的检查命令
+func filterChecks(filter *model.KeyFilter, checkType bool) []filterCheck {
+	checks := []filterCheck{}
+	if checkType && filter.Type != "" {
+		checks = append(checks, filterCheck{cmd: "type", match: func(reply interface{}) bool {
+			keyType, err := redis.String(reply, nil)
+			return err == nil && strings.EqualFold(keyType, filter.Type)
+		}})
+	}
+	if filter.NoTTL || filter.TTL != nil {
+		checks = append(checks, filterCheck{cmd: "pttl", match: func(reply interface{}) bool {
+			pttl, err := redis.Int64(reply, nil)
+			if err != nil {
+				return false
+			}
+			if filter.NoTTL && pttl != -1 {
+				return false
+			}
+			return filter.TTL == nil || (pttl >= 0 && filter.TTL.Match(pttl))
+		}})
+	}
+	if filter.Idle != nil {
+		checks = append(checks, filterCheck{cmd: "object", args: []interface{}{"idletime"}, match: func(reply interface{}) bool {
+			idle, err := redis.Int64(reply, nil)
+			return err == nil && filter.Idle.Match(idle)
+		}})
+	}
+	if filter.Mem != nil {
+		checks = append(checks, filterCheck{cmd: "memory", args: []interface{}{"usage"}, match: func(reply interface{}) bool {
+			mem, err := redis.Int64(reply, nil)
+			return err == nil && filter.Mem.Match(mem)
+		}})
+	}
+	if filter.Encoding != "" {
+		checks = append(checks, filterCheck{cmd: "object", args: []interface{}{"encoding"}, match: func(reply interface{}) bool {
+			encoding, err := redis.String(reply, nil)
+			return err == nil && strings.EqualFold(encoding, filter.Encoding)
+		}})
+	}
+	return checks
+}
+
+//按过滤条件筛选缓存key，所有检查命令通过管道批量发送
+func matchKeyFilter(conn redis.Conn, keys []string, filter *model.KeyFilter, checkType bool) []string {
+	if filter.IsEmpty() || len(keys) == 0 {
+		return keys
+	}
+	checks := filterChecks(filter, checkType)
+	if len(checks) == 0 {
+		return keys
+	}
+	for _, key := range keys {
+		for _, check := range checks {
+			conn.Send(check.cmd, append(check.args, key)...)
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		log.Println(err)
+		return []string{}
+	}
+	matchedKeys := []string{}
+	for _, key := range keys {
+		matched := true
+		for _, check := range checks {
+			reply, err := conn.Receive()
+			if matched && (err != nil || !check.match(reply)) {
+				matched = false
+			}
+		}
+		if matched {
+			matchedKeys = append(matchedKeys, key)
+		}
+	}
+	return matchedKeys
+}
+
+//通过scan按模式和过滤条件扫描缓存key，扫描结果写入通道
+func ScanRedisKeys(pattern string, filter *model.KeyFilter, keysChan chan string) {
+	defer close(keysChan) //关闭通道
+	conn, err := createRedisConnection()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer conn.Close()
+	scanRedisKeys(conn, pattern, filter, func(keys []string) bool {
+		for _, key := range keys {
+			keysChan <- key
+		}
+		return true
+	})
+}
+
+//按批次scan缓存key，每批次过滤后交给handle处理，handle返回false时停止扫描
+func scanRedisKeys(conn redis.Conn, pattern string, filter *model.KeyFilter, handle func(keys []string) bool) {
+	if pattern == "" {
+		pattern = "*"
+	}
+	scanType := filter != nil && filter.Type != "" //由scan的type参数过滤数据类型
+	cursor := "0"
+	for {
+		args := []interface{}{cursor, "match", pattern, "count", scanBatchCount}
+		if scanType {
+			args = append(args, "type", filter.Type)
+		}
+		ret, err := redis.Values(conn.Do("scan", args...))
+		if err != nil && scanType {
+			scanType = false //低版本redis不支持scan type，改为逐个检查key的类型
+			continue
+		}
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		cursor, _ = redis.String(ret[0], nil)
+		keys, _ := redis.Strings(ret[1], nil)
+		keys = matchKeyFilter(conn, keys, filter, !scanType)
+		if len(keys) > 0 && !handle(keys) {
+			return
+		}
+		if cursor == "0" {
+			return
+		}
+	}
+}
+
+//从cursor开始scan缓存key，扫描到至少limit个key或扫描结束时返回，下一个cursor为"0"表示扫描结束
+func ScanRedisKeysPage(pattern, cursor string, limit int) ([]string, string, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, cursor, err
+	}
+	defer conn.Close()
+	keys := []string{}
+	for {
+		ret, err := redis.Values(conn.Do("scan", cursor, "match", pattern, "count", scanBatchCount))
+		if err != nil {
+			return keys, cursor, err
+		}
+		cursor, _ = redis.String(ret[0], nil)
+		page, _ := redis.Strings(ret[1], nil)
+		keys = append(keys, page...)
+		if cursor == "0" || len(keys) >= limit {
+			return keys, cursor, nil
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"rediscmd/src/model"
+	"rediscmd/src/util"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	grepBatchCount   = 500 //读取集合类型元素时每批次的数量
+	grepSnippetWidth = 30  //匹配片段前后保留的字节数
+)
+
+//在缓存值中查找匹配正则表达式的内容，匹配结果写入通道，ctx取消时停止查找，scanned记录已查找的key数量
+func GrepRedisKeys(ctx context.Context, pattern string, filter *model.KeyFilter, matchKey func(key string) bool, valueReg *regexp.Regexp, scanned *int64, matchChan chan model.GrepMatch) {
+	defer close(matchChan) //关闭通道
+	conn, err := createRedisConnection()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer conn.Close()
+	scanRedisKeys(conn, pattern, filter, func(keys []string) bool {
+		for _, key := range keys {
+			if ctx.Err() != nil {
+				return false
+			}
+			if !matchKey(key) {
+				continue
+			}
+			g := &keyGrep{ctx: ctx, conn: conn, key: key, valueReg: valueReg, matchChan: matchChan}
+			if err := g.grep(); err != nil {
+				log.Printf("查找%s出错：%s", key, err.Error())
+			}
+			*scanned++
+		}
+		return ctx.Err() == nil
+	})
+}
+
+//在单个key中查找
+type keyGrep struct {
+	ctx       context.Context
+	conn      redis.Conn
+	key       string
+	keyType   string
+	valueReg  *regexp.Regexp
+	matchChan chan model.GrepMatch
+}
+
+//按数据类型分批读取元素并匹配
+func (g *keyGrep) grep() error {
+	keyType, err := redis.String(g.conn.Do("type", g.key))
+	if err != nil {
+		return err
+	}
+	g.keyType = keyType
+	switch keyType {
+	case "string":
+		value, err := redis.String(g.conn.Do("get", g.key))
+		if err != nil {
+			return err
+		}
+		g.match("值", value)
+		return nil
+	case "hash":
+		return g.scan("hscan", func(items []string) {
+			for i := 0; i+1 < len(items); i += 2 {
+				g.match("字段", items[i])
+				g.match(fmt.Sprintf("字段(%s)的值", items[i]), items[i+1])
+			}
+		})
+	case "set":
+		return g.scan("sscan", func(items []string) {
+			for _, item := range items {
+				g.match("成员", item)
+			}
+		})
+	case "zset":
+		return g.scan("zscan", func(items []string) {
+			for i := 0; i+1 < len(items); i += 2 {
+				g.match(fmt.Sprintf("成员(分数=%s)", items[i+1]), items[i])
+			}
+		})
+	case "list":
+		return g.grepList()
+	case "stream":
+		return g.grepStream()
+	}
+	return nil //key已被删除或模块类型
+}
+
+//使用hscan、sscan、zscan分批读取元素
+func (g *keyGrep) scan(cmd string, handle func(items []string)) error {
+	cursor := "0"
+	for g.ctx.Err() == nil {
+		ret, err := redis.Values(g.conn.Do(cmd, g.key, cursor, "count", grepBatchCount))
+		if err != nil {
+			return err
+		}
+		cursor, _ = redis.String(ret[0], nil)
+		items, _ := redis.Strings(ret[1], nil)
+		handle(items)
+		if cursor == "0" {
+			break
+		}
+	}
+	return nil
+}
+
+//使用lrange分批读取列表元素
+func (g *keyGrep) grepList() error {
+	for start := 0; g.ctx.Err() == nil; start += grepBatchCount {
+		items, err := redis.Strings(g.conn.Do("lrange", g.key, start, start+grepBatchCount-1))
+		if err != nil {
+			return err
+		}
+		for i, item := range items {
+			g.match(fmt.Sprintf("下标(%d)", start+i), item)
+		}
+		if len(items) < grepBatchCount {
+			break
+		}
+	}
+	return nil
+}
+
+//使用xrange分批读取stream消息
+func (g *keyGrep) grepStream() error {
+	start := "-"
+	for g.ctx.Err() == nil {
+		entries, err := redis.Values(g.conn.Do("xrange", g.key, start, "+", "count", grepBatchCount))
+		if err != nil {
+			return err
+		}
+		id := ""
+		for _, entry := range entries {
+			fields, _ := redis.Values(entry, nil)
+			if len(fields) != 2 {
+				continue
+			}
+			id, _ = redis.String(fields[0], nil)
+			kvs, _ := redis.Strings(fields[1], nil)
+			for i := 0; i+1 < len(kvs); i += 2 {
+				g.match(fmt.Sprintf("消息(%s)的字段", id), kvs[i])
+				g.match(fmt.Sprintf("消息(%s)字段(%s)的值", id, kvs[i]), kvs[i+1])
+			}
+		}
+		if len(entries) < grepBatchCount || id == "" {
+			break
+		}
+		start = nextStreamID(id)
+	}
+	return nil
+}
+
+//计算下一个stream消息id，用于兼容不支持"("开区间的低版本redis
+func nextStreamID(id string) string {
+	parts := strings.SplitN(id, "-", 2)
+	if len(parts) != 2 {
+		return id
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return id
+	}
+	return parts[0] + "-" + strconv.FormatUint(seq+1, 10)
+}
+
+//匹配一个值，匹配成功时写入通道
+func (g *keyGrep) match(location, value string) {
+	loc := g.valueReg.FindStringIndex(value)
+	if loc == nil {
+		return
+	}
+	grepMatch := model.GrepMatch{Key: g.key, Type: g.keyType, Location: location, Snippet: util.Snippet(value, loc, grepSnippetWidth)}
+	select {
+	case g.matchChan <- grepMatch:
+	case <-g.ctx.Done():
+	}
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"strings"
+
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//执行info命令并按分组解析，section为空时返回默认分组
+func GetRedisInfo(section string) ([]model.RedisInfoSection, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	args := []interface{}{}
+	if section != "" {
+		args = append(args, section)
+	}
+	content, err := redis.String(conn.Do("info", args...))
+	if err != nil {
+		return nil, err
+	}
+	sections := []model.RedisInfoSection{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			sections = append(sections, model.RedisInfoSection{Name: name})
+			continue
+		}
+		index := strings.Index(line, ":")
+		if index <= 0 || len(sections) == 0 {
+			continue
+		}
+		last := &sections[len(sections)-1]
+		last.Items = append(last.Items, model.KV{Key: line[:index], Value: line[index+1:]})
+	}
+	return sections, nil
+}
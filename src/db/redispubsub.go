@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+
+	"rediscmd/src/conf"
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//在不经过连接池的独立连接上订阅频道，pattern为true时按模式订阅，收到的消息写入通道，ctx取消时退出订阅
+func SubscribeRedis(ctx context.Context, pattern bool, channels []string, msgChan chan model.PubSubMessage) error {
+	defer close(msgChan) //关闭通道
+	config, err := conf.GetRedisConf()
+	if err != nil {
+		return err
+	}
+	conn, err := dialRedis(config)
+	if err != nil {
+		return err
+	}
+	return subscribeRedis(ctx, conn, pattern, channels, msgChan)
+}
+
+//订阅指定配置名称的redis的频道，参数与SubscribeRedis相同
+func SubscribeProfileRedis(ctx context.Context, profile string, pattern bool, channels []string, msgChan chan model.PubSubMessage) error {
+	defer close(msgChan) //关闭通道
+	conn, err := dialRedisProfile(profile)
+	if err != nil {
+		return err
+	}
+	return subscribeRedis(ctx, conn, pattern, channels, msgChan)
+}
+
+func subscribeRedis(ctx context.Context, conn redis.Conn, pattern bool, channels []string, msgChan chan model.PubSubMessage) error {
+	psc := redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+	args := make([]interface{}, 0, len(channels))
+	for _, channel := range channels {
+		args = append(args, channel)
+	}
+	var err error
+	if pattern {
+		err = psc.PSubscribe(args...)
+	} else {
+		err = psc.Subscribe(args...)
+	}
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			psc.Close() //关闭连接使Receive返回
+		case <-done:
+		}
+	}()
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			msgChan <- model.PubSubMessage{Channel: v.Channel, Data: string(v.Data)}
+		case redis.PMessage:
+			msgChan <- model.PubSubMessage{Pattern: v.Pattern, Channel: v.Channel, Data: string(v.Data)}
+		case error:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return v
+		}
+	}
+}
+
+//发布消息，返回收到消息的订阅者数量
+func PublishRedis(channel, message string) (int64, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return redis.Int64(conn.Do("publish", channel, message))
+}
+
+//查询活跃的频道，pattern为空时查询全部
+func PubSubRedisChannels(pattern string) ([]string, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	args := []interface{}{"channels"}
+	if pattern != "" {
+		args = append(args, pattern)
+	}
+	return redis.Strings(conn.Do("pubsub", args...))
+}
+
+//查询频道的订阅者数量
+func PubSubRedisNumSub(channels []string) ([]int64, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	args := []interface{}{"numsub"}
+	for _, channel := range channels {
+		args = append(args, channel)
+	}
+	values, err := redis.Values(conn.Do("pubsub", args...))
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int64, 0, len(channels))
+	for i := 1; i < len(values); i += 2 {
+		count, _ := redis.Int64(values[i], nil)
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+//查询模式订阅的数量
+func PubSubRedisNumPat() (int64, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return redis.Int64(conn.Do("pubsub", "numpat"))
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//key重命名的参数
+type RenameOptions struct {
+	Copy  bool //复制而不是重命名，保留原key
+	ToDB  int  //目标数据库编号，小于0表示当前操作的数据库
+	Force bool //目标key存在时覆盖
+}
+
+//检查key在指定数据库中是否存在，dbid小于0时为当前操作的数据库
+func ExistsRedisKeys(keys []string, dbid int) ([]bool, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if dbid >= 0 {
+		if _, err := conn.Do("select", dbid); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range keys {
+		conn.Send("exists", key)
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+	exists := make([]bool, 0, len(keys))
+	for range keys {
+		count, err := redis.Int(conn.Receive())
+		if err != nil {
+			return nil, err
+		}
+		exists = append(exists, count > 0)
+	}
+	return exists, nil
+}
+
+//批量重命名key，pairs中Key为原key，Value为新key，返回每个key的执行结果（nil表示成功）
+func RenameRedisKeys(pairs []model.KV, opts RenameOptions) []error {
+	errs := make([]error, len(pairs))
+	setAll := func(err error) []error {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	conn, err := createRedisConnection()
+	if err != nil {
+		return setAll(err)
+	}
+	defer conn.Close()
+	for _, pair := range pairs {
+		cmd, args := renameCommand(pair, opts)
+		conn.Send(cmd, args...)
+	}
+	if err := conn.Flush(); err != nil {
+		return setAll(err)
+	}
+	needDelete := []string{} //通过copy跨库移动成功后需要删除原key
+	deleteIndexes := []int{}
+	for i, pair := range pairs {
+		reply, err := conn.Receive()
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		if ret, ok := reply.(int64); ok && ret == 0 {
+			errs[i] = errors.New("目标key已存在")
+			continue
+		}
+		if opts.ToDB >= 0 && !opts.Copy && usesCopy(pair, opts) {
+			needDelete = append(needDelete, pair.Key)
+			deleteIndexes = append(deleteIndexes, i)
+		}
+	}
+	if err := DeleteRedisKey(needDelete...); err != nil {
+		for _, i := range deleteIndexes {
+			errs[i] = fmt.Errorf("已复制到目标数据库，删除原key失败：%s", err.Error())
+		}
+	}
+	return errs
+}
+
+//是否需要通过copy命令实现
+func usesCopy(pair model.KV, opts RenameOptions) bool {
+	return opts.Copy || (opts.ToDB >= 0 && (pair.Key != pair.Value || opts.Force))
+}
+
+//生成重命名一个key的命令：同库使用rename/renamenx，跨库且不改名使用move，其余使用copy
+func renameCommand(pair model.KV, opts RenameOptions) (string, []interface{}) {
+	if usesCopy(pair, opts) {
+		args := []interface{}{pair.Key, pair.Value}
+		if opts.ToDB >= 0 {
+			args = append(args, "db", opts.ToDB)
+		}
+		if opts.Force {
+			args = append(args, "replace")
+		}
+		return "copy", args
+	}
+	if opts.ToDB >= 0 {
+		return "move", []interface{}{pair.Key, opts.ToDB}
+	}
+	if opts.Force {
+		return "rename", []interface{}{pair.Key, pair.Value}
+	}
+	return "renamenx", []interface{}{pair.Key, pair.Value}
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"log"
+	"time"
+
+	"rediscmd/src/util"
+)
+
+const replayBatchCount = 100 //重放命令时每批次通过管道发送的命令数量
+
+//将命令重放到指定配置文件的redis，rate为每秒最多执行的命令数量，0表示不限速
+func ReplayRedisCommands(profile string, rate int, cmdChan chan []string) (succeed, failed int64, err error) {
+	conn, err := dialRedisProfile(profile)
+	if err != nil {
+		for range cmdChan { //丢弃剩余的命令，避免发送方阻塞
+		}
+		return 0, 0, err
+	}
+	defer conn.Close()
+	batchCount := replayBatchCount
+	if rate > 0 && rate < batchCount {
+		batchCount = rate
+	}
+	start := time.Now()
+	batch := make([][]string, 0, batchCount)
+	flush := func() {
+		for _, args := range batch {
+			cmdArgs := make([]interface{}, 0, len(args)-1)
+			for _, arg := range args[1:] {
+				cmdArgs = append(cmdArgs, arg)
+			}
+			conn.Send(args[0], cmdArgs...)
+		}
+		if err := conn.Flush(); err != nil {
+			log.Println(err)
+			failed += int64(len(batch))
+			batch = batch[:0]
+			return
+		}
+		for _, args := range batch {
+			if _, err := conn.Receive(); err != nil {
+				failed++
+				log.Printf("%s 执行失败：%s", util.FormatCommand(args), err.Error())
+				continue
+			}
+			succeed++
+		}
+		batch = batch[:0]
+		if rate > 0 { //按速率计算已执行命令应耗费的时间，执行过快时等待
+			expected := time.Duration(float64(succeed+failed) / float64(rate) * float64(time.Second))
+			if elapsed := time.Since(start); elapsed < expected {
+				time.Sleep(expected - elapsed)
+			}
+		}
+	}
+	for args := range cmdChan {
+		if len(args) == 0 {
+			continue
+		}
+		batch = append(batch, args)
+		if len(batch) >= batchCount {
+			flush()
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+	return succeed, failed, nil
+}
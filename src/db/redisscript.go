@@ -0,0 +1,212 @@
+package db
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//计算脚本的sha1，与script load返回的值相同
+func RedisScriptSHA(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+//执行lua脚本，先通过evalsha执行服务器缓存的脚本，返回NOSCRIPT时再通过eval执行并缓存，readOnly时使用redis7的只读命令
+func EvalRedisScript(script string, keys, args []string, readOnly bool) (interface{}, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	evalsha, eval := "evalsha", "eval"
+	if readOnly {
+		evalsha, eval = "evalsha_ro", "eval_ro"
+	}
+	params := scriptParams(keys, args)
+	reply, err := conn.Do(evalsha, append([]interface{}{RedisScriptSHA(script)}, params...)...)
+	if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		reply, err = conn.Do(eval, append([]interface{}{script}, params...)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return convertRedisReply(reply), nil
+}
+
+//拼接脚本和函数调用的key数量、key和参数
+func scriptParams(keys, args []string) []interface{} {
+	params := make([]interface{}, 0, len(keys)+len(args)+1)
+	params = append(params, len(keys))
+	for _, key := range keys {
+		params = append(params, key)
+	}
+	for _, arg := range args {
+		params = append(params, arg)
+	}
+	return params
+}
+
+//将redis的返回值转换为字符串、整数、nil和切片组成的值，便于输出
+func convertRedisReply(reply interface{}) interface{} {
+	switch v := reply.(type) {
+	case []byte:
+		return string(v)
+	case redis.Error:
+		return "(error) " + v.Error()
+	case []interface{}:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			items = append(items, convertRedisReply(item))
+		}
+		return items
+	default:
+		return v
+	}
+}
+
+//将脚本加载到服务器的脚本缓存，返回sha1
+func LoadRedisScript(script string) (string, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return redis.String(conn.Do("script", "load", script))
+}
+
+//检查脚本是否已缓存在服务器
+func ExistsRedisScripts(shas []string) ([]bool, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	params := make([]interface{}, 0, len(shas)+1)
+	params = append(params, "exists")
+	for _, sha := range shas {
+		params = append(params, sha)
+	}
+	values, err := redis.Ints(conn.Do("script", params...))
+	if err != nil {
+		return nil, err
+	}
+	exists := make([]bool, 0, len(values))
+	for _, value := range values {
+		exists = append(exists, value == 1)
+	}
+	return exists, nil
+}
+
+//清空服务器的脚本缓存
+func FlushRedisScripts() error {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("script", "flush")
+	return err
+}
+
+//加载函数库（redis7），返回库名称
+func LoadRedisFunction(code string, replace bool) (string, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if replace {
+		return redis.String(conn.Do("function", "load", "replace", code))
+	}
+	return redis.String(conn.Do("function", "load", code))
+}
+
+//获取名称匹配pattern的函数库中的函数（redis7）
+func ListRedisFunctions(pattern string) ([]model.RedisFunction, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	params := []interface{}{"list"}
+	if pattern != "" {
+		params = append(params, "libraryname", pattern)
+	}
+	libraries, err := redis.Values(conn.Do("function", params...))
+	if err != nil {
+		return nil, err
+	}
+	functions := []model.RedisFunction{}
+	for _, library := range libraries {
+		libraryFields := readReplyMap(library)
+		libraryName, _ := redis.String(libraryFields["library_name"], nil)
+		engine, _ := redis.String(libraryFields["engine"], nil)
+		items, _ := redis.Values(libraryFields["functions"], nil)
+		for _, item := range items {
+			fields := readReplyMap(item)
+			function := model.RedisFunction{Library: libraryName, Engine: engine}
+			function.Name, _ = redis.String(fields["name"], nil)
+			function.Description, _ = redis.String(fields["description"], nil)
+			flags, _ := redis.Strings(fields["flags"], nil)
+			function.Flags = strings.Join(flags, ",")
+			functions = append(functions, function)
+		}
+	}
+	return functions, nil
+}
+
+//将名称和值交替排列的返回值转换为map
+func readReplyMap(reply interface{}) map[string]interface{} {
+	items, _ := redis.Values(reply, nil)
+	fields := make(map[string]interface{}, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		name, _ := redis.String(items[i], nil)
+		fields[name] = items[i+1]
+	}
+	return fields
+}
+
+//调用函数（redis7），readOnly时使用fcall_ro
+func CallRedisFunction(name string, keys, args []string, readOnly bool) (interface{}, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	fcall := "fcall"
+	if readOnly {
+		fcall = "fcall_ro"
+	}
+	reply, err := conn.Do(fcall, append([]interface{}{name}, scriptParams(keys, args)...)...)
+	if err != nil {
+		return nil, err
+	}
+	return convertRedisReply(reply), nil
+}
+
+//删除函数库（redis7）
+func DeleteRedisFunction(library string) error {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("function", "delete", library)
+	return err
+}
+
+//删除所有函数库（redis7）
+func FlushRedisFunctions() error {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("function", "flush")
+	return err
+}
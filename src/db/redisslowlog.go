@@ -0,0 +1,108 @@
+package db
+
+import (
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//获取最近count条慢查询日志，count小于0时获取全部
+func GetRedisSlowlog(count int) ([]model.SlowlogEntry, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	items, err := redis.Values(conn.Do("slowlog", "get", count))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]model.SlowlogEntry, 0, len(items))
+	for _, item := range items {
+		fields, err := redis.Values(item, nil)
+		if err != nil || len(fields) < 4 {
+			continue
+		}
+		entry := model.SlowlogEntry{}
+		entry.ID, _ = redis.Int64(fields[0], nil)
+		entry.Time, _ = redis.Int64(fields[1], nil)
+		entry.Duration, _ = redis.Int64(fields[2], nil)
+		entry.Args, _ = redis.Strings(fields[3], nil)
+		if len(fields) >= 6 {
+			entry.Client, _ = redis.String(fields[4], nil)
+			entry.ClientName, _ = redis.String(fields[5], nil)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+//清空慢查询日志
+func ResetRedisSlowlog() error {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("slowlog", "reset")
+	return err
+}
+
+//获取各事件最近的延迟（latency latest）
+func GetRedisLatencyLatest() ([]model.LatencyEvent, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	items, err := redis.Values(conn.Do("latency", "latest"))
+	if err != nil {
+		return nil, err
+	}
+	events := make([]model.LatencyEvent, 0, len(items))
+	for _, item := range items {
+		fields, err := redis.Values(item, nil)
+		if err != nil || len(fields) < 4 {
+			continue
+		}
+		event := model.LatencyEvent{}
+		event.Event, _ = redis.String(fields[0], nil)
+		event.Time, _ = redis.Int64(fields[1], nil)
+		event.Latest, _ = redis.Int64(fields[2], nil)
+		event.Max, _ = redis.Int64(fields[3], nil)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+//获取事件的延迟历史（latency history），返回的每一项为时间和延迟
+func GetRedisLatencyHistory(event string) ([]model.LatencyEvent, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	items, err := redis.Values(conn.Do("latency", "history", event))
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]model.LatencyEvent, 0, len(items))
+	for _, item := range items {
+		fields, err := redis.Int64s(item, nil)
+		if err != nil || len(fields) < 2 {
+			continue
+		}
+		samples = append(samples, model.LatencyEvent{Event: event, Time: fields[0], Latest: fields[1]})
+	}
+	return samples, nil
+}
+
+//获取延迟诊断报告（latency doctor）
+func GetRedisLatencyDoctor() (string, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return redis.String(conn.Do("latency", "doctor"))
+}
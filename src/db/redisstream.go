@@ -0,0 +1,213 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+
+	"rediscmd/src/model"
+	"rediscmd/src/rdb"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//获取stream的概要信息（xinfo stream），首尾消息只保留id
+func GetRedisStreamInfo(key string) ([]model.KV, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	items, err := redis.Values(conn.Do("xinfo", "stream", key))
+	if err != nil {
+		return nil, err
+	}
+	kvs := make([]model.KV, 0, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		name, _ := redis.String(items[i], nil)
+		kvs = append(kvs, model.KV{Key: name, Value: formatStreamInfoValue(items[i+1])})
+	}
+	return kvs, nil
+}
+
+//将xinfo返回的值转换为字符串，消息（[id, fields]）只保留id
+func formatStreamInfoValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []byte:
+		return string(v)
+	case []interface{}:
+		if len(v) == 2 {
+			if id, ok := v[0].([]byte); ok {
+				return string(id)
+			}
+		}
+		return fmt.Sprint(len(v))
+	}
+	return fmt.Sprint(value)
+}
+
+//将xinfo返回的字段列表转换为map
+func readStreamInfoMap(reply interface{}) map[string]interface{} {
+	items, _ := redis.Values(reply, nil)
+	fields := make(map[string]interface{}, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		name, _ := redis.String(items[i], nil)
+		fields[name] = items[i+1]
+	}
+	return fields
+}
+
+//获取stream的消费组列表（xinfo groups）
+func GetRedisStreamGroups(key string) ([]model.StreamGroup, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	items, err := redis.Values(conn.Do("xinfo", "groups", key))
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]model.StreamGroup, 0, len(items))
+	for _, item := range items {
+		fields := readStreamInfoMap(item)
+		group := model.StreamGroup{}
+		group.Name, _ = redis.String(fields["name"], nil)
+		group.Consumers, _ = redis.Int64(fields["consumers"], nil)
+		group.Pending, _ = redis.Int64(fields["pending"], nil)
+		group.LastDeliveredID, _ = redis.String(fields["last-delivered-id"], nil)
+		group.Lag = formatStreamInfoValue(fields["lag"])
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+//获取消费组中的消费者列表（xinfo consumers）
+func GetRedisStreamConsumers(key, group string) ([]model.StreamConsumer, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	items, err := redis.Values(conn.Do("xinfo", "consumers", key, group))
+	if err != nil {
+		return nil, err
+	}
+	consumers := make([]model.StreamConsumer, 0, len(items))
+	for _, item := range items {
+		fields := readStreamInfoMap(item)
+		consumer := model.StreamConsumer{}
+		consumer.Name, _ = redis.String(fields["name"], nil)
+		consumer.Pending, _ = redis.Int64(fields["pending"], nil)
+		consumer.Idle, _ = redis.Int64(fields["idle"], nil)
+		consumers = append(consumers, consumer)
+	}
+	return consumers, nil
+}
+
+//获取消费组中已投递未确认的消息（xpending），consumer不为空时只查询该消费者，minIdle大于0时需要redis6.2及以上
+func GetRedisStreamPending(key, group, start, end string, count int64, consumer string, minIdle int64) ([]model.StreamPending, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	args := []interface{}{key, group}
+	if minIdle > 0 {
+		args = append(args, "idle", minIdle)
+	}
+	args = append(args, start, end, count)
+	if consumer != "" {
+		args = append(args, consumer)
+	}
+	items, err := redis.Values(conn.Do("xpending", args...))
+	if err != nil {
+		return nil, err
+	}
+	pendings := make([]model.StreamPending, 0, len(items))
+	for _, item := range items {
+		fields, err := redis.Values(item, nil)
+		if err != nil || len(fields) != 4 {
+			continue
+		}
+		pending := model.StreamPending{}
+		pending.ID, _ = redis.String(fields[0], nil)
+		pending.Consumer, _ = redis.String(fields[1], nil)
+		pending.Idle, _ = redis.Int64(fields[2], nil)
+		pending.Deliveries, _ = redis.Int64(fields[3], nil)
+		pendings = append(pendings, pending)
+	}
+	return pendings, nil
+}
+
+//按id范围读取stream的消息，rev为true时倒序读取（start、end仍为小、大的id）
+func GetRedisStreamRange(key, start, end string, count int64, rev bool) ([]rdb.StreamEntry, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if rev {
+		return readStreamEntries(conn.Do("xrevrange", key, end, start, "count", count))
+	}
+	return readStreamEntries(conn.Do("xrange", key, start, end, "count", count))
+}
+
+//将空闲时间不小于minIdle毫秒的消息转移给consumer（xclaim），返回转移成功的消息id
+func ClaimRedisStreamEntries(key, group, consumer string, minIdle int64, ids []string) ([]string, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	args := []interface{}{key, group, consumer, minIdle}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return redis.Strings(conn.Do("xclaim", append(args, "justid")...))
+}
+
+//确认消费组中的消息（xack），返回确认的消息数量
+func AckRedisStreamEntries(key, group string, ids []string) (int64, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	args := []interface{}{key, group}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return redis.Int64(conn.Do("xack", args...))
+}
+
+//删除stream中的消息（xdel），返回删除的消息数量
+func DeleteRedisStreamEntries(key string, ids []string) (int64, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	args := []interface{}{key}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return redis.Int64(conn.Do("xdel", args...))
+}
+
+//裁剪stream（xtrim），strategy为maxlen或minid，approx为true时使用~近似裁剪，返回删除的消息数量
+func TrimRedisStream(key, strategy, threshold string, approx bool) (int64, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	args := []interface{}{key, strategy}
+	if approx {
+		args = append(args, "~")
+	}
+	return redis.Int64(conn.Do("xtrim", append(args, threshold)...))
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const syncTTLTolerance = 5000 //对比源和目标的过期时间时允许的误差（毫秒）
+
+//单向同步的参数
+type SyncOptions struct {
+	FromProfile string
+	FromDB      int
+	ToProfile   string
+	ToDB        int
+	Pattern     string
+}
+
+//将源redis中的key复制到目标redis，连接出错后在下次调用时重新连接
+type RedisSyncer struct {
+	options SyncOptions
+	from    redis.Conn
+	to      redis.Conn
+}
+
+func NewRedisSyncer(options SyncOptions) *RedisSyncer {
+	return &RedisSyncer{options: options}
+}
+
+//建立源和目标的连接
+func (s *RedisSyncer) connect() error {
+	if s.from != nil && s.to != nil {
+		return nil
+	}
+	s.Close()
+	from, err := dialRedisEndpoint(s.options.FromProfile, s.options.FromDB)
+	if err != nil {
+		return fmt.Errorf("连接源%s失败：%s", s.options.FromProfile, err.Error())
+	}
+	to, err := dialRedisEndpoint(s.options.ToProfile, s.options.ToDB)
+	if err != nil {
+		from.Close()
+		return fmt.Errorf("连接目标%s失败：%s", s.options.ToProfile, err.Error())
+	}
+	s.from, s.to = from, to
+	return nil
+}
+
+//关闭连接
+func (s *RedisSyncer) Close() {
+	if s.from != nil {
+		s.from.Close()
+		s.from = nil
+	}
+	if s.to != nil {
+		s.to.Close()
+		s.to = nil
+	}
+}
+
+//从cursor开始在源redis中scan一批匹配的key，返回下一个cursor，为"0"时表示扫描结束
+func (s *RedisSyncer) Scan(cursor string) ([]string, string, error) {
+	return s.scan(false, cursor)
+}
+
+//从cursor开始在目标redis中scan一批匹配的key，用于查找源中已不存在的key
+func (s *RedisSyncer) ScanTarget(cursor string) ([]string, string, error) {
+	return s.scan(true, cursor)
+}
+
+//在源或目标redis中scan一批匹配的key
+func (s *RedisSyncer) scan(target bool, cursor string) ([]string, string, error) {
+	if err := s.connect(); err != nil {
+		return nil, cursor, err
+	}
+	conn := s.from
+	if target {
+		conn = s.to
+	}
+	ret, err := redis.Values(conn.Do("scan", cursor, "match", s.options.Pattern, "count", scanBatchCount))
+	if err != nil {
+		s.Close()
+		return nil, cursor, err
+	}
+	next, _ := redis.String(ret[0], nil)
+	keys, _ := redis.Strings(ret[1], nil)
+	return keys, next, nil
+}
+
+//删除目标中源已不存在的key，返回删除的key数量
+func (s *RedisSyncer) DeleteMissing(keys []string) (deleted int, err error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	if err := s.connect(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			s.Close()
+		}
+	}()
+	for _, key := range keys {
+		s.from.Send("exists", key)
+	}
+	if err := s.from.Flush(); err != nil {
+		return 0, err
+	}
+	missing := []interface{}{}
+	for _, key := range keys {
+		count, err := redis.Int(s.from.Receive())
+		if err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+	return redis.Int(s.to.Do("del", missing...))
+}
+
+//对比源和目标中key的dump结果和过期时间，返回不一致的key，源和目标的redis版本不同时dump结果不同，所有key都视为不一致
+func (s *RedisSyncer) Changed(keys []string) (changed []string, err error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			s.Close()
+		}
+	}()
+	fromTTLs, fromDumps, err := dumpKeys(s.from, keys)
+	if err != nil {
+		return nil, err
+	}
+	toTTLs, toDumps, err := dumpKeys(s.to, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		if !bytes.Equal(fromDumps[i], toDumps[i]) || !ttlEqual(fromTTLs[i], toTTLs[i], syncTTLTolerance) {
+			changed = append(changed, key)
+		}
+	}
+	return changed, nil
+}
+
+//通过管道获取key的剩余过期时间（毫秒）和dump结果，key不存在时dump结果为nil
+func dumpKeys(conn redis.Conn, keys []string) ([]int64, [][]byte, error) {
+	for _, key := range keys {
+		conn.Send("pttl", key)
+		conn.Send("dump", key)
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, nil, err
+	}
+	ttls := make([]int64, len(keys))
+	dumps := make([][]byte, len(keys))
+	for i := range keys {
+		var err error
+		if ttls[i], err = redis.Int64(conn.Receive()); err != nil {
+			return nil, nil, err
+		}
+		if dumps[i], err = redis.Bytes(conn.Receive()); err != nil && err != redis.ErrNil {
+			return nil, nil, err
+		}
+	}
+	return ttls, dumps, nil
+}
+
+//通过dump、restore将key复制到目标redis，源中不存在的key在目标中删除，返回复制和删除的key数量，单个key写入失败时只记录日志
+func (s *RedisSyncer) Copy(keys []string) (copied, deleted int, err error) {
+	if len(keys) == 0 {
+		return 0, 0, nil
+	}
+	if err := s.connect(); err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err != nil {
+			s.Close() //连接可能已失效，下次调用时重新连接
+		}
+	}()
+	ttls, dumps, err := dumpKeys(s.from, keys)
+	if err != nil {
+		return 0, 0, err
+	}
+	sentKeys := make([]string, 0, len(keys)) //每条写入命令对应的key
+	for i, key := range keys {
+		if dumps[i] == nil || ttls[i] == -2 {
+			s.to.Send("del", key)
+			sentKeys = append(sentKeys, key)
+			continue
+		}
+		s.to.Send("restore", key, 0, dumps[i], "replace")
+		sentKeys = append(sentKeys, key)
+		if ttls[i] > 0 {
+			s.to.Send("pexpire", key, ttls[i])
+			sentKeys = append(sentKeys, key)
+		}
+	}
+	if err := s.to.Flush(); err != nil {
+		return 0, 0, err
+	}
+	failed := map[string]bool{}
+	for _, key := range sentKeys {
+		if _, err := s.to.Receive(); err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				return 0, 0, err
+			}
+			if !failed[key] {
+				log.Printf("写入%s失败：%s", key, err.Error())
+			}
+			failed[key] = true
+		}
+	}
+	for i, key := range keys {
+		switch {
+		case failed[key]:
+		case dumps[i] == nil || ttls[i] == -2:
+			deleted++
+		default:
+			copied++
+		}
+	}
+	return copied, deleted, nil
+}
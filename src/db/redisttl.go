@@ -0,0 +1,80 @@
+package db
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//对每个key执行同一个命令，通过管道批量发送，返回每个key的执行结果
+func pipelineRedisKeys(cmd string, keys []string, args ...interface{}) ([]interface{}, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	for _, key := range keys {
+		conn.Send(cmd, append([]interface{}{key}, args...)...)
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, 0, len(keys))
+	for range keys {
+		reply, err := conn.Receive()
+		if err != nil {
+			reply = err
+		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
+
+//统计管道执行结果中返回1的数量
+func countSucceedReplies(replies []interface{}) int {
+	count := 0
+	for _, reply := range replies {
+		if ret, err := redis.Int(reply, nil); err == nil && ret == 1 {
+			count++
+		}
+	}
+	return count
+}
+
+//批量设置key的过期时间，返回设置成功的数量
+func ExpireRedisKeys(keys []string, ttl time.Duration) (int, error) {
+	replies, err := pipelineRedisKeys("pexpire", keys, ttl.Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+	return countSucceedReplies(replies), nil
+}
+
+//批量移除key的过期时间，返回移除成功的数量
+func PersistRedisKeys(keys []string) (int, error) {
+	replies, err := pipelineRedisKeys("persist", keys)
+	if err != nil {
+		return 0, err
+	}
+	return countSucceedReplies(replies), nil
+}
+
+//批量获取key的剩余过期时间（毫秒），-1表示没有过期时间，-2表示key不存在
+func TTLRedisKeys(keys []string) ([]int64, error) {
+	replies, err := pipelineRedisKeys("pttl", keys)
+	if err != nil {
+		return nil, err
+	}
+	ttls := make([]int64, 0, len(replies))
+	for _, reply := range replies {
+		ttl, err := redis.Int64(reply, nil)
+		if err != nil {
+			ttl = -2
+		}
+		ttls = append(ttls, ttl)
+	}
+	return ttls, nil
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"rediscmd/src/conf"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//事务因被watch的key已被修改而未执行
+var ErrRedisTxAborted = errors.New("被watch的key已被修改，事务未执行")
+
+//事务，在单独建立的连接上执行watch、multi和exec，不经过连接池的借还
+type RedisTx struct {
+	conn  redis.Conn
+	multi bool //是否已发送multi
+}
+
+//在当前配置和数据库上建立事务使用的连接
+func BeginRedisTx() (*RedisTx, error) {
+	config, err := conf.GetRedisConf()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialRedis(config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Do("select", redisOptionDBId); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &RedisTx{conn: conn}, nil
+}
+
+//监视key，需要在排队命令之前执行，exec时key已被修改则事务不执行
+func (tx *RedisTx) Watch(keys []string) error {
+	if tx.multi {
+		return fmt.Errorf("watch需要在排队命令之前执行")
+	}
+	args := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	_, err := tx.conn.Do("watch", args...)
+	return err
+}
+
+//将命令加入事务队列，第一条命令前发送multi
+func (tx *RedisTx) Queue(cmd string, args ...interface{}) error {
+	if !tx.multi {
+		if _, err := tx.conn.Do("multi"); err != nil {
+			return err
+		}
+		tx.multi = true
+	}
+	_, err := tx.conn.Do(cmd, args...)
+	return err
+}
+
+//执行事务，返回每条命令的结果，命令执行出错时结果为(error)开头的字符串
+func (tx *RedisTx) Exec() ([]interface{}, error) {
+	defer tx.Close()
+	if !tx.multi {
+		return nil, nil
+	}
+	reply, err := tx.conn.Do("exec")
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrRedisTxAborted
+	}
+	results, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range results {
+		results[i] = convertRedisReply(result)
+	}
+	return results, nil
+}
+
+//放弃事务并取消watch
+func (tx *RedisTx) Discard() error {
+	defer tx.Close()
+	if tx.multi {
+		_, err := tx.conn.Do("discard")
+		return err
+	}
+	_, err := tx.conn.Do("unwatch")
+	return err
+}
+
+//关闭事务的连接
+func (tx *RedisTx) Close() {
+	tx.conn.Close()
+}
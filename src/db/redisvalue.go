@@ -0,0 +1,199 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"rediscmd/src/model"
+	"rediscmd/src/rdb"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//编辑期间key被其他客户端修改
+var ErrKeyModified = errors.New("key在编辑期间已被修改，本次修改未写入")
+
+//按数据类型获取key的值，limit大于0时集合类型最多获取limit个元素
+func GetRedisKeyValue(key string, limit int) (*model.RedisValue, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return readRedisValue(conn, key, limit)
+}
+
+func readRedisValue(conn redis.Conn, key string, limit int) (*model.RedisValue, error) {
+	keyType, err := redis.String(conn.Do("type", key))
+	if err != nil {
+		return nil, err
+	}
+	end := limit - 1 //lrange、zrange的结束下标，limit为0时为-1即全部
+	value := &model.RedisValue{Key: key, Type: keyType}
+	switch keyType {
+	case "none":
+		return nil, fmt.Errorf("key(%s)不存在", key)
+	case "string":
+		value.Value, err = redis.String(conn.Do("get", key))
+	case "list":
+		value.Value, err = redis.Strings(conn.Do("lrange", key, 0, end))
+	case "set":
+		var members []string
+		if limit > 0 {
+			members, err = scanFirstPage(conn, "sscan", key, limit)
+		} else {
+			members, err = redis.Strings(conn.Do("smembers", key))
+		}
+		sort.Strings(members)
+		value.Value = members
+	case "hash":
+		if limit > 0 {
+			value.Value, err = readKVs(scanFirstPage(conn, "hscan", key, limit*2))
+		} else {
+			value.Value, err = readKVs(conn.Do("hgetall", key))
+		}
+	case "zset":
+		value.Value, err = readKVs(conn.Do("zrange", key, 0, end, "withscores"))
+	case "stream":
+		args := []interface{}{key, "-", "+"}
+		if limit > 0 {
+			args = append(args, "count", limit)
+		}
+		value.Value, err = readStreamEntries(conn.Do("xrange", args...))
+	default:
+		return nil, fmt.Errorf("不支持%s类型的key", keyType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := redis.Int64(conn.Do("pttl", key))
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		value.ExpireAt = time.Now().UnixNano()/int64(time.Millisecond) + ttl
+	}
+	return value, nil
+}
+
+//通过sscan、hscan读取集合的前limit个元素（hash的字段和值各算一个元素）
+func scanFirstPage(conn redis.Conn, cmd, key string, limit int) ([]string, error) {
+	items, cursor := []string{}, "0"
+	for len(items) < limit {
+		ret, err := redis.Values(conn.Do(cmd, key, cursor, "count", limit))
+		if err != nil {
+			return nil, err
+		}
+		cursor, _ = redis.String(ret[0], nil)
+		page, _ := redis.Strings(ret[1], nil)
+		items = append(items, page...)
+		if cursor == "0" {
+			break
+		}
+	}
+	return items, nil
+}
+
+//将xrange的返回值转换为stream消息列表
+func readStreamEntries(reply interface{}, err error) ([]rdb.StreamEntry, error) {
+	entries, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	streamEntries := make([]rdb.StreamEntry, 0, len(entries))
+	for _, entry := range entries {
+		fields, err := redis.Values(entry, nil)
+		if err != nil || len(fields) != 2 {
+			continue
+		}
+		id, _ := redis.String(fields[0], nil)
+		kvs, _ := readKVs(fields[1], nil)
+		streamEntries = append(streamEntries, rdb.StreamEntry{ID: id, Fields: kvs})
+	}
+	return streamEntries, nil
+}
+
+//将hgetall、zrange withscores的返回值转换为键值列表
+func readKVs(reply interface{}, err error) ([]model.KV, error) {
+	items, err := redis.Strings(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	kvs := make([]model.KV, 0, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		kvs = append(kvs, model.KV{Key: items[i], Value: items[i+1]})
+	}
+	return kvs, nil
+}
+
+//通过watch读取key的值交给edit修改，再通过multi/exec写回，edit返回nil表示未修改
+func EditRedisKey(key string, edit func(value *model.RedisValue) (*model.RedisValue, error)) error {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.Do("watch", key); err != nil {
+		return err
+	}
+	value, err := readRedisValue(conn, key, 0)
+	if err == nil {
+		value, err = edit(value)
+	}
+	if err != nil || value == nil {
+		conn.Do("unwatch")
+		return err
+	}
+	entry := rdb.Entry{Key: key, Type: value.Type, Value: value.Value, ExpireAt: value.ExpireAt}
+	commands, err := entry.Commands()
+	if err != nil {
+		conn.Do("unwatch")
+		return err
+	}
+	conn.Send("multi")
+	if value.Type == "string" {
+		conn.Send("del", key) //字符串类型的命令中不包含del，需要清除原有的过期时间
+	}
+	for _, command := range commands {
+		args := make([]interface{}, 0, len(command)-1)
+		for _, arg := range command[1:] {
+			args = append(args, arg)
+		}
+		conn.Send(command[0], args...)
+	}
+	replies, err := redis.Values(conn.Do("exec"))
+	if err == redis.ErrNil {
+		return ErrKeyModified
+	}
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if err, ok := reply.(redis.Error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+//检查key是否存在
+func ExistsRedisKey(key string) (bool, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	return redis.Bool(conn.Do("exists", key))
+}
+
+//获取字符串类型key的值的字节数，key不是字符串类型时返回错误
+func GetRedisValueSize(key string) (int64, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return redis.Int64(conn.Do("strlen", key))
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"hash/fnv"
+
+	"rediscmd/src/model"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//扫描匹配的key并通过dump计算值的摘要，用于轮询方式检测key的变化，maxKeys大于0时最多扫描maxKeys个key
+func SnapshotRedisKeys(pattern string, filter *model.KeyFilter, maxKeys int) (map[string]uint64, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	snapshot := map[string]uint64{}
+	var snapshotErr error
+	scanRedisKeys(conn, pattern, filter, func(keys []string) bool {
+		for _, key := range keys {
+			conn.Send("dump", key)
+		}
+		if snapshotErr = conn.Flush(); snapshotErr != nil {
+			return false
+		}
+		for _, key := range keys {
+			value, err := redis.Bytes(conn.Receive())
+			if err == redis.ErrNil {
+				continue //扫描过程中key已被删除
+			}
+			if err != nil {
+				snapshotErr = err
+				return false
+			}
+			h := fnv.New64a()
+			h.Write(value)
+			snapshot[key] = h.Sum64()
+		}
+		return maxKeys <= 0 || len(snapshot) < maxKeys
+	})
+	return snapshot, snapshotErr
+}
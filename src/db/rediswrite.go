@@ -0,0 +1,42 @@
+package db
+
+import (
+	"github.com/garyburd/redigo/redis"
+)
+
+//通过管道分批执行写命令，head为key和选项，items每批最多batch项，返回每批的结果，命令出错的批次结果为(error)开头的字符串
+func WriteRedisItems(cmd string, head, items []interface{}, batch int) ([]interface{}, error) {
+	conn, err := createRedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if batch <= 0 || batch > len(items) {
+		batch = len(items)
+	}
+	count := 0
+	for start := 0; start < len(items) || count == 0; start += batch {
+		end := start + batch
+		if end > len(items) {
+			end = len(items)
+		}
+		args := append(append([]interface{}{}, head...), items[start:end]...)
+		conn.Send(cmd, args...)
+		count++
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		reply, err := conn.Receive()
+		if err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				return results, err
+			}
+			reply = err //某一批次出错时其他批次可能已写入，记录错误继续接收
+		}
+		results = append(results, convertRedisReply(reply))
+	}
+	return results, nil
+}
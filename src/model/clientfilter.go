@@ -0,0 +1,14 @@
+package model
+
+//客户端连接的过滤条件，地址和名称为glob模式
+type ClientFilter struct {
+	Addr string
+	Name string
+	Idle *NumCond //空闲时长（秒）
+	DB   *NumCond
+}
+
+//是否没有任何过滤条件
+func (f *ClientFilter) IsEmpty() bool {
+	return f == nil || (f.Addr == "" && f.Name == "" && f.Idle == nil && f.DB == nil)
+}
@@ -0,0 +1,9 @@
+package model
+
+//值搜索的匹配结果
+type GrepMatch struct {
+	Key      string `json:"key"`
+	Type     string `json:"type"`
+	Location string `json:"location"` //匹配的位置，例如字段、下标、成员
+	Snippet  string `json:"snippet"`  //匹配内容的片段
+}
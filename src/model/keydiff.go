@@ -0,0 +1,10 @@
+package model
+
+//两个keyspace中同一个key的差异
+type KeyDiff struct {
+	Key   string     `json:"key"`
+	Kind  string     `json:"kind"`  //仅左侧、仅右侧、类型不同、值不同、TTL不同
+	Left  string     `json:"left"`  //左侧的类型或过期时间
+	Right string     `json:"right"` //右侧的类型或过期时间
+	Patch [][]string `json:"-"`     //使右侧与左侧一致的命令
+}
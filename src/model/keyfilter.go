@@ -0,0 +1,38 @@
+package model
+
+//数值比较条件
+type NumCond struct {
+	Op    string //比较符：< <= > >= =
+	Value int64
+}
+
+//判断数值是否满足比较条件
+func (c *NumCond) Match(v int64) bool {
+	switch c.Op {
+	case "<":
+		return v < c.Value
+	case "<=":
+		return v <= c.Value
+	case ">":
+		return v > c.Value
+	case ">=":
+		return v >= c.Value
+	default:
+		return v == c.Value
+	}
+}
+
+//缓存key的过滤条件
+type KeyFilter struct {
+	Type     string   //数据类型
+	TTL      *NumCond //剩余过期时间（毫秒），仅匹配设置了过期时间的key
+	NoTTL    bool     //仅匹配没有过期时间的key
+	Idle     *NumCond //空闲时间（秒）
+	Mem      *NumCond //内存占用（字节）
+	Encoding string   //内部编码
+}
+
+//是否没有任何过滤条件
+func (f *KeyFilter) IsEmpty() bool {
+	return f == nil || (f.Type == "" && f.TTL == nil && !f.NoTTL && f.Idle == nil && f.Mem == nil && f.Encoding == "")
+}
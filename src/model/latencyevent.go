@@ -0,0 +1,9 @@
+package model
+
+//延迟监控的事件
+type LatencyEvent struct {
+	Event  string
+	Time   int64 //最近一次超过阈值的时间（unix秒）
+	Latest int64 //最近一次的延迟（毫秒）
+	Max    int64 //最大延迟（毫秒）
+}
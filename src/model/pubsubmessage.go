@@ -0,0 +1,8 @@
+package model
+
+//订阅收到的消息
+type PubSubMessage struct {
+	Pattern string `json:"pattern,omitempty"` //模式订阅时匹配的模式
+	Channel string `json:"channel"`
+	Data    string `json:"data"`
+}
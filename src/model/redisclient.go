@@ -0,0 +1,14 @@
+package model
+
+//client list中的一个客户端连接
+type RedisClient struct {
+	ID    int64
+	Addr  string //客户端地址，ip:port
+	Name  string
+	Age   int64 //连接时长（秒）
+	Idle  int64 //空闲时长（秒）
+	DB    int
+	Flags string
+	Cmd   string //最近执行的命令
+	User  string
+}
@@ -8,5 +8,9 @@ type RedisConf struct {
 		Password   string
 		MaxConnect int    //连接池中允许最大的连接数
 		KeyPrefix  string //缓存key的前缀字符
+		ReadOnly   bool   //只读配置，禁止执行修改、删除缓存的命令
+	}
+	Group map[string]*struct {
+		Profiles string //配置组包含的配置名称，多个以,分隔
 	}
 }
@@ -0,0 +1,10 @@
+package model
+
+//redis7中通过function load加载的函数
+type RedisFunction struct {
+	Library     string `json:"library"`
+	Engine      string `json:"engine"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Flags       string `json:"flags"` //多个以,分隔，例如no-writes
+}
@@ -0,0 +1,7 @@
+package model
+
+//info命令返回的一个分组，例如memory、stats
+type RedisInfoSection struct {
+	Name  string
+	Items []KV
+}
@@ -0,0 +1,10 @@
+package model
+
+//缓存key的统计信息
+type RedisKeyInfo struct {
+	Key      string `json:"key"`
+	Type     string `json:"type"`
+	Memory   int64  `json:"memory"`   //内存占用（字节）
+	Elements int64  `json:"elements"` //元素数量，字符串类型为值的长度
+	TTL      int64  `json:"ttl"`      //剩余过期时间（毫秒），-1表示没有过期时间
+}
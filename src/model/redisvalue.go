@@ -0,0 +1,9 @@
+package model
+
+//缓存key的值
+type RedisValue struct {
+	Key      string
+	Type     string      //数据类型：string hash list set zset stream
+	Value    interface{} //string、[]string（list/set）、[]KV（hash、zset的成员和分数）、[]rdb.StreamEntry
+	ExpireAt int64       //过期时间戳（毫秒），0表示没有过期时间
+}
@@ -0,0 +1,11 @@
+package model
+
+//慢查询日志
+type SlowlogEntry struct {
+	ID         int64
+	Time       int64 //执行时间（unix秒）
+	Duration   int64 //耗时（微秒）
+	Args       []string
+	Client     string //客户端地址，redis4.0以下为空
+	ClientName string
+}
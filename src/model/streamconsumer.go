@@ -0,0 +1,8 @@
+package model
+
+//消费组中的消费者信息
+type StreamConsumer struct {
+	Name    string `json:"name"`
+	Pending int64  `json:"pending"` //已投递未确认的消息数量
+	Idle    int64  `json:"idle"`    //空闲时间（毫秒）
+}
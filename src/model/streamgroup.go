@@ -0,0 +1,10 @@
+package model
+
+//stream的消费组信息
+type StreamGroup struct {
+	Name            string `json:"name"`
+	Consumers       int64  `json:"consumers"`
+	Pending         int64  `json:"pending"`           //已投递未确认的消息数量
+	LastDeliveredID string `json:"last_delivered_id"` //最后投递的消息id
+	Lag             string `json:"lag"`               //未投递的消息数量，redis7.0以下或无法计算时为空
+}
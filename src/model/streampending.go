@@ -0,0 +1,9 @@
+package model
+
+//消费组中已投递未确认的消息
+type StreamPending struct {
+	ID         string `json:"id"`
+	Consumer   string `json:"consumer"`
+	Idle       int64  `json:"idle"`       //距离最后一次投递的时间（毫秒）
+	Deliveries int64  `json:"deliveries"` //投递次数
+}
@@ -0,0 +1,217 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+//长度的特殊编码
+const (
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+)
+
+//读取长度，encoded为true时表示返回的是字符串的特殊编码类型
+func (p *Parser) readLength() (length uint64, encoded bool, err error) {
+	b, err := p.readByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3f), false, nil
+	case 1:
+		next, err := p.readByte()
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(b&0x3f)<<8 | uint64(next), false, nil
+	case 2:
+		switch b {
+		case 0x80:
+			buf, err := p.readN(4)
+			if err != nil {
+				return 0, false, err
+			}
+			return uint64(binary.BigEndian.Uint32(buf)), false, nil
+		case 0x81:
+			buf, err := p.readN(8)
+			if err != nil {
+				return 0, false, err
+			}
+			return binary.BigEndian.Uint64(buf), false, nil
+		default:
+			return 0, false, fmt.Errorf("无法识别的长度编码0x%x", b)
+		}
+	default:
+		return uint64(b & 0x3f), true, nil
+	}
+}
+
+//读取长度，不允许特殊编码
+func (p *Parser) readLen() (int, error) {
+	length, encoded, err := p.readLength()
+	if err != nil {
+		return 0, err
+	}
+	if encoded {
+		return 0, errors.New("此处的长度不允许使用特殊编码")
+	}
+	return int(length), nil
+}
+
+//读取元素数量，每个元素至少占用一个字节，超过剩余字节数时文件已损坏
+func (p *Parser) readCount() (int, error) {
+	count, err := p.readLen()
+	if err != nil {
+		return 0, err
+	}
+	if count < 0 || int64(count) > p.remaining() {
+		return 0, errCorrupted
+	}
+	return count, nil
+}
+
+//读取字符串，isInt为true时表示字符串是以整数编码存储的
+func (p *Parser) readStringEx() (str []byte, isInt bool, err error) {
+	length, encoded, err := p.readLength()
+	if err != nil {
+		return nil, false, err
+	}
+	if !encoded {
+		str, err = p.readN(int(length))
+		return str, false, err
+	}
+	switch length {
+	case encInt8:
+		buf, err := p.readN(1)
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(strconv.Itoa(int(int8(buf[0])))), true, nil
+	case encInt16:
+		buf, err := p.readN(2)
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(strconv.Itoa(int(int16(binary.LittleEndian.Uint16(buf))))), true, nil
+	case encInt32:
+		buf, err := p.readN(4)
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(strconv.Itoa(int(int32(binary.LittleEndian.Uint32(buf))))), true, nil
+	case encLZF:
+		compressedLen, err := p.readCount()
+		if err != nil {
+			return nil, false, err
+		}
+		rawLen, err := p.readLen()
+		if err != nil {
+			return nil, false, err
+		}
+		compressed, err := p.readN(compressedLen)
+		if err != nil {
+			return nil, false, err
+		}
+		str, err = lzfDecompress(compressed, rawLen)
+		return str, false, err
+	default:
+		return nil, false, fmt.Errorf("无法识别的字符串编码%d", length)
+	}
+}
+
+//读取字符串
+func (p *Parser) readString() ([]byte, error) {
+	str, _, err := p.readStringEx()
+	return str, err
+}
+
+//读取旧版本zset中以字符串保存的分数
+func (p *Parser) readDouble() (string, error) {
+	length, err := p.readByte()
+	if err != nil {
+		return "", err
+	}
+	switch length {
+	case 253:
+		return "nan", nil
+	case 254:
+		return "inf", nil
+	case 255:
+		return "-inf", nil
+	}
+	buf, err := p.readN(int(length))
+	return string(buf), err
+}
+
+//读取以8字节二进制保存的分数
+func (p *Parser) readBinaryDouble() (string, error) {
+	buf, err := p.readN(8)
+	if err != nil {
+		return "", err
+	}
+	return formatScore(math.Float64frombits(binary.LittleEndian.Uint64(buf))), nil
+}
+
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(score, 'g', -1, 64)
+}
+
+//lzf每3个字节的回溯引用最多还原264个字节，解压后的长度不会超过压缩长度的88倍
+const lzfMaxRatio = 88
+
+//lzf解压缩
+func lzfDecompress(in []byte, outLen int) ([]byte, error) {
+	if outLen < 0 || outLen > len(in)*lzfMaxRatio || outLen > maxLength {
+		return nil, errCorrupted
+	}
+	out := make([]byte, 0, outLen)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < 32 { //字面量
+			n := ctrl + 1
+			if i+n > len(in) {
+				return nil, errors.New("lzf数据已损坏")
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+		length := ctrl >> 5 //回溯引用
+		if length == 7 {
+			if i >= len(in) {
+				return nil, errors.New("lzf数据已损坏")
+			}
+			length += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, errors.New("lzf数据已损坏")
+		}
+		ref := len(out) - ((ctrl & 0x1f) << 8) - int(in[i]) - 1
+		i++
+		if ref < 0 {
+			return nil, errors.New("lzf数据已损坏")
+		}
+		for j := 0; j < length+2; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+	if len(out) != outLen {
+		return nil, fmt.Errorf("lzf解压后的长度%d与预期长度%d不一致", len(out), outLen)
+	}
+	return out, nil
+}
@@ -0,0 +1,84 @@
+package rdb
+
+import (
+	"fmt"
+	"strconv"
+
+	"rediscmd/src/model"
+)
+
+//rdb文件中的一个缓存key
+type Entry struct {
+	DB       int         `json:"db"`
+	Key      string      `json:"key"`
+	Type     string      `json:"type"`      //数据类型：string list set zset hash stream module
+	Encoding string      `json:"encoding"`  //在rdb文件中的编码
+	ExpireAt int64       `json:"expire_at"` //过期时间戳（毫秒），0表示没有过期时间
+	Idle     int64       `json:"-"`         //空闲时间（秒），-1表示rdb文件中没有记录
+	Elements int64       `json:"elements"`  //元素数量，字符串类型为值的长度
+	Size     int64       `json:"size"`      //值在rdb文件中占用的字节数
+	Value    interface{} `json:"value"`     //string、[]string（list/set）、[]model.KV（hash、zset的成员和分数）、[]StreamEntry
+}
+
+//stream中的一条消息
+type StreamEntry struct {
+	ID     string     `json:"id"`
+	Fields []model.KV `json:"fields"`
+}
+
+const commandBatchCount = 1000 //集合类型的每条命令包含的最大元素数量
+
+//将key转换为可以重建此key的redis命令
+func (e *Entry) Commands() ([][]string, error) {
+	args := [][]string{}
+	batch := func(cmd string, items []string, step int) {
+		for i := 0; i < len(items); i += commandBatchCount * step {
+			end := i + commandBatchCount*step
+			if end > len(items) {
+				end = len(items)
+			}
+			args = append(args, append([]string{cmd, e.Key}, items[i:end]...))
+		}
+	}
+	if e.Type != "string" {
+		args = append(args, []string{"del", e.Key})
+	}
+	switch value := e.Value.(type) {
+	case string:
+		args = append(args, []string{"set", e.Key, value})
+	case []string:
+		cmd := "rpush"
+		if e.Type == "set" {
+			cmd = "sadd"
+		}
+		batch(cmd, value, 1)
+	case []model.KV:
+		items := make([]string, 0, len(value)*2)
+		for _, kv := range value {
+			if e.Type == "zset" {
+				items = append(items, kv.Value, kv.Key)
+			} else {
+				items = append(items, kv.Key, kv.Value)
+			}
+		}
+		if e.Type == "zset" {
+			batch("zadd", items, 2)
+		} else {
+			batch("hset", items, 2)
+		}
+	case []StreamEntry:
+		for _, streamEntry := range value {
+			item := []string{"xadd", e.Key, streamEntry.ID}
+			for _, kv := range streamEntry.Fields {
+				item = append(item, kv.Key, kv.Value)
+			}
+			args = append(args, item)
+		}
+	default:
+		return nil, fmt.Errorf("key(%s)的类型%s无法转换为命令", e.Key, e.Type)
+	}
+	if e.ExpireAt != 0 {
+		args = append(args, []string{"pexpireat", e.Key, strconv.FormatInt(e.ExpireAt, 10)})
+	}
+	return args, nil
+}
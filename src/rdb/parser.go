@@ -0,0 +1,480 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"rediscmd/src/model"
+)
+
+const maxVersion = 12 //支持的rdb文件最高版本
+
+//输入大小未知时字符串长度和元素数量的上限，与redis的proto-max-bulk-len默认值相同
+const maxLength = 512 << 20
+
+//预先分配的元素数量上限，文件损坏时数量不可信，超过的部分按实际读取逐步分配
+const maxPrealloc = 1024
+
+//rdb文件中的操作码
+const (
+	opSlotInfo     = 0xf4
+	opFunction2    = 0xf5
+	opFunction     = 0xf6
+	opModuleAux    = 0xf7
+	opIdle         = 0xf8
+	opFreq         = 0xf9
+	opAux          = 0xfa
+	opResizeDB     = 0xfb
+	opExpireTimeMs = 0xfc
+	opExpireTime   = 0xfd
+	opSelectDB     = 0xfe
+	opEOF          = 0xff
+)
+
+//rdb文件中的值类型
+const (
+	typeString           = 0
+	typeList             = 1
+	typeSet              = 2
+	typeZSet             = 3
+	typeHash             = 4
+	typeZSet2            = 5
+	typeModule           = 6
+	typeModule2          = 7
+	typeHashZipmap       = 9
+	typeListZiplist      = 10
+	typeSetIntset        = 11
+	typeZSetZiplist      = 12
+	typeHashZiplist      = 13
+	typeListQuicklist    = 14
+	typeStreamListpacks  = 15
+	typeHashListpack     = 16
+	typeZSetListpack     = 17
+	typeListQuicklist2   = 18
+	typeStreamListpacks2 = 19
+	typeSetListpack      = 20
+	typeStreamListpacks3 = 21
+)
+
+//rdb文件解析器
+type Parser struct {
+	reader  *bufio.Reader
+	offset  int64
+	size    int64             //输入的总字节数，未知时为0
+	Version int               //rdb文件版本
+	Aux     map[string]string //rdb文件中的辅助信息，例如redis-ver、ctime
+}
+
+//创建rdb解析器，传入*bufio.Reader时直接使用，解析结束后可继续读取rdb之后的内容（例如aof的rdb前缀）
+func NewParser(reader io.Reader) *Parser {
+	var size int64
+	if f, ok := reader.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+			size = info.Size()
+		}
+	}
+	bufReader, ok := reader.(*bufio.Reader)
+	if !ok {
+		bufReader = bufio.NewReaderSize(reader, 64*1024)
+	}
+	return &Parser{reader: bufReader, size: size, Aux: map[string]string{}}
+}
+
+//解析rdb文件，每解析出一个key就交给handle处理，handle返回false时停止解析
+func ParseFile(filePath string, handle func(entry *Entry) bool) (*Parser, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	parser := NewParser(f)
+	return parser, parser.Parse(handle)
+}
+
+//解析rdb数据，每解析出一个key就交给handle处理，handle返回false时停止解析
+func (p *Parser) Parse(handle func(entry *Entry) bool) error {
+	header, err := p.readN(9)
+	if err != nil {
+		return fmt.Errorf("读取rdb文件头失败：%s", err.Error())
+	}
+	if string(header[:5]) != "REDIS" {
+		return fmt.Errorf("不是有效的rdb文件")
+	}
+	p.Version, err = strconv.Atoi(string(header[5:]))
+	if err != nil || p.Version < 1 || p.Version > maxVersion {
+		return fmt.Errorf("不支持的rdb文件版本%s", string(header[5:]))
+	}
+	dbid := 0
+	var expireAt int64
+	var idle int64 = -1
+	for {
+		opcode, err := p.readByte()
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case opEOF:
+			if p.Version >= 5 {
+				_, err = p.readN(8) //跳过末尾的校验和
+			}
+			return err
+		case opSelectDB:
+			if dbid, err = p.readLen(); err != nil {
+				return err
+			}
+		case opResizeDB:
+			if err = p.skipLengths(2); err != nil {
+				return err
+			}
+		case opSlotInfo:
+			if err = p.skipLengths(3); err != nil {
+				return err
+			}
+		case opExpireTime:
+			buf, err := p.readN(4)
+			if err != nil {
+				return err
+			}
+			expireAt = int64(binary.LittleEndian.Uint32(buf)) * 1000
+		case opExpireTimeMs:
+			buf, err := p.readN(8)
+			if err != nil {
+				return err
+			}
+			expireAt = int64(binary.LittleEndian.Uint64(buf))
+		case opAux:
+			key, err := p.readString()
+			if err != nil {
+				return err
+			}
+			value, err := p.readString()
+			if err != nil {
+				return err
+			}
+			p.Aux[string(key)] = string(value)
+		case opFreq:
+			if _, err = p.readByte(); err != nil {
+				return err
+			}
+		case opIdle:
+			length, err := p.readLen()
+			if err != nil {
+				return err
+			}
+			idle = int64(length)
+		case opModuleAux:
+			if err = p.skipLengths(3); err != nil { //模块id、when_opcode、when
+				return err
+			}
+			if err = p.skipModuleValue(); err != nil {
+				return err
+			}
+		case opFunction2:
+			if _, err = p.readString(); err != nil {
+				return err
+			}
+		case opFunction:
+			return fmt.Errorf("不支持redis7.0预览版的函数格式")
+		default:
+			key, err := p.readString()
+			if err != nil {
+				return err
+			}
+			entry := &Entry{DB: dbid, Key: string(key), ExpireAt: expireAt, Idle: idle}
+			if err := p.readObject(opcode, entry); err != nil {
+				return fmt.Errorf("解析key(%s)失败：%s", entry.Key, err.Error())
+			}
+			expireAt, idle = 0, -1
+			if !handle(entry) {
+				return nil
+			}
+		}
+	}
+}
+
+//读取一个值
+func (p *Parser) readObject(valueType byte, entry *Entry) error {
+	start := p.offset
+	var err error
+	switch valueType {
+	case typeString:
+		var str []byte
+		var isInt bool
+		str, isInt, err = p.readStringEx()
+		entry.Type, entry.Encoding, entry.Value = "string", "raw", string(str)
+		if isInt {
+			entry.Encoding = "int"
+		}
+		entry.Elements = int64(len(str))
+	case typeList, typeSet:
+		entry.Type, entry.Encoding = "list", "linkedlist"
+		if valueType == typeSet {
+			entry.Type, entry.Encoding = "set", "hashtable"
+		}
+		entry.Value, err = p.readStrings()
+	case typeHash:
+		entry.Type, entry.Encoding = "hash", "hashtable"
+		entry.Value, err = p.readPairs(nil)
+	case typeZSet, typeZSet2:
+		entry.Type, entry.Encoding = "zset", "skiplist"
+		readScore := p.readBinaryDouble
+		if valueType == typeZSet {
+			readScore = p.readDouble
+		}
+		entry.Value, err = p.readPairs(readScore)
+	case typeModule2:
+		entry.Type = "module"
+		var moduleID uint64
+		if moduleID, _, err = p.readLength(); err == nil {
+			entry.Encoding = moduleName(moduleID)
+			err = p.skipModuleValue()
+		}
+	case typeModule:
+		return fmt.Errorf("不支持旧版本的模块数据格式")
+	case typeHashZipmap, typeHashZiplist, typeHashListpack:
+		entry.Type = "hash"
+		entry.Encoding, entry.Value, err = p.readPacked(valueType, true)
+	case typeZSetZiplist, typeZSetListpack:
+		entry.Type = "zset"
+		entry.Encoding, entry.Value, err = p.readPacked(valueType, true)
+	case typeListZiplist, typeSetIntset, typeSetListpack:
+		entry.Type = "list"
+		if valueType != typeListZiplist {
+			entry.Type = "set"
+		}
+		entry.Encoding, entry.Value, err = p.readPacked(valueType, false)
+	case typeListQuicklist, typeListQuicklist2:
+		entry.Type, entry.Encoding = "list", "quicklist"
+		entry.Value, err = p.readQuicklist(valueType == typeListQuicklist2)
+	case typeStreamListpacks, typeStreamListpacks2, typeStreamListpacks3:
+		entry.Type, entry.Encoding = "stream", "stream"
+		entry.Value, err = p.readStream(valueType)
+	default:
+		return fmt.Errorf("不支持的数据类型%d", valueType)
+	}
+	if err != nil {
+		return err
+	}
+	switch value := entry.Value.(type) {
+	case []string:
+		entry.Elements = int64(len(value))
+	case []model.KV:
+		entry.Elements = int64(len(value))
+	case []StreamEntry:
+		entry.Elements = int64(len(value))
+	}
+	entry.Size = p.offset - start
+	return nil
+}
+
+//读取字符串列表
+func (p *Parser) readStrings() ([]string, error) {
+	count, err := p.readCount()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]string, 0, preallocSize(count))
+	for i := 0; i < count; i++ {
+		item, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, string(item))
+	}
+	return items, nil
+}
+
+//读取成对的字段和值，readValue为空时值也按字符串读取
+func (p *Parser) readPairs(readValue func() (string, error)) ([]model.KV, error) {
+	count, err := p.readCount()
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]model.KV, 0, preallocSize(count))
+	for i := 0; i < count; i++ {
+		key, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		var value string
+		if readValue != nil {
+			value, err = readValue()
+		} else {
+			var buf []byte
+			buf, err = p.readString()
+			value = string(buf)
+		}
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, model.KV{Key: string(key), Value: value})
+	}
+	return pairs, nil
+}
+
+//读取以ziplist、listpack、intset、zipmap压缩保存的值
+func (p *Parser) readPacked(valueType byte, isPairs bool) (string, interface{}, error) {
+	buf, err := p.readString()
+	if err != nil {
+		return "", nil, err
+	}
+	var encoding string
+	var items []string
+	switch valueType {
+	case typeHashZipmap:
+		encoding = "zipmap"
+		items, err = parseZipmap(buf)
+	case typeSetIntset:
+		encoding = "intset"
+		items, err = parseIntset(buf)
+	case typeHashListpack, typeZSetListpack, typeSetListpack:
+		encoding = "listpack"
+		items, err = parseListpack(buf)
+	default:
+		encoding = "ziplist"
+		items, err = parseZiplist(buf)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if !isPairs {
+		return encoding, items, nil
+	}
+	if len(items)%2 != 0 {
+		return "", nil, errCorrupted
+	}
+	pairs := make([]model.KV, 0, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		pairs = append(pairs, model.KV{Key: items[i], Value: items[i+1]})
+	}
+	return encoding, pairs, nil
+}
+
+//读取quicklist，isV2为true时每个节点带有容器类型
+func (p *Parser) readQuicklist(isV2 bool) ([]string, error) {
+	count, err := p.readCount()
+	if err != nil {
+		return nil, err
+	}
+	items := []string{}
+	for i := 0; i < count; i++ {
+		container := 2 //1:普通节点 2:压缩节点
+		if isV2 {
+			if container, err = p.readLen(); err != nil {
+				return nil, err
+			}
+		}
+		buf, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		if container == 1 {
+			items = append(items, string(buf))
+			continue
+		}
+		var nodeItems []string
+		if isV2 {
+			nodeItems, err = parseListpack(buf)
+		} else {
+			nodeItems, err = parseZiplist(buf)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, nodeItems...)
+	}
+	return items, nil
+}
+
+//跳过模块序列化的值
+func (p *Parser) skipModuleValue() error {
+	for {
+		opcode, err := p.readLen()
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case 0: //结束
+			return nil
+		case 1, 2: //有符号、无符号整数
+			_, _, err = p.readLength()
+		case 3: //float
+			_, err = p.readN(4)
+		case 4: //double
+			_, err = p.readN(8)
+		case 5: //字符串
+			_, err = p.readString()
+		default:
+			return fmt.Errorf("无法识别的模块数据操作码%d", opcode)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+//根据模块id还原模块名称
+func moduleName(moduleID uint64) string {
+	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	name := make([]byte, 9)
+	for i := range name {
+		name[i] = charset[(moduleID>>(64-6*uint(i+1)))&63]
+	}
+	return string(name)
+}
+
+func (p *Parser) skipLengths(n int) error {
+	for i := 0; i < n; i++ {
+		if _, _, err := p.readLength(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Parser) readByte() (byte, error) {
+	b, err := p.reader.ReadByte()
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	p.offset++
+	return b, nil
+}
+
+func (p *Parser) readN(n int) ([]byte, error) {
+	if n < 0 || int64(n) > p.remaining() {
+		return nil, errCorrupted
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(p.reader, buf); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	p.offset += int64(n)
+	return buf, nil
+}
+
+//剩余可读取的字节数，输入大小未知时返回maxLength
+func (p *Parser) remaining() int64 {
+	if p.size > 0 {
+		return p.size - p.offset
+	}
+	return maxLength
+}
+
+func preallocSize(count int) int {
+	if count > maxPrealloc {
+		return maxPrealloc
+	}
+	return count
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
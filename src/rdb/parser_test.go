@@ -0,0 +1,247 @@
+package rdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseZiplist(t *testing.T) {
+	header := make([]byte, 10)
+	tests := []struct {
+		name    string
+		entries []byte
+		want    []string
+		wantErr bool
+	}{
+		{"空", []byte{0xff}, []string{}, false},
+		{"字符串", []byte{0x00, 0x02, 'a', 'b', 0xff}, []string{"ab"}, false},
+		{"14位长度字符串", append(append([]byte{0x00, 0x40, 0x03}, "abc"...), 0xff), []string{"abc"}, false},
+		{"立即数", []byte{0x00, 0xf1, 0x02, 0xfd, 0xff}, []string{"0", "12"}, false},
+		{"int8", []byte{0x00, 0xfe, 0x80, 0xff}, []string{"-128"}, false},
+		{"int16", []byte{0x00, 0xc0, 0x34, 0x12, 0xff}, []string{"4660"}, false},
+		{"int24", []byte{0x00, 0xf0, 0xff, 0xff, 0xff, 0xff}, []string{"-1"}, false},
+		{"int32", []byte{0x00, 0xd0, 0x00, 0x00, 0x00, 0x80, 0xff}, []string{"-2147483648"}, false},
+		{"5字节的前一元素长度", []byte{0xfe, 0, 0, 0, 0, 0x01, 'x', 0xff}, []string{"x"}, false},
+		{"缺少结束标记", []byte{0x00, 0x01, 'a'}, nil, true},
+		{"字符串长度超出", []byte{0x00, 0x05, 'a', 0xff}, nil, true},
+		{"32位长度超出", []byte{0x00, 0x80, 0x7f, 0xff, 0xff, 0xff, 0xff}, nil, true},
+		{"无法识别的编码", []byte{0x00, 0xc1, 0xff}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseZiplist(append(append([]byte{}, header...), tt.entries...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListpack(t *testing.T) {
+	header := make([]byte, 6)
+	tests := []struct {
+		name    string
+		entries []byte
+		want    []string
+		wantErr bool
+	}{
+		{"空", []byte{0xff}, []string{}, false},
+		{"7位整数", []byte{0x05, 0x01, 0x7f, 0x01, 0xff}, []string{"5", "127"}, false},
+		{"6位长度字符串", []byte{0x82, 'h', 'i', 0x03, 0xff}, []string{"hi"}, false},
+		{"13位负整数", []byte{0xdf, 0xff, 0x02, 0xff}, []string{"-1"}, false},
+		{"12位长度字符串", append(append([]byte{0xe0, 0x03}, "abc"...), 0x05, 0xff), []string{"abc"}, false},
+		{"int16", []byte{0xf1, 0x00, 0x80, 0x03, 0xff}, []string{"-32768"}, false},
+		{"int24", []byte{0xf2, 0x01, 0x00, 0x00, 0x04, 0xff}, []string{"1"}, false},
+		{"int32", []byte{0xf3, 0xff, 0xff, 0xff, 0x7f, 0x05, 0xff}, []string{"2147483647"}, false},
+		{"int64", []byte{0xf4, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x09, 0xff}, []string{"-2"}, false},
+		{"缺少结束标记", []byte{0x05, 0x01}, nil, true},
+		{"缺少元素长度", []byte{0x05}, nil, true},
+		{"字符串长度超出", []byte{0x85, 'a', 0x06, 0xff}, nil, true},
+		{"32位长度超出", []byte{0xf0, 0xff, 0xff, 0xff, 0x7f, 0xff}, nil, true},
+		{"无法识别的编码", []byte{0xf5, 0x01, 0xff}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseListpack(append(append([]byte{}, header...), tt.entries...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIntset(t *testing.T) {
+	tests := []struct {
+		name    string
+		buf     []byte
+		want    []string
+		wantErr bool
+	}{
+		{"int16", []byte{2, 0, 0, 0, 2, 0, 0, 0, 0xff, 0xff, 0x01, 0x00}, []string{"-1", "1"}, false},
+		{"int32", []byte{4, 0, 0, 0, 1, 0, 0, 0, 0x00, 0x00, 0x01, 0x00}, []string{"65536"}, false},
+		{"int64", []byte{8, 0, 0, 0, 1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, []string{"-1"}, false},
+		{"头部不完整", []byte{2, 0, 0, 0}, nil, true},
+		{"宽度为0", []byte{0, 0, 0, 0, 1, 0, 0, 0}, nil, true},
+		{"数量超出", []byte{2, 0, 0, 0, 0xff, 0xff, 0xff, 0x7f, 0x01, 0x00}, nil, true},
+		{"无法识别的宽度", []byte{3, 0, 0, 0, 1, 0, 0, 0, 1, 2, 3}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIntset(tt.buf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLzfDecompress(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		outLen  int
+		want    string
+		wantErr bool
+	}{
+		{"字面量", []byte{0x02, 'a', 'b', 'c'}, 3, "abc", false},
+		{"回溯引用", []byte{0x02, 'a', 'b', 'c', 0x80, 0x02}, 9, "abcabcabc", false},
+		{"长回溯引用", []byte{0x00, 'a', 0xe0, 0x01, 0x00}, 11, "aaaaaaaaaaa", false},
+		{"字面量超出", []byte{0x05, 'a', 'b'}, 6, "", true},
+		{"回溯超出开头", []byte{0x00, 'a', 0x20, 0x05}, 4, "", true},
+		{"缺少回溯偏移", []byte{0x00, 'a', 0x20}, 4, "", true},
+		{"长度不一致", []byte{0x02, 'a', 'b', 'c'}, 4, "", true},
+		{"负的解压长度", []byte{0x02, 'a', 'b', 'c'}, -1, "", true},
+		{"解压长度超过压缩比", []byte{0x02, 'a', 'b', 'c'}, 4*lzfMaxRatio + 1, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lzfDecompress(tt.in, tt.outLen)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+//生成rdb文件的内容，body为select db之后的部分
+func rdbFile(body ...byte) []byte {
+	content := append([]byte("REDIS0009"), opSelectDB, 0x00)
+	content = append(content, body...)
+	return append(content, opEOF, 0, 0, 0, 0, 0, 0, 0, 0)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    []*Entry
+		wantErr string
+	}{
+		{
+			name:    "字符串",
+			content: rdbFile(typeString, 0x01, 'k', 0x02, 'v', '1'),
+			want:    []*Entry{{Key: "k", Type: "string", Encoding: "raw", Value: "v1", Elements: 2, Size: 3, Idle: -1}},
+		},
+		{
+			name:    "整数编码的字符串",
+			content: rdbFile(typeString, 0x01, 'n', 0xc0|encInt16, 0x39, 0x30),
+			want:    []*Entry{{Key: "n", Type: "string", Encoding: "int", Value: "12345", Elements: 5, Size: 3, Idle: -1}},
+		},
+		{
+			name:    "lzf压缩的字符串",
+			content: rdbFile(typeString, 0x01, 'z', 0xc0|encLZF, 0x06, 0x09, 0x02, 'a', 'b', 'c', 0x80, 0x02),
+			want:    []*Entry{{Key: "z", Type: "string", Encoding: "raw", Value: "abcabcabc", Elements: 9, Size: 9, Idle: -1}},
+		},
+		{
+			name:    "过期时间和列表",
+			content: rdbFile(opExpireTimeMs, 0xe8, 0x03, 0, 0, 0, 0, 0, 0, typeList, 0x01, 'l', 0x02, 0x01, 'a', 0x01, 'b'),
+			want:    []*Entry{{Key: "l", Type: "list", Encoding: "linkedlist", ExpireAt: 1000, Value: []string{"a", "b"}, Elements: 2, Size: 5, Idle: -1}},
+		},
+		{
+			name:    "不是rdb文件",
+			content: []byte("RESP00009"),
+			wantErr: "不是有效的rdb文件",
+		},
+		{
+			name:    "不支持的版本",
+			content: []byte("REDIS0099"),
+			wantErr: "不支持的rdb文件版本",
+		},
+		{
+			name:    "元素数量超过文件大小",
+			content: rdbFile(typeList, 0x01, 'l', 0x80, 0x7f, 0xff, 0xff, 0xff),
+			wantErr: errCorrupted.Error(),
+		},
+		{
+			name:    "字符串长度超过文件大小",
+			content: rdbFile(typeString, 0x01, 'k', 0x80, 0x7f, 0xff, 0xff, 0xff),
+			wantErr: errCorrupted.Error(),
+		},
+		{
+			name:    "lzf压缩长度超过文件大小",
+			content: rdbFile(typeString, 0x01, 'z', 0xc0|encLZF, 0x80, 0x7f, 0xff, 0xff, 0xff, 0x09),
+			wantErr: errCorrupted.Error(),
+		},
+		{
+			name:    "lzf解压长度超过压缩比",
+			content: rdbFile(typeString, 0x01, 'z', 0xc0|encLZF, 0x01, 0x80, 0x7f, 0xff, 0xff, 0xff, 0x00),
+			wantErr: errCorrupted.Error(),
+		},
+		{
+			name:    "quicklist节点数量超过文件大小",
+			content: rdbFile(typeListQuicklist2, 0x01, 'q', 0x80, 0x7f, 0xff, 0xff, 0xff),
+			wantErr: errCorrupted.Error(),
+		},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, "dump.rdb")
+			if err := ioutil.WriteFile(file, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			entries := []*Entry{}
+			_, err := ParseFile(file, func(entry *Entry) bool {
+				entries = append(entries, entry)
+				return true
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Errorf("got %+v, want %+v", entries, tt.want)
+			}
+		})
+	}
+}
+
+//输入大小未知时按maxLength限制长度，不会预先分配超大的切片
+func TestParseUnknownSize(t *testing.T) {
+	content := rdbFile(typeList, 0x01, 'l', 0x81, 0, 0, 0, 0x7f, 0xff, 0xff, 0xff, 0xff)
+	err := NewParser(bytes.NewReader(content)).Parse(func(entry *Entry) bool { return true })
+	if err == nil || !strings.Contains(err.Error(), errCorrupted.Error()) {
+		t.Fatalf("err = %v, want %q", err, errCorrupted.Error())
+	}
+}
@@ -0,0 +1,186 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"rediscmd/src/model"
+)
+
+//stream消息的标记位
+const (
+	streamItemDeleted    = 1
+	streamItemSameFields = 2
+)
+
+//读取stream，消费组信息只跳过不解析
+func (p *Parser) readStream(valueType byte) ([]StreamEntry, error) {
+	count, err := p.readCount()
+	if err != nil {
+		return nil, err
+	}
+	entries := []StreamEntry{}
+	for i := 0; i < count; i++ {
+		masterID, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		if len(masterID) != 16 {
+			return nil, errCorrupted
+		}
+		listpack, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		items, err := parseListpack(listpack)
+		if err != nil {
+			return nil, err
+		}
+		nodeEntries, err := parseStreamListpack(binary.BigEndian.Uint64(masterID[:8]), binary.BigEndian.Uint64(masterID[8:]), items)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, nodeEntries...)
+	}
+	//消息数量、最后的消息id
+	if err := p.skipLengths(3); err != nil {
+		return nil, err
+	}
+	if valueType >= typeStreamListpacks2 {
+		//第一条消息id、最大删除的消息id、累计添加的消息数量
+		if err := p.skipLengths(5); err != nil {
+			return nil, err
+		}
+	}
+	groups, err := p.readCount()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < groups; i++ {
+		if err := p.skipStreamGroup(valueType); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+//解析stream中的一个listpack节点
+func parseStreamListpack(masterMs, masterSeq uint64, items []string) ([]StreamEntry, error) {
+	nums := func(index, n int) ([]int64, error) {
+		if index+n > len(items) {
+			return nil, errCorrupted
+		}
+		ret := make([]int64, n)
+		for i := 0; i < n; i++ {
+			num, err := strconv.ParseInt(items[index+i], 10, 64)
+			if err != nil {
+				return nil, errCorrupted
+			}
+			ret[i] = num
+		}
+		return ret, nil
+	}
+	//主消息：消息数量、删除数量、字段数量、字段列表、结束标记
+	head, err := nums(0, 3)
+	if err != nil {
+		return nil, err
+	}
+	masterFieldsCount := int(head[2])
+	if 3+masterFieldsCount+1 > len(items) {
+		return nil, errCorrupted
+	}
+	masterFields := items[3 : 3+masterFieldsCount]
+	pos := 3 + masterFieldsCount + 1
+	entries := []StreamEntry{}
+	for pos < len(items) {
+		entryHead, err := nums(pos, 3) //标记、毫秒差值、序号差值
+		if err != nil {
+			return nil, err
+		}
+		pos += 3
+		flags := entryHead[0]
+		entry := StreamEntry{ID: fmt.Sprintf("%d-%d", masterMs+uint64(entryHead[1]), masterSeq+uint64(entryHead[2]))}
+		if flags&streamItemSameFields != 0 {
+			if pos+masterFieldsCount > len(items) {
+				return nil, errCorrupted
+			}
+			for i, field := range masterFields {
+				entry.Fields = append(entry.Fields, model.KV{Key: field, Value: items[pos+i]})
+			}
+			pos += masterFieldsCount
+		} else {
+			fieldsCount, err := nums(pos, 1)
+			if err != nil {
+				return nil, err
+			}
+			pos++
+			if pos+int(fieldsCount[0])*2 > len(items) {
+				return nil, errCorrupted
+			}
+			for i := 0; i < int(fieldsCount[0]); i++ {
+				entry.Fields = append(entry.Fields, model.KV{Key: items[pos+2*i], Value: items[pos+2*i+1]})
+			}
+			pos += int(fieldsCount[0]) * 2
+		}
+		pos++ //跳过lp-count
+		if flags&streamItemDeleted == 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+//跳过stream的一个消费组
+func (p *Parser) skipStreamGroup(valueType byte) error {
+	if _, err := p.readString(); err != nil { //消费组名称
+		return err
+	}
+	if err := p.skipLengths(2); err != nil { //最后投递的消息id
+		return err
+	}
+	if valueType >= typeStreamListpacks2 {
+		if err := p.skipLengths(1); err != nil { //已读取的消息数量
+			return err
+		}
+	}
+	pending, err := p.readCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < pending; i++ {
+		if _, err := p.readN(16 + 8); err != nil { //消息id、投递时间
+			return err
+		}
+		if err := p.skipLengths(1); err != nil { //投递次数
+			return err
+		}
+	}
+	consumers, err := p.readCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < consumers; i++ {
+		if _, err := p.readString(); err != nil { //消费者名称
+			return err
+		}
+		timeBytes := 8 //最后可见时间
+		if valueType >= typeStreamListpacks3 {
+			timeBytes += 8 //最后活跃时间
+		}
+		if _, err := p.readN(timeBytes); err != nil {
+			return err
+		}
+		consumerPending, err := p.readCount()
+		if err != nil {
+			return err
+		}
+		if int64(consumerPending) > p.remaining()/16 {
+			return errCorrupted
+		}
+		if _, err := p.readN(consumerPending * 16); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var errCorrupted = errors.New("rdb数据已损坏")
+
+//在字节切片上顺序读取
+type byteCursor struct {
+	buf []byte
+	pos int
+}
+
+func (c *byteCursor) next(n int) ([]byte, error) {
+	if n < 0 || c.pos+n > len(c.buf) {
+		return nil, errCorrupted
+	}
+	ret := c.buf[c.pos : c.pos+n]
+	c.pos += n
+	return ret, nil
+}
+
+func (c *byteCursor) peek() (byte, error) {
+	if c.pos >= len(c.buf) {
+		return 0, errCorrupted
+	}
+	return c.buf[c.pos], nil
+}
+
+//解析ziplist
+func parseZiplist(buf []byte) ([]string, error) {
+	c := &byteCursor{buf: buf, pos: 10} //跳过zlbytes、zltail、zllen
+	items := []string{}
+	for {
+		b, err := c.peek()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0xff {
+			return items, nil
+		}
+		if b == 0xfe { //前一个元素的长度
+			_, err = c.next(5)
+		} else {
+			_, err = c.next(1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		item, err := parseZiplistEntry(c)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+func parseZiplistEntry(c *byteCursor) (string, error) {
+	head, err := c.next(1)
+	if err != nil {
+		return "", err
+	}
+	enc := head[0]
+	var length int
+	switch enc >> 6 {
+	case 0:
+		length = int(enc & 0x3f)
+	case 1:
+		buf, err := c.next(1)
+		if err != nil {
+			return "", err
+		}
+		length = int(enc&0x3f)<<8 | int(buf[0])
+	case 2:
+		buf, err := c.next(4)
+		if err != nil {
+			return "", err
+		}
+		length = int(binary.BigEndian.Uint32(buf))
+	default:
+		return parseZiplistInt(c, enc)
+	}
+	buf, err := c.next(length)
+	return string(buf), err
+}
+
+func parseZiplistInt(c *byteCursor, enc byte) (string, error) {
+	var value int64
+	switch enc {
+	case 0xc0:
+		buf, err := c.next(2)
+		if err != nil {
+			return "", err
+		}
+		value = int64(int16(binary.LittleEndian.Uint16(buf)))
+	case 0xd0:
+		buf, err := c.next(4)
+		if err != nil {
+			return "", err
+		}
+		value = int64(int32(binary.LittleEndian.Uint32(buf)))
+	case 0xe0:
+		buf, err := c.next(8)
+		if err != nil {
+			return "", err
+		}
+		value = int64(binary.LittleEndian.Uint64(buf))
+	case 0xf0:
+		buf, err := c.next(3)
+		if err != nil {
+			return "", err
+		}
+		value = int64(int32(uint32(buf[0])<<8|uint32(buf[1])<<16|uint32(buf[2])<<24) >> 8)
+	case 0xfe:
+		buf, err := c.next(1)
+		if err != nil {
+			return "", err
+		}
+		value = int64(int8(buf[0]))
+	default:
+		if enc < 0xf1 || enc > 0xfd {
+			return "", fmt.Errorf("无法识别的ziplist编码0x%x", enc)
+		}
+		value = int64(enc&0x0f) - 1
+	}
+	return strconv.FormatInt(value, 10), nil
+}
+
+//解析listpack
+func parseListpack(buf []byte) ([]string, error) {
+	c := &byteCursor{buf: buf, pos: 6} //跳过总字节数和元素数量
+	items := []string{}
+	for {
+		b, err := c.peek()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0xff {
+			return items, nil
+		}
+		start := c.pos
+		item, err := parseListpackEntry(c)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.next(listpackBacklenSize(c.pos - start)); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+func parseListpackEntry(c *byteCursor) (string, error) {
+	head, err := c.next(1)
+	if err != nil {
+		return "", err
+	}
+	b := head[0]
+	var length int
+	switch {
+	case b&0x80 == 0:
+		return strconv.Itoa(int(b & 0x7f)), nil
+	case b&0xc0 == 0x80:
+		length = int(b & 0x3f)
+	case b&0xe0 == 0xc0:
+		buf, err := c.next(1)
+		if err != nil {
+			return "", err
+		}
+		value := int(b&0x1f)<<8 | int(buf[0])
+		if value >= 1<<12 {
+			value -= 1 << 13
+		}
+		return strconv.Itoa(value), nil
+	case b&0xf0 == 0xe0:
+		buf, err := c.next(1)
+		if err != nil {
+			return "", err
+		}
+		length = int(b&0x0f)<<8 | int(buf[0])
+	case b == 0xf0:
+		buf, err := c.next(4)
+		if err != nil {
+			return "", err
+		}
+		length = int(binary.LittleEndian.Uint32(buf))
+	case b == 0xf1:
+		buf, err := c.next(2)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(buf)))), nil
+	case b == 0xf2:
+		buf, err := c.next(3)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(int32(uint32(buf[0])<<8|uint32(buf[1])<<16|uint32(buf[2])<<24) >> 8)), nil
+	case b == 0xf3:
+		buf, err := c.next(4)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(buf)))), nil
+	case b == 0xf4:
+		buf, err := c.next(8)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(int64(binary.LittleEndian.Uint64(buf)), 10), nil
+	default:
+		return "", fmt.Errorf("无法识别的listpack编码0x%x", b)
+	}
+	buf, err := c.next(length)
+	return string(buf), err
+}
+
+//listpack元素末尾记录元素长度所占的字节数
+func listpackBacklenSize(length int) int {
+	switch {
+	case length <= 127:
+		return 1
+	case length < 16383:
+		return 2
+	case length < 2097151:
+		return 3
+	case length < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+//解析intset
+func parseIntset(buf []byte) ([]string, error) {
+	c := &byteCursor{buf: buf}
+	head, err := c.next(8)
+	if err != nil {
+		return nil, err
+	}
+	width := int(binary.LittleEndian.Uint32(head[0:4]))
+	count := int(binary.LittleEndian.Uint32(head[4:8]))
+	if width <= 0 || count > (len(buf)-8)/width {
+		return nil, errCorrupted
+	}
+	items := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		buf, err := c.next(width)
+		if err != nil {
+			return nil, err
+		}
+		switch width {
+		case 2:
+			items = append(items, strconv.Itoa(int(int16(binary.LittleEndian.Uint16(buf)))))
+		case 4:
+			items = append(items, strconv.Itoa(int(int32(binary.LittleEndian.Uint32(buf)))))
+		case 8:
+			items = append(items, strconv.FormatInt(int64(binary.LittleEndian.Uint64(buf)), 10))
+		default:
+			return nil, fmt.Errorf("无法识别的intset编码%d", width)
+		}
+	}
+	return items, nil
+}
+
+//解析zipmap，返回字段和值依次排列的切片
+func parseZipmap(buf []byte) ([]string, error) {
+	c := &byteCursor{buf: buf, pos: 1} //跳过元素数量
+	items := []string{}
+	for {
+		b, err := c.peek()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0xff {
+			return items, nil
+		}
+		keyLen, err := zipmapLength(c)
+		if err != nil {
+			return nil, err
+		}
+		key, err := c.next(keyLen)
+		if err != nil {
+			return nil, err
+		}
+		valueLen, err := zipmapLength(c)
+		if err != nil {
+			return nil, err
+		}
+		free, err := c.next(1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := c.next(valueLen)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.next(int(free[0])); err != nil {
+			return nil, err
+		}
+		items = append(items, string(key), string(value))
+	}
+}
+
+func zipmapLength(c *byteCursor) (int, error) {
+	head, err := c.next(1)
+	if err != nil {
+		return 0, err
+	}
+	if head[0] < 254 {
+		return int(head[0]), nil
+	}
+	if head[0] == 255 {
+		return 0, errCorrupted
+	}
+	buf, err := c.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.LittleEndian.Uint32(buf)), nil
+}
@@ -0,0 +1,111 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//参数的最大字节数，与redis的proto-max-bulk-len默认值相同
+const maxBulkLength = 512 << 20
+
+//预先分配的参数数量上限，命令头中的数量不可信，超过的部分按实际读取逐步分配
+const maxPrealloc = 1024
+
+//RESP协议格式的命令流读取器
+type Reader struct {
+	reader     *bufio.Reader
+	Annotation string //最近一次读取到的#注释行，例如redis7的aof时间戳#TS:1690000000
+}
+
+//创建命令流读取器
+func NewReader(reader *bufio.Reader) *Reader {
+	return &Reader{reader: reader}
+}
+
+//读取一条命令，支持RESP数组格式和以空格分隔的内联格式，读取结束时返回io.EOF
+func (r *Reader) ReadCommand() ([]string, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			r.Annotation = line
+			continue
+		}
+		if !strings.HasPrefix(line, "*") {
+			return strings.Fields(line), nil
+		}
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("无法识别的命令头%q", line)
+		}
+		args := make([]string, 0, preallocSize(count))
+		for i := 0; i < count; i++ {
+			arg, err := r.readBulkString()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			args = append(args, arg)
+		}
+		return args, nil
+	}
+}
+
+func (r *Reader) readBulkString() (string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(line, "$") {
+		return "", fmt.Errorf("无法识别的参数头%q", line)
+	}
+	length, err := strconv.Atoi(line[1:])
+	if err != nil || length < 0 {
+		return "", fmt.Errorf("无法识别的参数头%q", line)
+	}
+	if length > maxBulkLength {
+		return "", fmt.Errorf("参数长度%d超过上限%d", length, maxBulkLength)
+	}
+	buf := make([]byte, length+2)
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		return "", err
+	}
+	if buf[length] != '\r' || buf[length+1] != '\n' {
+		return "", fmt.Errorf("长度为%d的参数之后缺少\\r\\n", length)
+	}
+	return string(buf[:length]), nil
+}
+
+//读取一行，去掉末尾的\r\n
+func (r *Reader) readLine() (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			return "", io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+//预先分配的容量，不超过maxPrealloc
+func preallocSize(count int) int {
+	if count > maxPrealloc {
+		return maxPrealloc
+	}
+	return count
+}
@@ -0,0 +1,94 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		want       [][]string
+		annotation string
+		err        error //读取完want之后的错误
+	}{
+		{"数组格式", "*3\r\n$3\r\nset\r\n$1\r\nk\r\n$5\r\nv a l\r\n", [][]string{{"set", "k", "v a l"}}, "", io.EOF},
+		{"参数包含换行", "*2\r\n$3\r\nget\r\n$4\r\na\r\nb\r\n", [][]string{{"get", "a\r\nb"}}, "", io.EOF},
+		{"空参数", "*2\r\n$3\r\nget\r\n$0\r\n\r\n", [][]string{{"get", ""}}, "", io.EOF},
+		{"内联格式", "set k v\nget k\r\n", [][]string{{"set", "k", "v"}, {"get", "k"}}, "", io.EOF},
+		{"跳过空行", "\r\n\nping\r\n", [][]string{{"ping"}}, "", io.EOF},
+		{"注释", "#TS:1690000000\r\n*1\r\n$5\r\nmulti\r\n", [][]string{{"multi"}}, "#TS:1690000000", io.EOF},
+		{"空数组", "*0\r\n", [][]string{{}}, "", io.EOF},
+		{"空输入", "", nil, "", io.EOF},
+		{"参数不完整", "*2\r\n$3\r\nget\r\n", nil, "", io.ErrUnexpectedEOF},
+		{"参数值截断", "*1\r\n$5\r\nab", nil, "", io.ErrUnexpectedEOF},
+		{"行不完整", "*1\r\n$3", nil, "", io.ErrUnexpectedEOF},
+		{"参数数量过大", "*99999999999\r\n$3\r\nget\r\n", nil, "", io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		reader := NewReader(bufio.NewReader(strings.NewReader(tt.input)))
+		for i, want := range tt.want {
+			got, err := reader.ReadCommand()
+			if err != nil {
+				t.Fatalf("%s: 第%d条命令读取失败：%v", tt.name, i+1, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: 第%d条命令为%q，期望%q", tt.name, i+1, got, want)
+			}
+		}
+		if _, err := reader.ReadCommand(); err != tt.err {
+			t.Errorf("%s: 错误为%v，期望%v", tt.name, err, tt.err)
+		}
+		if reader.Annotation != tt.annotation {
+			t.Errorf("%s: 注释为%q，期望%q", tt.name, reader.Annotation, tt.annotation)
+		}
+	}
+}
+
+func TestReadCommandInvalid(t *testing.T) {
+	tests := []string{
+		"*x\r\n",
+		"*-1\r\n",
+		"*1\r\n:1\r\n",
+		"*1\r\n$-1\r\n",
+		"*1\r\n$abc\r\n",
+		"*1\r\n$536870913\r\n",
+		"*1\r\n$99999999999999999999\r\n",
+		"*1\r\n$3\r\ngetxx\r\n",
+		"*1\r\n$3\r\nget\n\n",
+	}
+	for _, input := range tests {
+		reader := NewReader(bufio.NewReader(strings.NewReader(input)))
+		if args, err := reader.ReadCommand(); err == nil || err == io.EOF || err == io.ErrUnexpectedEOF {
+			t.Errorf("ReadCommand(%q) = %q, %v，期望格式错误", input, args, err)
+		}
+	}
+}
+
+func TestWriteCommandRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"set", "k", "v"},
+		{"set", "key with space", "a\r\nb"},
+		{"get", ""},
+		{"hset", "中文", "字段", "值"},
+	}
+	for _, args := range tests {
+		builder := &strings.Builder{}
+		writer := bufio.NewWriter(builder)
+		if err := WriteCommand(writer, args...); err != nil {
+			t.Fatal(err)
+		}
+		writer.Flush()
+		got, err := NewReader(bufio.NewReader(strings.NewReader(builder.String()))).ReadCommand()
+		if err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+		if !reflect.DeepEqual(got, args) {
+			t.Errorf("读取到%q，期望%q", got, args)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package resp
+
+import (
+	"bufio"
+	"strconv"
+)
+
+//以RESP协议格式写入一条命令
+func WriteCommand(writer *bufio.Writer, args ...string) error {
+	writer.WriteString("*")
+	writer.WriteString(strconv.Itoa(len(args)))
+	writer.WriteString("\r\n")
+	for _, arg := range args {
+		writer.WriteString("$")
+		writer.WriteString(strconv.Itoa(len(arg)))
+		writer.WriteString("\r\n")
+		writer.WriteString(arg)
+		if _, err := writer.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,6 +2,9 @@ package util
 
 import (
 	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
@@ -32,14 +35,30 @@ func ClearConsoleScreen() {
 	}
 }
 
+//自动回答(y/n)确认，执行可信脚本时不再询问
+var autoConfirm bool
+
+//设置是否自动回答(y/n)确认
+func SetAutoConfirm(auto bool) {
+	autoConfirm = auto
+}
+
 //从控制塔台读取一个值
 func ReadValueFromConsole(noticeMsg string, isNum bool) (string, int) {
+	if autoConfirm && strings.Contains(noticeMsg, "(y/n)") {
+		log.Println(noticeMsg + "y")
+		return "y", 0
+	}
 	stdInput := bufio.NewReader(os.Stdin)
 	log.Println(noticeMsg)
 	str, err := stdInput.ReadString('\n')
 	str = strings.ReplaceAll(str, "\n", "")
 	str = strings.ReplaceAll(str, "\r", "")
 	str = strings.Trim(str, " ")
+	if err == io.EOF && str == "" {
+		log.Println("输入已结束，未获取到您输入的内容")
+		return "", -1 //非交互方式执行时没有更多输入，避免一直等待
+	}
 	if err != nil || str == "" {
 		log.Println("未获取到您输入的任何内容，请重新输入！")
 		return ReadValueFromConsole(noticeMsg, isNum)
@@ -54,3 +73,32 @@ func ReadValueFromConsole(noticeMsg string, isNum bool) (string, int) {
 	}
 	return str, 0
 }
+
+//在$EDITOR中编辑内容，返回编辑后的内容，suffix为临时文件的后缀便于编辑器识别格式
+func EditInEditor(content, suffix string) (string, error) {
+	f, err := ioutil.TempFile("", "rediscmd-*"+suffix)
+	if err != nil {
+		return "", err
+	}
+	defer RemoveFile(f.Name())
+	_, err = f.WriteString(content)
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			editor = []string{"notepad"}
+		}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("编辑器%s运行出错：%s", editor[0], err.Error())
+	}
+	return ReadFileAsString(f.Name())
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//格式化命令用于展示，包含空白、引号或不可见字符的参数加引号
+func FormatCommand(args []string) string {
+	items := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\r\n\"'") || !isPrintable(arg) {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		items = append(items, arg)
+	}
+	return strings.Join(items, " ")
+}
+
+func isPrintable(str string) bool {
+	for _, r := range str {
+		if r < 0x20 || r == 0x7f || r == 0xfffd {
+			return false
+		}
+	}
+	return true
+}
+
+//截取匹配内容前后width个字节的片段用于展示，loc为匹配的起止位置
+func Snippet(str string, loc []int, width int) string {
+	start, end := loc[0]-width, loc[1]+width
+	prefix, suffix := "...", "..."
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(str) {
+		end, suffix = len(str), ""
+	}
+	for start > 0 && !utf8.RuneStart(str[start]) {
+		start--
+	}
+	for end < len(str) && !utf8.RuneStart(str[end]) {
+		end++
+	}
+	snippet := str[start:end]
+	if !isPrintable(snippet) {
+		snippet = strconv.Quote(snippet)
+	}
+	return prefix + snippet + suffix
+}
+
+//将字符串截断为最多max个字符，截断时以...结尾
+func Truncate(str string, max int) string {
+	if utf8.RuneCountInString(str) <= max {
+		return str
+	}
+	runes := []rune(str)
+	return string(runes[:max]) + "..."
+}
@@ -0,0 +1,110 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+//将忽略大小写的模糊key转换为正则表达式，匹配时key需要先转为小写
+func IgnoreCaseKeyRegexp(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.ToLower(pattern)
+	pattern = strings.ReplaceAll(pattern, ".", "\\.")
+	pattern = strings.ReplaceAll(pattern, "*", ".*")
+	return regexp.Compile(pattern)
+}
+
+//按redis的glob规则匹配key，支持* ? [abc] [^a] [a-z]和\转义
+func GlobMatch(pattern, str string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(str); i++ {
+				if GlobMatch(pattern[1:], str[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(str) == 0 {
+				return false
+			}
+			str = str[1:]
+		case '[':
+			if len(str) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 { //没有闭合的[按普通字符处理
+				if str[0] != '[' {
+					return false
+				}
+				str = str[1:]
+				break
+			}
+			if !matchCharClass(pattern[1:end+1], str[0]) {
+				return false
+			}
+			pattern = pattern[end+1:]
+			str = str[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(str) == 0 || pattern[0] != str[0] {
+				return false
+			}
+			str = str[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return len(str) == 0
+}
+
+//匹配[]中的字符集合
+func matchCharClass(class string, c byte) bool {
+	not := strings.HasPrefix(class, "^")
+	if not {
+		class = class[1:]
+	}
+	matched := false
+	for i := 0; i < len(class); i++ {
+		if class[i] == '\\' && i+1 < len(class) {
+			i++
+			if class[i] == c {
+				matched = true
+			}
+		} else if i+2 < len(class) && class[i+1] == '-' {
+			start, end := class[i], class[i+2]
+			if start > end {
+				start, end = end, start
+			}
+			if c >= start && c <= end {
+				matched = true
+			}
+			i += 2
+		} else if class[i] == c {
+			matched = true
+		}
+	}
+	return matched != not
+}
+
+//转义glob中的特殊字符，用于按字面量匹配前缀
+func EscapeGlob(str string) string {
+	var builder strings.Builder
+	for _, c := range str {
+		if strings.ContainsRune(`*?[]\`, c) {
+			builder.WriteByte('\\')
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
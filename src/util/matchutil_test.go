@@ -0,0 +1,65 @@
+package util
+
+import "testing"
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		str     string
+		want    bool
+	}{
+		{"*", "", true},
+		{"*", "user:1", true},
+		{"user:*", "user:1", true},
+		{"user:*", "order:1", false},
+		{"*:1", "user:1", true},
+		{"u**r", "user", true},
+		{"user:?", "user:1", true},
+		{"user:?", "user:", false},
+		{"user:?", "user:12", false},
+		{"user:[0-9]", "user:5", true},
+		{"user:[9-0]", "user:5", true},
+		{"user:[0-9]", "user:a", false},
+		{"user:[^0-9]", "user:a", true},
+		{"user:[^0-9]", "user:5", false},
+		{"user:[abc]", "user:b", true},
+		{"user:[abc]", "user:d", false},
+		{"user:[abc]", "user:", false},
+		{"user:[\\-]", "user:-", true},
+		{"user:[", "user:[", true},
+		{"user:[", "user:a", false},
+		{"user\\*", "user*", true},
+		{"user\\*", "users", false},
+		{"a\\", "a\\", true},
+		{"h?llo*world", "hello, world", true},
+		{"h?llo*world", "hello, world!", false},
+		{"", "", true},
+		{"", "a", false},
+	}
+	for _, tt := range tests {
+		if got := GlobMatch(tt.pattern, tt.str); got != tt.want {
+			t.Errorf("GlobMatch(%q, %q) = %v, want %v", tt.pattern, tt.str, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeGlob(t *testing.T) {
+	tests := []struct {
+		str  string
+		want string
+	}{
+		{"user:1", "user:1"},
+		{"a*b?c", "a\\*b\\?c"},
+		{"[x]\\", "\\[x\\]\\\\"},
+		{"中文*", "中文\\*"},
+	}
+	for _, tt := range tests {
+		got := EscapeGlob(tt.str)
+		if got != tt.want {
+			t.Errorf("EscapeGlob(%q) = %q, want %q", tt.str, got, tt.want)
+		}
+		if !GlobMatch(got, tt.str) {
+			t.Errorf("GlobMatch(%q, %q) = false, want true", got, tt.str)
+		}
+	}
+}
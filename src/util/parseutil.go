@@ -0,0 +1,80 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//解析时长，支持ms/s/m/h/d单位，不带单位时按秒处理
+func ParseDuration(str string) (time.Duration, error) {
+	str = strings.ToLower(strings.TrimSpace(str))
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		{"ms", time.Millisecond},
+		{"s", time.Second},
+		{"m", time.Minute},
+		{"h", time.Hour},
+		{"d", 24 * time.Hour},
+	}
+	unit := time.Second
+	for _, item := range units {
+		if strings.HasSuffix(str, item.suffix) {
+			str = strings.TrimSuffix(str, item.suffix)
+			unit = item.unit
+			break
+		}
+	}
+	num, err := strconv.ParseFloat(str, 64)
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("无法解析时长%s", str)
+	}
+	return time.Duration(num * float64(unit)), nil
+}
+
+//解析字节大小，支持B/KB/MB/GB单位（1024进制），不带单位时按字节处理
+func ParseByteSize(str string) (int64, error) {
+	str = strings.ToUpper(strings.TrimSpace(str))
+	units := []struct {
+		suffix string
+		unit   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"G", 1 << 30},
+		{"M", 1 << 20},
+		{"K", 1 << 10},
+		{"B", 1},
+	}
+	unit := int64(1)
+	for _, item := range units {
+		if strings.HasSuffix(str, item.suffix) {
+			str = strings.TrimSuffix(str, item.suffix)
+			unit = item.unit
+			break
+		}
+	}
+	num, err := strconv.ParseFloat(str, 64)
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("无法解析大小%s", str)
+	}
+	return int64(num * float64(unit)), nil
+}
+
+//格式化字节大小
+func FormatByteSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.2fGB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.2fMB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.2fKB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+//终端进入raw模式，返回恢复终端设置的函数，目前只支持linux和macos
+func TerminalRawMode() (func(), error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("您的系统还不支持全屏界面")
+	}
+	state, err := stty("-g")
+	if err != nil {
+		return nil, errors.New("无法读取终端设置，请在终端中运行此命令")
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(strings.TrimSpace(state))
+	}, nil
+}
+
+//获取终端的行数和列数，获取失败时返回24行80列
+func TerminalSize() (int, int) {
+	out, err := stty("size")
+	if err != nil {
+		return 24, 80
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 24, 80
+	}
+	rows, err1 := strconv.Atoi(fields[0])
+	cols, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+//字符在终端中占用的列数，中日韩文字和全角字符占两列
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x1100:
+		return 1
+	case r <= 0x115f, r >= 0x2e80 && r <= 0xa4cf && r != 0x303f, r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff, r >= 0xfe30 && r <= 0xfe4f, r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6, r >= 0x1f300 && r <= 0x1f64f, r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
+
+//将字符串截断或补齐空格到指定的显示宽度，不可见字符替换为空格
+func FitWidth(str string, width int) string {
+	var builder strings.Builder
+	used := 0
+	for _, r := range str {
+		if r < 0x20 || r == 0x7f {
+			r = ' '
+		}
+		w := RuneWidth(r)
+		if used+w > width {
+			break
+		}
+		builder.WriteRune(r)
+		used += w
+	}
+	builder.WriteString(strings.Repeat(" ", width-used))
+	return builder.String()
+}